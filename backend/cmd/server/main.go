@@ -1,26 +1,63 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"time"
 
+	"brew-detective-backend/internal/audit"
 	"brew-detective-backend/internal/auth"
+	"brew-detective-backend/internal/badges"
+	"brew-detective-backend/internal/catalog"
 	"brew-detective-backend/internal/database"
 	"brew-detective-backend/internal/handlers"
+	"brew-detective-backend/internal/leaderboard"
+	"brew-detective-backend/internal/roles"
+	"brew-detective-backend/internal/schedule"
+	"brew-detective-backend/internal/scoring"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	// Initialize Firestore
-	if err := database.InitFirestore(); err != nil {
-		log.Fatalf("Failed to initialize Firestore: %v", err)
+	// Initialize storage (Firestore or in-memory, see STORAGE_BACKEND)
+	if err := database.Init(); err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
 	}
-	defer database.CloseFirestore()
+	defer database.Close()
 
-	// Initialize Auth
+	// Seed the default badges and the flavor wheel on first start, and convert
+	// badges saved as plain strings
+	seedCtx, cancelSeed := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := badges.SeedDefaults(seedCtx, database.DB.Badges()); err != nil {
+		log.Printf("Failed to seed default badges: %v", err)
+	}
+	if err := badges.MigrateLegacy(seedCtx, database.DB); err != nil {
+		log.Printf("Failed to migrate legacy badges: %v", err)
+	}
+	if err := catalog.SeedTastingNotes(seedCtx, database.DB.Catalog()); err != nil {
+		log.Printf("Failed to seed tasting notes: %v", err)
+	}
+	cancelSeed()
+
+	// Build the leaderboard standings if they have never been computed
+	buildCtx, cancelBuild := context.WithTimeout(context.Background(), 60*time.Second)
+	if err := leaderboard.EnsureBuilt(buildCtx, database.DB); err != nil {
+		log.Printf("Failed to build leaderboard standings: %v", err)
+	}
+	cancelBuild()
+
+	// Grade region, variety and process answers through the catalog
+	scoring.Default.SetCatalog(catalog.Cached{})
+
+	// Initialize Auth and keep rotating the token signing keys
 	auth.InitAuth()
+	auth.StartKeyRotation(context.Background())
+
+	// Open, close and reveal cases on their schedule
+	schedule.Start(context.Background())
 
 	// Initialize Gin router
 	router := gin.Default()
@@ -34,10 +71,12 @@ func main() {
 		"http://127.0.0.1:8080",        // Alternative localhost
 	}
 	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", "X-Request-ID"}
+	config.ExposeHeaders = []string{"X-Request-ID"}
 	config.AllowCredentials = true
 
 	router.Use(cors.New(config))
+	router.Use(audit.RequestID())
 
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
@@ -47,12 +86,20 @@ func main() {
 	// Firestore test endpoint
 	router.GET("/test/firestore", handlers.TestFirestore)
 
+	// Public keys for verifying access tokens
+	router.GET("/.well-known/jwks.json", handlers.GetJWKS)
+
 	// Auth routes
 	authRoutes := router.Group("/auth")
 	{
-		authRoutes.GET("/google", handlers.GoogleLogin)
-		authRoutes.GET("/google/callback", handlers.GoogleCallback)
-		authRoutes.POST("/logout", handlers.Logout)
+		authRoutes.GET("/providers", handlers.GetAuthProviders)
+		authRoutes.POST("/email", handlers.RequestEmailLogin)
+		authRoutes.GET("/email/verify", handlers.VerifyEmailLogin)
+		authRoutes.GET("/:provider", handlers.ProviderLogin)
+		authRoutes.GET("/:provider/callback", handlers.ProviderCallback)
+		authRoutes.POST("/refresh", handlers.RefreshToken)
+		authRoutes.POST("/logout", auth.AuthMiddleware(), handlers.Logout)
+		authRoutes.POST("/logout-all", auth.AuthMiddleware(), handlers.LogoutAll)
 	}
 
 	// API routes
@@ -62,10 +109,17 @@ func main() {
 		api.GET("/cases/public", handlers.GetCasesPublic)
 		api.GET("/cases/active/public", handlers.GetActiveCasePublic)
 		api.GET("/cases/:id/public", handlers.GetCaseByIDPublic)
-		api.GET("/leaderboard", handlers.GetLeaderboard)
-		api.GET("/leaderboard/current", handlers.GetCurrentCaseLeaderboard)
+		api.GET("/cases/:id/reveal", auth.OptionalAuthMiddleware(), handlers.GetCaseReveal)
+		api.GET("/leaderboard", auth.OptionalAuthMiddleware(), handlers.GetLeaderboard)
+		api.GET("/leaderboard/current", auth.OptionalAuthMiddleware(), handlers.GetCurrentCaseLeaderboard)
+		api.GET("/leaderboard/cases/:id", auth.OptionalAuthMiddleware(), handlers.GetCaseLeaderboard)
+		api.GET("/leaderboard/cases/:id/teams", auth.OptionalAuthMiddleware(), handlers.GetCaseTeamLeaderboard)
+		api.GET("/leaderboard/seasons/:id", auth.OptionalAuthMiddleware(), handlers.GetSeasonLeaderboard)
+		api.GET("/seasons", handlers.GetSeasons)
+		api.GET("/users/:id/public", handlers.GetPublicUserProfile)
 		api.GET("/catalog", handlers.GetAllCatalog)
 		api.GET("/catalog/:category", handlers.GetCatalogByCategory)
+		api.GET("/badges", handlers.GetBadges)
 
 		// Protected routes
 		protected := api.Group("/")
@@ -76,42 +130,102 @@ func main() {
 			protected.GET("/users/:id", handlers.GetUserProfile)
 			protected.PUT("/users/:id", handlers.UpdateUserProfile)
 
+			// Identities
+			protected.GET("/identities", handlers.GetIdentities)
+			protected.POST("/identities/:provider", handlers.LinkIdentity)
+			protected.DELETE("/identities/:id", handlers.UnlinkIdentity)
+
+			// Sessions
+			protected.GET("/sessions", handlers.GetSessions)
+			protected.DELETE("/sessions/:id", handlers.RevokeSession)
+
 			// Submissions
 			protected.POST("/submissions", handlers.SubmitCase)
 			protected.GET("/submissions", handlers.GetUserSubmissions)
+			protected.GET("/submissions/:id", handlers.GetUserSubmission)
+
+			// Drafts, keyed by order code
+			protected.GET("/drafts", handlers.GetUserDrafts)
+			protected.GET("/drafts/:order_id", handlers.GetDraft)
+			protected.PUT("/drafts/:order_id", handlers.SaveDraft)
+			protected.POST("/drafts/:order_id/finalize", handlers.FinalizeDraft)
+
+			// Teams sharing an order
+			protected.POST("/teams", handlers.CreateTeam)
+			protected.POST("/teams/join", handlers.JoinTeam)
+			protected.GET("/teams", handlers.GetUserTeams)
+			protected.GET("/teams/:id", handlers.GetTeam)
+			protected.DELETE("/teams/:id/members/:user_id", handlers.RemoveTeamMember)
 
 			// Orders
 			protected.POST("/orders", handlers.CreateOrder)
 			protected.GET("/orders/:id", handlers.GetOrder)
-			protected.PUT("/orders/:id/status", handlers.UpdateOrderStatus)
+			protected.POST("/orders/:id/cancel", handlers.CancelOrder)
 		}
 
-		// Admin routes
+		// Staff routes, each requiring a permission granted by the user's roles.
+		// Every change made through them is recorded in the audit log: handlers
+		// write the entry in the transaction that makes the change, and the bulk
+		// changes that cannot run in one transaction are logged before they start.
 		admin := api.Group("/admin")
-		admin.Use(auth.AdminMiddleware())
+		admin.Use(auth.AuthMiddleware())
+		can := auth.RequirePermission
+		logs := audit.Log
 		{
 			// Catalog management
-			admin.GET("/catalog", handlers.GetAllCatalogItems)
-			admin.POST("/catalog", handlers.CreateCatalogItem)
-			admin.PUT("/catalog/:id", handlers.UpdateCatalogItem)
-			admin.DELETE("/catalog/:id", handlers.DeleteCatalogItem)
+			admin.GET("/catalog", can(roles.CatalogManage), handlers.GetAllCatalogItems)
+			admin.POST("/catalog", can(roles.CatalogManage), handlers.CreateCatalogItem)
+			admin.PUT("/catalog/:id", can(roles.CatalogManage), handlers.UpdateCatalogItem)
+			admin.DELETE("/catalog/:id", can(roles.CatalogManage), handlers.DeleteCatalogItem)
 
 			// Case management
-			admin.GET("/cases", handlers.GetAllCases)
-			admin.GET("/cases/active", handlers.GetActiveCase)
-			admin.GET("/cases/:id", handlers.GetCaseByID)
-			admin.GET("/cases/list", handlers.GetCases)
-			admin.POST("/cases", handlers.CreateCase)
-			admin.PUT("/cases/:id", handlers.UpdateCase)
-			admin.DELETE("/cases/:id", handlers.DeleteCase)
+			admin.GET("/cases", can(roles.CasesManage), handlers.GetAllCases)
+			admin.GET("/cases/active", can(roles.CasesManage), handlers.GetActiveCase)
+			admin.GET("/cases/:id", can(roles.CasesManage), handlers.GetCaseByID)
+			admin.GET("/cases/list", can(roles.CasesManage), handlers.GetCases)
+			admin.POST("/cases", can(roles.CasesManage), handlers.CreateCase)
+			admin.PUT("/cases/:id", can(roles.CasesManage), handlers.UpdateCase)
+			admin.DELETE("/cases/:id", can(roles.CasesManage), handlers.DeleteCase)
+			admin.POST("/cases/:id/rescore", can(roles.CasesManage), logs("case.rescore", audit.Cases), handlers.RescoreCase)
+			admin.GET("/scoring-rules/default", can(roles.CasesManage), handlers.GetDefaultScoringRules)
+
+			// Submission management
+			admin.GET("/submissions/:id", can(roles.SubmissionsRead), handlers.GetSubmissionByID)
+
+			// Badge management
+			admin.GET("/badges", can(roles.BadgesManage), handlers.GetAllBadges)
+			admin.POST("/badges", can(roles.BadgesManage), handlers.CreateBadge)
+			admin.PUT("/badges/:id", can(roles.BadgesManage), handlers.UpdateBadge)
+			admin.DELETE("/badges/:id", can(roles.BadgesManage), handlers.DeleteBadge)
+
+			// Leaderboard management
+			admin.POST("/leaderboard/rebuild", can(roles.LeaderboardManage), logs("leaderboard.rebuild", audit.Leaderboard), handlers.RebuildLeaderboard)
+			admin.POST("/seasons", can(roles.LeaderboardManage), handlers.CreateSeason)
+			admin.PUT("/seasons/:id", can(roles.LeaderboardManage), handlers.UpdateSeason)
+			admin.DELETE("/seasons/:id", can(roles.LeaderboardManage), handlers.DeleteSeason)
 
 			// Order management
-			admin.GET("/orders", handlers.GetAllOrders)
-			admin.POST("/orders", handlers.CreateOrder)
-			admin.PUT("/orders/:id/status", handlers.UpdateOrderStatus)
+			admin.GET("/orders", can(roles.OrdersRead), handlers.GetAllOrders)
+			admin.POST("/orders", can(roles.OrdersManage), handlers.CreateOrder)
+			admin.PUT("/orders/:id/status", can(roles.OrdersManage), handlers.UpdateOrderStatus)
+			admin.GET("/orders/:id/history", can(roles.OrdersRead), handlers.GetOrderHistory)
+
+			// Token signing keys
+			admin.POST("/keys/rotate", can(roles.KeysManage), logs("signing_key.rotate", audit.SigningKeys), handlers.RotateSigningKeys)
 
 			// User management
-			admin.GET("/users", handlers.GetAllUsers)
+			admin.GET("/users", can(roles.UsersRead), handlers.GetAllUsers)
+			admin.GET("/users/:id/sessions", can(roles.UsersRead), handlers.GetUserSessions)
+			admin.DELETE("/users/:id/sessions", can(roles.SessionsManage), logs("user.sessions_revoke", audit.Users), handlers.RevokeUserSessions)
+
+			// Roles
+			admin.GET("/roles", can(roles.RolesManage), handlers.GetRoles)
+			admin.POST("/users/:id/roles", can(roles.RolesManage), handlers.GrantRole)
+			admin.DELETE("/users/:id/roles/:role", can(roles.RolesManage), handlers.RevokeRole)
+
+			// Audit log
+			admin.GET("/audit", can(roles.AuditRead), handlers.GetAuditLog)
+			admin.GET("/audit/export", can(roles.AuditRead), handlers.ExportAuditLog)
 		}
 	}
 
@@ -0,0 +1,172 @@
+// Package audit records who changed what through the staff API.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"reflect"
+	"time"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const requestIDKey = "requestID"
+
+// Target is a kind of document whose changes are audited
+type Target struct {
+	Type string
+}
+
+// Audited targets
+var (
+	CatalogItems = Target{"catalog_item"}
+	Cases        = Target{"case"}
+	Badges       = Target{"badge"}
+	Seasons      = Target{"season"}
+	Orders       = Target{"order"}
+	Users        = Target{"user"}
+	Leaderboard  = Target{"leaderboard"}
+	SigningKeys  = Target{"signing_key"}
+)
+
+// RequestID tags every request with an ID, reusing the caller's X-Request-ID
+// when it is reasonable, and echoes it in the response
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader("X-Request-ID")
+		if !validRequestID(id) {
+			id = uuid.New().String()
+		}
+		c.Set(requestIDKey, id)
+		c.Header("X-Request-ID", id)
+		c.Next()
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' || r == ':') {
+			return false
+		}
+	}
+	return true
+}
+
+// Log records a request as action on the target named by the :id route
+// parameter before the handler runs, and refuses the request when the entry
+// cannot be written. It is for bulk changes that cannot share a transaction
+// with their entry, so the entry has no field changes and the status is 202
+// Accepted: it shows that the change was started, not that it succeeded.
+// Routes that change a single document use Write in their transaction instead.
+func Log(action string, target Target) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		entry := newEntry(c, action, target.Type, c.Param("id"), nil, http.StatusAccepted)
+		if err := database.DB.Audit().Append(ctx, entry); err != nil {
+			log.Printf("Failed to write audit entry %s (%s %s): %v", action, target.Type, entry.TargetID, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to write the audit log"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Diff compares two documents by their JSON fields and returns the fields
+// that differ. Either document may be nil.
+func Diff(before, after interface{}) (map[string]models.FieldChange, error) {
+	old, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]models.FieldChange)
+	for name, value := range old {
+		if !reflect.DeepEqual(value, updated[name]) {
+			changes[name] = models.FieldChange{Before: value, After: updated[name]}
+		}
+	}
+	for name, value := range updated {
+		if _, ok := old[name]; !ok {
+			changes[name] = models.FieldChange{After: value}
+		}
+	}
+	return changes, nil
+}
+
+func fields(doc interface{}) (map[string]interface{}, error) {
+	if doc == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// Write records a change to the target as part of the transaction that makes
+// it, so that the change is not saved without its entry. Before is nil for
+// created documents and after is nil for deleted ones.
+func Write(ctx context.Context, tx database.Store, c *gin.Context, action string, target Target, id string, before, after interface{}) error {
+	changes, err := Diff(before, after)
+	if err != nil {
+		return err
+	}
+	return tx.Audit().Append(ctx, newEntry(c, action, target.Type, id, changes, http.StatusOK))
+}
+
+// WriteUpdate is Write for a partial update given as document fields, for
+// transactions that cannot read the document back once they have written it
+func WriteUpdate(ctx context.Context, tx database.Store, c *gin.Context, action string, target Target, id string, before interface{}, updates map[string]interface{}) error {
+	after, err := fields(before)
+	if err != nil {
+		return err
+	}
+	changed, err := fields(updates)
+	if err != nil {
+		return err
+	}
+	if after == nil {
+		after = make(map[string]interface{})
+	}
+	for name, value := range changed {
+		after[name] = value
+	}
+	return Write(ctx, tx, c, action, target, id, before, after)
+}
+
+func newEntry(c *gin.Context, action, targetType, targetID string, changes map[string]models.FieldChange, status int) *models.AuditEntry {
+	return &models.AuditEntry{
+		ID:         uuid.New().String(),
+		Actor:      c.GetString("userID"),
+		ActorEmail: c.GetString("email"),
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Changes:    changes,
+		RequestID:  c.GetString(requestIDKey),
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+		Status:     status,
+		CreatedAt:  time.Now(),
+	}
+}
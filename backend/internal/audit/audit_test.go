@@ -0,0 +1,163 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// brokenAudit is a store whose audit log cannot be written
+type brokenAudit struct {
+	*database.MemoryStore
+}
+
+func (brokenAudit) Audit() database.AuditRepository { return failingLog{} }
+
+type failingLog struct{}
+
+func (failingLog) Append(ctx context.Context, entry *models.AuditEntry) error {
+	return errors.New("audit log unavailable")
+}
+
+func (failingLog) Query(ctx context.Context, filter database.AuditFilter) ([]models.AuditEntry, error) {
+	return nil, errors.New("audit log unavailable")
+}
+
+// serve runs a request through Log and a handler that counts its calls
+func serve(action string, target Target) (*httptest.ResponseRecorder, int) {
+	calls := 0
+	router := gin.New()
+	router.Use(RequestID())
+	router.POST("/things/:id", func(c *gin.Context) { c.Set("userID", "admin") }, Log(action, target), func(c *gin.Context) {
+		calls++
+		c.Status(http.StatusOK)
+	})
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/things/t1", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	router.ServeHTTP(rec, req)
+	return rec, calls
+}
+
+func TestDiff(t *testing.T) {
+	before := &models.BadgeDefinition{ID: "b1", Name: "Old", Rule: "cases >= 1"}
+	after := &models.BadgeDefinition{ID: "b1", Name: "New", Rule: "cases >= 1"}
+
+	changes, err := Diff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes["name"].Before != "Old" || changes["name"].After != "New" {
+		t.Errorf("changes = %v, want only the name", changes)
+	}
+
+	created, err := Diff(nil, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created["name"].Before != nil || created["name"].After != "New" {
+		t.Errorf("created name = %+v", created["name"])
+	}
+	deleted, err := Diff(before, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted["id"].Before != "b1" || deleted["id"].After != nil {
+		t.Errorf("deleted id = %+v", deleted["id"])
+	}
+}
+
+func TestWriteUpdateRecordsTheUpdatedFields(t *testing.T) {
+	ctx := context.Background()
+	store := database.NewMemoryStore()
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPut, "/admin/cases/case1", nil)
+	c.Set("userID", "admin")
+
+	before := &models.CoffeeCase{ID: "case1", Name: "Caso", Price: 10}
+	updates := map[string]interface{}{"name": "Caso 2", "price": 10}
+	err := store.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		return WriteUpdate(ctx, tx, c, "case.update", Cases, "case1", before, updates)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := store.Audit().Query(ctx, database.AuditFilter{TargetID: "case1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("%d entries, want 1", len(entries))
+	}
+	entry := entries[0]
+	if entry.Actor != "admin" || entry.Action != "case.update" || entry.TargetType != "case" || entry.Status != http.StatusOK {
+		t.Errorf("entry = %+v", entry)
+	}
+	// The price did not change, so only the name is recorded
+	if len(entry.Changes) != 1 || entry.Changes["name"].After != "Caso 2" {
+		t.Errorf("changes = %v, want only the name", entry.Changes)
+	}
+}
+
+func TestLogWritesTheEntryBeforeTheHandler(t *testing.T) {
+	database.DB = database.NewMemoryStore()
+
+	rec, calls := serve("leaderboard.rebuild", Leaderboard)
+	if rec.Code != http.StatusOK || calls != 1 {
+		t.Fatalf("got %d with %d calls, want 200 with 1", rec.Code, calls)
+	}
+
+	entries, err := database.DB.Audit().Query(context.Background(), database.AuditFilter{Action: "leaderboard.rebuild"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("%d entries, want 1", len(entries))
+	}
+	entry := entries[0]
+	if entry.TargetID != "t1" || entry.RequestID != "req-1" || entry.Status != http.StatusAccepted {
+		t.Errorf("entry = %+v", entry)
+	}
+}
+
+func TestLogRefusesRequestsItCannotRecord(t *testing.T) {
+	database.DB = brokenAudit{database.NewMemoryStore()}
+
+	rec, calls := serve("signing_key.rotate", SigningKeys)
+	if rec.Code != http.StatusInternalServerError || calls != 0 {
+		t.Errorf("got %d with %d calls, want 500 with none", rec.Code, calls)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/", func(c *gin.Context) { c.String(http.StatusOK, c.GetString(requestIDKey)) })
+
+	for header, reused := range map[string]bool{"abc-123": true, "": false, "not valid!": false} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Request-ID", header)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		id := rec.Header().Get("X-Request-ID")
+		if id == "" || id != rec.Body.String() {
+			t.Errorf("%q: echoed %q, handled %q", header, id, rec.Body.String())
+		}
+		if (id == header) != reused {
+			t.Errorf("%q: got ID %q", header, id)
+		}
+	}
+}
@@ -2,143 +2,113 @@ package auth
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"time"
 
 	"brew-detective-backend/internal/database"
 	"brew-detective-backend/internal/models"
+	"brew-detective-backend/internal/roles"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 )
 
-var (
-	googleOauthConfig *oauth2.Config
-	jwtSecret         []byte
-)
-
-type GoogleUser struct {
-	ID            string `json:"id"`
-	Email         string `json:"email"`
-	VerifiedEmail bool   `json:"verified_email"`
-	Name          string `json:"name"`
-	GivenName     string `json:"given_name"`
-	FamilyName    string `json:"family_name"`
-	Picture       string `json:"picture"`
-	Locale        string `json:"locale"`
-}
-
 type Claims struct {
 	UserID string `json:"user_id"`
 	Email  string `json:"email"`
 	Name   string `json:"name"`
+	// SessionID is the session the token was issued for
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
 func InitAuth() {
-	clientID := os.Getenv("GOOGLE_CLIENT_ID")
-	clientSecret := os.Getenv("GOOGLE_CLIENT_SECRET")
-	redirectURL := os.Getenv("GOOGLE_REDIRECT_URL")
-	
-	// Debug logging (don't log secrets in production!)
-	fmt.Printf("OAuth Config - ClientID: %s\n", clientID)
-	fmt.Printf("OAuth Config - RedirectURL: %s\n", redirectURL)
-	fmt.Printf("OAuth Config - ClientSecret present: %v\n", clientSecret != "")
-	
-	googleOauthConfig = &oauth2.Config{
-		RedirectURL:  redirectURL,
-		ClientID:     clientID,
-		ClientSecret: clientSecret,
-		Scopes:       []string{"https://www.googleapis.com/auth/userinfo.email", "https://www.googleapis.com/auth/userinfo.profile"},
-		Endpoint:     google.Endpoint,
-	}
+	// Register the identity providers configured in the environment
+	initMailer()
+	initProviders()
 
 	jwtSecretStr := os.Getenv("JWT_SECRET")
 	if jwtSecretStr == "" {
-		panic("JWT_SECRET environment variable not set")
-	}
-	jwtSecret = []byte(jwtSecretStr)
-}
-
-func GenerateOAuthState(c *gin.Context) string {
-	b := make([]byte, 32) // 32 bytes for better security
-	// Fill with cryptographically secure random data
-	if _, err := rand.Read(b); err != nil {
-		// Fallback to time-based generation if crypto/rand fails
-		timestamp := time.Now().UnixNano()
-		for i := range b {
-			b[i] = byte((timestamp + int64(i)) % 256)
+		// The in-memory backend loses its keys on restart anyway, so it can
+		// boot without a secret
+		if _, ok := database.DB.(*database.MemoryStore); !ok {
+			panic("JWT_SECRET environment variable not set")
+		}
+		secret, err := randomHex(32)
+		if err != nil {
+			panic(fmt.Sprintf("failed to generate a JWT secret: %v", err))
 		}
+		jwtSecretStr = secret
+		log.Println("Warning: JWT_SECRET not set, using a temporary secret for the in-memory backend")
 	}
-	state := fmt.Sprintf("%x", b)
-	
-	// For OAuth flows, we don't use cookies due to cross-domain issues
-	// The state is validated by the OAuth provider and returned in the callback
-	// In a production app with user sessions, you'd store this in a session store
-	
-	return state
-}
 
-func GetGoogleOauthConfig() *oauth2.Config {
-	return googleOauthConfig
+	// Access tokens are signed with rotating key pairs kept in the database;
+	// JWT_SECRET encrypts their private keys
+	algorithm := os.Getenv("JWT_SIGNING_ALG")
+	if algorithm == "" {
+		algorithm = "RS256"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := initKeys(ctx, algorithm, []byte(jwtSecretStr)); err != nil {
+		panic(fmt.Sprintf("failed to initialize signing keys: %v", err))
+	}
 }
 
-func GetUserDataFromGoogle(code string) (*GoogleUser, error) {
-	// Debug: Log OAuth config (without exposing secrets)
-	fmt.Printf("OAuth Exchange - ClientID: %s\n", googleOauthConfig.ClientID)
-	fmt.Printf("OAuth Exchange - RedirectURL: %s\n", googleOauthConfig.RedirectURL)
-	fmt.Printf("OAuth Exchange - ClientSecret present: %v\n", googleOauthConfig.ClientSecret != "")
-	fmt.Printf("OAuth Exchange - Code length: %d\n", len(code))
-	
-	token, err := googleOauthConfig.Exchange(context.Background(), code)
+// generateAccessToken signs a short-lived access token for a session with the
+// current signing key. The token's ID (jti) is what the revocation list is keyed by.
+func generateAccessToken(user *models.User, sessionID string, now time.Time) (string, models.IssuedToken, error) {
+	key, err := keys.signing(now)
 	if err != nil {
-		return nil, fmt.Errorf("code exchange wrong: %s", err.Error())
+		return "", models.IssuedToken{}, err
 	}
-
-	response, err := http.Get("https://www.googleapis.com/oauth2/v2/userinfo?access_token=" + token.AccessToken)
+	jti, err := randomHex(16)
 	if err != nil {
-		return nil, fmt.Errorf("failed getting user info: %s", err.Error())
+		return "", models.IssuedToken{}, err
 	}
-	defer response.Body.Close()
+	issued := models.IssuedToken{JTI: jti, ExpiresAt: now.Add(accessTokenTTL)}
 
-	var user GoogleUser
-	if err := json.NewDecoder(response.Body).Decode(&user); err != nil {
-		return nil, fmt.Errorf("failed reading response body: %s", err.Error())
-	}
-
-	return &user, nil
-}
-
-func GenerateJWT(userID, email, name string) (string, error) {
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
-		Name:   name,
+		UserID:    user.ID,
+		Email:     user.Email,
+		Name:      user.Name,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        jti,
+			Issuer:    tokenIssuer(),
+			ExpiresAt: jwt.NewNumericDate(issued.ExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.ID
+	signed, err := token.SignedString(key.private)
+	if err != nil {
+		return "", models.IssuedToken{}, err
+	}
+	return signed, issued, nil
 }
 
+// ValidateJWT verifies a token against the published key named by its kid header
 func ValidateJWT(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		key, err := keys.verifying(context.Background(), kid)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != key.method.Alg() {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return jwtSecret, nil
-	})
+		return key.public, nil
+	}, jwt.WithIssuer(tokenIssuer()), jwt.WithValidMethods([]string{"RS256", "EdDSA"}))
 
 	if err != nil {
 		return nil, err
@@ -151,92 +121,124 @@ func ValidateJWT(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
-func AuthMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
-			return
-		}
+// ErrTokenRevoked is returned for access tokens whose session was revoked
+var ErrTokenRevoked = errors.New("token has been revoked")
 
-		// Extract token from "Bearer <token>"
-		if len(authHeader) < 7 || authHeader[:7] != "Bearer " {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
-			c.Abort()
-			return
-		}
+// authenticate validates the bearer token of the request and checks it against
+// the revocation list. It writes the error response when authentication fails.
+func authenticate(c *gin.Context) (*Claims, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+		return nil, false
+	}
 
-		tokenString := authHeader[7:]
-		claims, err := ValidateJWT(tokenString)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+	// Extract token from "Bearer <token>"
+	if len(authHeader) < 7 || authHeader[:7] != "Bearer " {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+		return nil, false
+	}
+
+	claims, err := validateAccessToken(c.Request.Context(), authHeader[7:])
+	if errors.Is(err, ErrTokenRevoked) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return nil, false
+	}
+	return claims, true
+}
+
+// validateAccessToken validates a token issued for a session and makes sure
+// it has not been revoked
+func validateAccessToken(ctx context.Context, tokenString string) (*Claims, error) {
+	claims, err := ValidateJWT(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	// Tokens issued before sessions existed cannot be revoked, so they are refused
+	if claims.ID == "" || claims.SessionID == "" {
+		return nil, fmt.Errorf("token is not bound to a session")
+	}
+
+	revoked, err := database.DB.RevokedTokens().IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+func setClaims(c *gin.Context, claims *Claims) {
+	c.Set("userID", claims.UserID)
+	c.Set("email", claims.Email)
+	c.Set("name", claims.Name)
+	c.Set("sessionID", claims.SessionID)
+}
+
+func AuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := authenticate(c)
+		if !ok {
 			c.Abort()
 			return
 		}
 
 		// Add claims to context
-		c.Set("userID", claims.UserID)
-		c.Set("email", claims.Email)
-		c.Set("name", claims.Name)
+		setClaims(c, claims)
 		c.Next()
 	}
 }
 
-// AdminMiddleware ensures the user is authenticated and has admin privileges
-func AdminMiddleware() gin.HandlerFunc {
+// OptionalAuthMiddleware identifies the user when a valid token is sent but
+// lets anonymous requests through
+func OptionalAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// First check if user is authenticated
 		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
-			return
-		}
-
-		// Extract token from "Bearer <token>"
-		if len(authHeader) < 7 || authHeader[:7] != "Bearer " {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
-			c.Abort()
-			return
-		}
-
-		tokenString := authHeader[7:]
-		claims, err := ValidateJWT(tokenString)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
-			return
+		if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+			if claims, err := validateAccessToken(c.Request.Context(), authHeader[7:]); err == nil {
+				setClaims(c, claims)
+			}
 		}
+		c.Next()
+	}
+}
 
-		// Check if user has admin privileges
-		userRef := database.FirestoreClient.Collection(database.UsersCollection).Doc(claims.UserID)
-		doc, err := userRef.Get(c.Request.Context())
-		
-		if err != nil || !doc.Exists() {
+// RequirePermission lets the request through only when the user's roles grant
+// the permission. It runs after AuthMiddleware. Roles are cached briefly, so
+// most staff requests do not read the user from the database.
+func RequirePermission(permission roles.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRoles, err := roles.For(c.Request.Context(), c.GetString("userID"))
+		if errors.Is(err, database.ErrNotFound) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
 			c.Abort()
 			return
 		}
-
-		var user models.User
-		if err := doc.DataTo(&user); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse user data"})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
 			c.Abort()
 			return
 		}
 
-		if user.Type != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin privileges required"})
+		if !roles.Allows(userRoles, permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Permission required", "permission": permission})
 			c.Abort()
 			return
 		}
 
-		// Add claims to context
-		c.Set("userID", claims.UserID)
-		c.Set("email", claims.Email)
-		c.Set("name", claims.Name)
-		c.Set("userType", user.Type)
+		c.Set("roles", userRoles)
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// HasPermission reports whether the authenticated user's roles grant the
+// permission, for handlers that also serve the owner of a resource
+func HasPermission(c *gin.Context, permission roles.Permission) bool {
+	userRoles, err := roles.For(c.Request.Context(), c.GetString("userID"))
+	return err == nil && roles.Allows(userRoles, permission)
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// useMemoryStore points the package at an empty in-memory store with a fresh
+// signing key
+func useMemoryStore(t *testing.T) {
+	t.Helper()
+	database.DB = database.NewMemoryStore()
+	if err := initKeys(context.Background(), "EdDSA", []byte("test secret")); err != nil {
+		t.Fatalf("initializing keys: %v", err)
+	}
+}
+
+func seedUser(t *testing.T, user *models.User) *models.User {
+	t.Helper()
+	if err := database.DB.Users().Save(context.Background(), user); err != nil {
+		t.Fatalf("saving user: %v", err)
+	}
+	return user
+}
+
+func TestInitAuthWithoutSecretOnMemoryStore(t *testing.T) {
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("JWT_SIGNING_ALG", "EdDSA")
+	database.DB = database.NewMemoryStore()
+
+	InitAuth()
+	if _, err := keys.signing(time.Now()); err != nil {
+		t.Fatalf("no signing key after InitAuth: %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+)
+
+var (
+	// ErrIdentityInUse is returned when linking an account that belongs to another user
+	ErrIdentityInUse = errors.New("identity is linked to another user")
+	// ErrLastIdentity is returned when unlinking the only way a user can log in
+	ErrLastIdentity = errors.New("cannot unlink the last identity")
+)
+
+// newUserID generates the internal ID of a new user. It is unrelated to the
+// IDs the user has at their identity providers.
+func newUserID() (string, error) {
+	return randomHex(12)
+}
+
+// ResolveUser returns the user an identity logs in as, creating the user on
+// first login. An unknown identity is attached to an existing user when it is
+// a Google account from before identities were stored, whose user ID is the
+// Google ID, or when the provider verified an email that a user has verified
+// too. A user whose email is not verified has to link other identities from a
+// signed-in session, so nobody can claim an account by signing up with its
+// email first.
+func ResolveUser(ctx context.Context, identity *ProviderIdentity) (*models.User, error) {
+	var user *models.User
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		now := time.Now()
+		id := models.IdentityID(identity.Provider, identity.Subject)
+
+		linked, err := tx.Identities().Get(ctx, id)
+		switch {
+		case err == nil:
+			if user, err = tx.Users().Get(ctx, linked.UserID); err != nil {
+				return err
+			}
+		case errors.Is(err, database.ErrNotFound):
+			if user, err = existingUserFor(ctx, tx, identity); errors.Is(err, database.ErrNotFound) {
+				if user, err = newUser(now); err != nil {
+					return err
+				}
+			} else if err != nil {
+				return err
+			}
+			linked = &models.Identity{
+				ID:        id,
+				UserID:    user.ID,
+				Provider:  identity.Provider,
+				Subject:   identity.Subject,
+				CreatedAt: now,
+			}
+		default:
+			return err
+		}
+
+		linked.Email = identity.Email
+		linked.LastLoginAt = now
+		updateProfile(user, identity, now)
+
+		if err := tx.Users().Save(ctx, user); err != nil {
+			return err
+		}
+		return tx.Identities().Save(ctx, linked)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// existingUserFor finds the user an unlinked identity belongs to
+func existingUserFor(ctx context.Context, store database.Store, identity *ProviderIdentity) (*models.User, error) {
+	if identity.Provider == "google" {
+		user, err := store.Users().Get(ctx, identity.Subject)
+		if !errors.Is(err, database.ErrNotFound) {
+			return user, err
+		}
+	}
+	if identity.EmailVerified && identity.Email != "" {
+		return store.Users().GetByVerifiedEmail(ctx, identity.Email)
+	}
+	return nil, database.ErrNotFound
+}
+
+func newUser(now time.Time) (*models.User, error) {
+	id, err := newUserID()
+	if err != nil {
+		return nil, err
+	}
+	return &models.User{
+		ID:        id,
+		Type:      "regular", // Default to regular user
+		Badges:    []models.UserBadge{},
+		CreatedAt: now,
+	}, nil
+}
+
+// updateProfile fills in the profile fields the user does not have yet. A
+// custom name is never replaced. The email is marked verified once an
+// identity whose provider verified it logs in.
+func updateProfile(user *models.User, identity *ProviderIdentity, now time.Time) {
+	if user.Name == "" {
+		user.Name = identity.Name
+	}
+	if user.Email == "" {
+		user.Email = identity.Email
+	}
+	if identity.EmailVerified && identity.Email != "" && user.Email == identity.Email {
+		user.EmailVerified = true
+	}
+	if identity.Picture != "" {
+		user.Picture = identity.Picture
+	}
+	if user.Type == "" {
+		user.Type = "regular"
+	}
+	user.UpdatedAt = now
+}
+
+// LinkIdentity attaches an identity to a signed-in user
+func LinkIdentity(ctx context.Context, userID string, identity *ProviderIdentity) (*models.Identity, error) {
+	var linked *models.Identity
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		now := time.Now()
+		id := models.IdentityID(identity.Provider, identity.Subject)
+
+		existing, err := tx.Identities().Get(ctx, id)
+		switch {
+		case err == nil:
+			if existing.UserID != userID {
+				return ErrIdentityInUse
+			}
+			linked = existing
+		case errors.Is(err, database.ErrNotFound):
+			linked = &models.Identity{
+				ID:        id,
+				UserID:    userID,
+				Provider:  identity.Provider,
+				Subject:   identity.Subject,
+				CreatedAt: now,
+			}
+		default:
+			return err
+		}
+		user, err := tx.Users().Get(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		linked.Email = identity.Email
+		linked.LastLoginAt = now
+		if identity.EmailVerified && identity.Email != "" && user.Email == identity.Email && !user.EmailVerified {
+			user.EmailVerified = true
+			user.UpdatedAt = now
+			if err := tx.Users().Save(ctx, user); err != nil {
+				return err
+			}
+		}
+		return tx.Identities().Save(ctx, linked)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return linked, nil
+}
+
+// UnlinkIdentity removes one of a user's identities, keeping at least one so
+// the user can still log in
+func UnlinkIdentity(ctx context.Context, userID, identityID string) error {
+	return database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		identities, err := tx.Identities().ListByUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		found := false
+		for _, identity := range identities {
+			if identity.ID == identityID {
+				found = true
+			}
+		}
+		if !found {
+			return database.ErrNotFound
+		}
+		if len(identities) == 1 {
+			return ErrLastIdentity
+		}
+		return tx.Identities().Delete(ctx, identityID)
+	})
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"brew-detective-backend/internal/models"
+)
+
+func TestResolveUserCreatesUsers(t *testing.T) {
+	useMemoryStore(t)
+	ctx := context.Background()
+	identity := &ProviderIdentity{Provider: "github", Subject: "42", Email: "ana@example.com", EmailVerified: true, Name: "Ana"}
+
+	user, err := ResolveUser(ctx, identity)
+	if err != nil {
+		t.Fatalf("ResolveUser: %v", err)
+	}
+	if user.Email != "ana@example.com" || !user.EmailVerified || user.Name != "Ana" {
+		t.Errorf("new user = %+v", user)
+	}
+
+	again, err := ResolveUser(ctx, identity)
+	if err != nil || again.ID != user.ID {
+		t.Errorf("the same identity logged in as %q, want %q (%v)", again.ID, user.ID, err)
+	}
+}
+
+func TestResolveUserJoinsVerifiedEmails(t *testing.T) {
+	useMemoryStore(t)
+	ctx := context.Background()
+	existing := seedUser(t, &models.User{ID: "user-1", Email: "ana@example.com", EmailVerified: true})
+
+	user, err := ResolveUser(ctx, &ProviderIdentity{Provider: EmailProvider, Subject: "ana@example.com", Email: "ana@example.com", EmailVerified: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != existing.ID {
+		t.Errorf("verified email logged in as %q, want %q", user.ID, existing.ID)
+	}
+
+	// An email the provider did not verify never joins
+	user, err = ResolveUser(ctx, &ProviderIdentity{Provider: "oidc", Subject: "7", Email: "ana@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID == existing.ID {
+		t.Error("an unverified email joined an existing user")
+	}
+}
+
+func TestResolveUserDoesNotJoinUnverifiedUsers(t *testing.T) {
+	useMemoryStore(t)
+	ctx := context.Background()
+
+	// Someone signs up first with the victim's email at a provider that does not verify it
+	squatter, err := ResolveUser(ctx, &ProviderIdentity{Provider: "oidc", Subject: "squatter", Email: "ana@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if squatter.EmailVerified {
+		t.Fatal("an unverified email was marked verified")
+	}
+
+	victim, err := ResolveUser(ctx, &ProviderIdentity{Provider: "google", Subject: "ana", Email: "ana@example.com", EmailVerified: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if victim.ID == squatter.ID {
+		t.Fatal("a verified identity joined a user whose email is not verified")
+	}
+}
+
+func TestLinkIdentityVerifiesEmail(t *testing.T) {
+	useMemoryStore(t)
+	ctx := context.Background()
+	user, err := ResolveUser(ctx, &ProviderIdentity{Provider: "oidc", Subject: "1", Email: "ana@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Linking an email login link from the signed-in session proves the email
+	link := &ProviderIdentity{Provider: EmailProvider, Subject: "ana@example.com", Email: "ana@example.com", EmailVerified: true}
+	if _, err := LinkIdentity(ctx, user.ID, link); err != nil {
+		t.Fatalf("LinkIdentity: %v", err)
+	}
+
+	joined, err := ResolveUser(ctx, &ProviderIdentity{Provider: "github", Subject: "2", Email: "ana@example.com", EmailVerified: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if joined.ID != user.ID {
+		t.Errorf("after verifying, the email logged in as %q, want %q", joined.ID, user.ID)
+	}
+}
@@ -0,0 +1,367 @@
+package auth
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+	"brew-detective-backend/internal/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrUnknownKey is returned for tokens signed with a key that is not published
+var ErrUnknownKey = errors.New("unknown signing key")
+
+var (
+	// keyRotation is how long each key signs tokens before the next one takes over
+	keyRotation = utils.DurationFromEnv("JWT_KEY_ROTATION", 30*24*time.Hour)
+	// keyOverlap is how long a new key is published before it starts signing,
+	// and how long a retired key stays published so its tokens can be verified
+	keyOverlap = utils.DurationFromEnv("JWT_KEY_OVERLAP", 24*time.Hour)
+	// keyCheckInterval is how often the rotation schedule is checked
+	keyCheckInterval = time.Hour
+	// keyReloadInterval limits how often an unknown kid triggers a reload of the
+	// keys, which happens after another instance rotated them
+	keyReloadInterval = time.Minute
+)
+
+// signingAlgorithms are the supported values of JWT_SIGNING_ALG
+var signingAlgorithms = map[string]jwt.SigningMethod{
+	"RS256": jwt.SigningMethodRS256,
+	"EdDSA": jwt.SigningMethodEdDSA,
+}
+
+// loadedKey is a stored key with its private key decrypted
+type loadedKey struct {
+	models.SigningKey
+	method  jwt.SigningMethod
+	private interface{}
+	public  interface{}
+}
+
+// keyRing caches the signing keys of the database
+type keyRing struct {
+	mu        sync.RWMutex
+	keys      []loadedKey // Ordered by NotBefore
+	loadedAt  time.Time
+	algorithm string
+	secret    []byte // Encrypts private keys at rest
+}
+
+var keys = &keyRing{}
+
+// initKeys sets up the key ring and makes sure a key is ready to sign
+func initKeys(ctx context.Context, algorithm string, secret []byte) error {
+	if _, ok := signingAlgorithms[algorithm]; !ok {
+		return fmt.Errorf("unsupported JWT_SIGNING_ALG %q", algorithm)
+	}
+	sum := sha256.Sum256(secret)
+	keys.algorithm = algorithm
+	keys.secret = sum[:]
+	return RotateKeys(ctx, false)
+}
+
+// retention is how long a retired key stays published. It is never shorter
+// than the lifetime of the tokens the key signed.
+func retention() time.Duration {
+	if keyOverlap < accessTokenTTL {
+		return accessTokenTTL
+	}
+	return keyOverlap
+}
+
+// RotateKeys applies the rotation schedule: it creates a signing key when there
+// is none, publishes the next key keyOverlap before the current one retires and
+// deletes keys that are no longer published. With force, the current key is
+// retired at once and a new key starts signing immediately.
+func RotateKeys(ctx context.Context, force bool) error {
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		stored, err := tx.SigningKeys().List(ctx)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		var current, latest *models.SigningKey
+		for i := range stored {
+			key := &stored[i]
+			if !now.Before(key.ExpiresAt) {
+				if err := tx.SigningKeys().Delete(ctx, key.ID); err != nil {
+					return err
+				}
+				continue
+			}
+			if !key.NotBefore.After(now) && now.Before(key.RetiresAt) {
+				current = key
+			}
+			latest = key
+		}
+
+		switch {
+		case current == nil || force:
+			if current != nil {
+				current.RetiresAt = now
+				current.ExpiresAt = now.Add(retention())
+				if err := tx.SigningKeys().Save(ctx, current); err != nil {
+					return err
+				}
+			}
+			return keys.create(ctx, tx, now)
+		case latest == current && !now.Before(current.RetiresAt.Add(-keyOverlap)):
+			return keys.create(ctx, tx, current.RetiresAt)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return keys.reload(ctx)
+}
+
+// create generates a key that starts signing at notBefore
+func (r *keyRing) create(ctx context.Context, store database.Store, notBefore time.Time) error {
+	var private interface{}
+	var err error
+	switch r.algorithm {
+	case "EdDSA":
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return err
+	}
+	encrypted, err := r.encrypt(der)
+	if err != nil {
+		return err
+	}
+	kid, err := randomHex(8)
+	if err != nil {
+		return err
+	}
+
+	retiresAt := notBefore.Add(keyRotation)
+	return store.SigningKeys().Save(ctx, &models.SigningKey{
+		ID:         kid,
+		Algorithm:  r.algorithm,
+		PrivateKey: encrypted,
+		CreatedAt:  time.Now(),
+		NotBefore:  notBefore,
+		RetiresAt:  retiresAt,
+		ExpiresAt:  retiresAt.Add(retention()),
+	})
+}
+
+// reload replaces the cached keys with the ones in the database
+func (r *keyRing) reload(ctx context.Context) error {
+	stored, err := database.DB.SigningKeys().List(ctx)
+	if err != nil {
+		return err
+	}
+
+	loaded := make([]loadedKey, 0, len(stored))
+	for _, key := range stored {
+		method, ok := signingAlgorithms[key.Algorithm]
+		if !ok {
+			return fmt.Errorf("signing key %s uses unsupported algorithm %q", key.ID, key.Algorithm)
+		}
+		der, err := r.decrypt(key.PrivateKey)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt signing key %s: %w", key.ID, err)
+		}
+		private, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return fmt.Errorf("failed to parse signing key %s: %w", key.ID, err)
+		}
+
+		entry := loadedKey{SigningKey: key, method: method, private: private}
+		switch private := private.(type) {
+		case *rsa.PrivateKey:
+			entry.public = &private.PublicKey
+		case ed25519.PrivateKey:
+			entry.public = private.Public()
+		default:
+			return fmt.Errorf("signing key %s has an unsupported key type", key.ID)
+		}
+		loaded = append(loaded, entry)
+	}
+
+	r.mu.Lock()
+	r.keys = loaded
+	r.loadedAt = time.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+// signing returns the key that signs tokens at the given time
+func (r *keyRing) signing(now time.Time) (*loadedKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var current *loadedKey
+	for i := range r.keys {
+		key := &r.keys[i]
+		if !key.NotBefore.After(now) && now.Before(key.RetiresAt) {
+			current = key
+		}
+	}
+	if current == nil {
+		return nil, errors.New("no signing key is active")
+	}
+	return current, nil
+}
+
+// verifying returns the published key with the given ID, reloading the keys
+// once in a while when the ID is unknown
+func (r *keyRing) verifying(ctx context.Context, kid string) (*loadedKey, error) {
+	if key := r.find(kid); key != nil {
+		return key, nil
+	}
+
+	r.mu.RLock()
+	stale := time.Since(r.loadedAt) > keyReloadInterval
+	r.mu.RUnlock()
+	if stale {
+		if err := r.reload(ctx); err != nil {
+			return nil, err
+		}
+		if key := r.find(kid); key != nil {
+			return key, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+func (r *keyRing) find(kid string) *loadedKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	for i := range r.keys {
+		if r.keys[i].ID == kid && now.Before(r.keys[i].ExpiresAt) {
+			return &r.keys[i]
+		}
+	}
+	return nil
+}
+
+func (r *keyRing) encrypt(plaintext []byte) ([]byte, error) {
+	gcm, err := r.cipher()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (r *keyRing) decrypt(ciphertext []byte) ([]byte, error) {
+	gcm, err := r.cipher()
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, nil)
+}
+
+func (r *keyRing) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(r.secret)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// StartKeyRotation checks the rotation schedule periodically until ctx is done
+func StartKeyRotation(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(keyCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				rotateCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+				if err := RotateKeys(rotateCtx, false); err != nil {
+					log.Printf("Failed to rotate signing keys: %v", err)
+				}
+				cancel()
+			}
+		}
+	}()
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	N         string `json:"n,omitempty"`   // RSA modulus
+	E         string `json:"e,omitempty"`   // RSA exponent
+	Curve     string `json:"crv,omitempty"` // OKP curve
+	X         string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKS returns the published public keys: the signing key, the next key once
+// it is announced and retired keys whose tokens may still be valid
+func JWKS() []JWK {
+	keys.mu.RLock()
+	defer keys.mu.RUnlock()
+
+	now := time.Now()
+	published := []JWK{}
+	for _, key := range keys.keys {
+		if !now.Before(key.ExpiresAt) {
+			continue
+		}
+		jwk := JWK{KeyID: key.ID, Algorithm: key.Algorithm, Use: "sig"}
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+		published = append(published, jwk)
+	}
+	return published
+}
+
+// tokenIssuer is the iss claim of access tokens
+func tokenIssuer() string {
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "brew-detective"
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+	"brew-detective-backend/internal/utils"
+)
+
+// EmailProvider is the provider name of passwordless email logins
+const EmailProvider = "email"
+
+var (
+	// ErrEmailLoginDisabled is returned when no mailer is configured
+	ErrEmailLoginDisabled = errors.New("email login is not enabled")
+	// ErrInvalidEmail is returned for malformed email addresses
+	ErrInvalidEmail = errors.New("invalid email address")
+	// ErrInvalidLoginLink is returned for login links that were never sent, expired or were already used
+	ErrInvalidLoginLink = errors.New("invalid or expired login link")
+)
+
+// loginLinkTTL is how long an emailed login link can be used
+var loginLinkTTL = utils.DurationFromEnv("EMAIL_LINK_TTL", 15*time.Minute)
+
+// Mailer sends login links
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// mailer is nil while email login is disabled
+var mailer Mailer
+
+// SetMailer replaces the mailer used for login links
+func SetMailer(m Mailer) {
+	mailer = m
+}
+
+// EmailLoginEnabled reports whether login links can be sent
+func EmailLoginEnabled() bool {
+	return mailer != nil
+}
+
+// initMailer enables email login with SMTP when SMTP_HOST is set, or by
+// logging the links when EMAIL_LINKS_LOG is true (for local development)
+func initMailer() {
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		m := &smtpMailer{addr: host + ":" + port, from: os.Getenv("SMTP_FROM")}
+		if username := os.Getenv("SMTP_USERNAME"); username != "" {
+			m.auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+		}
+		SetMailer(m)
+	} else if os.Getenv("EMAIL_LINKS_LOG") == "true" {
+		SetMailer(logMailer{})
+	}
+}
+
+// smtpMailer sends email through an SMTP server
+type smtpMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func (m *smtpMailer) Send(ctx context.Context, to, subject, body string) error {
+	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		m.from, to, subject, body)
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{to}, []byte(message))
+}
+
+// logMailer writes emails to the server log instead of sending them
+type logMailer struct{}
+
+func (logMailer) Send(ctx context.Context, to, subject, body string) error {
+	log.Printf("Email to %s: %s\n%s", to, subject, body)
+	return nil
+}
+
+// NormalizeEmail lowercases an address and checks that it is valid
+func NormalizeEmail(email string) (string, error) {
+	address, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil || address.Address != strings.TrimSpace(email) {
+		return "", ErrInvalidEmail
+	}
+	return strings.ToLower(address.Address), nil
+}
+
+// SendLoginLink emails a single-use login link. verifyURL is the address of
+// the endpoint that redeems it; the token is appended as a query parameter.
+// With linkUserID the email is linked to that user instead of logging in.
+func SendLoginLink(ctx context.Context, email, linkUserID, verifyURL string) error {
+	if mailer == nil {
+		return ErrEmailLoginDisabled
+	}
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return err
+	}
+
+	token, err := randomHex(32)
+	if err != nil {
+		return fmt.Errorf("failed to generate login link: %w", err)
+	}
+	now := time.Now()
+	link := &models.LoginLink{
+		ID:         hashSecret(token),
+		Email:      email,
+		LinkUserID: linkUserID,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(loginLinkTTL),
+	}
+	if err := database.DB.LoginLinks().Save(ctx, link); err != nil {
+		return fmt.Errorf("failed to save login link: %w", err)
+	}
+
+	body := fmt.Sprintf("Usa este enlace para entrar a Brew Detective. Caduca en %d minutos.\n\n%s?token=%s\n\nSi no lo pediste, ignora este correo.",
+		int(loginLinkTTL.Minutes()), verifyURL, token)
+	return mailer.Send(ctx, email, "Tu enlace para entrar a Brew Detective", body)
+}
+
+// ConsumeLoginLink redeems a login link and returns the verified email
+// identity and, when linking, the user to link it to
+func ConsumeLoginLink(ctx context.Context, token string) (*ProviderIdentity, string, error) {
+	var link *models.LoginLink
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		var err error
+		link, err = tx.LoginLinks().Get(ctx, hashSecret(token))
+		if errors.Is(err, database.ErrNotFound) {
+			return ErrInvalidLoginLink
+		}
+		if err != nil {
+			return err
+		}
+		return tx.LoginLinks().Delete(ctx, link.ID)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if time.Now().After(link.ExpiresAt) {
+		return nil, "", ErrInvalidLoginLink
+	}
+
+	return &ProviderIdentity{
+		Provider:      EmailProvider,
+		Subject:       link.Email,
+		Email:         link.Email,
+		EmailVerified: true,
+		Name:          strings.SplitN(link.Email, "@", 2)[0],
+	}, link.LinkUserID, nil
+}
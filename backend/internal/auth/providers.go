@@ -0,0 +1,301 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+	"golang.org/x/oauth2/google"
+)
+
+// ErrUnknownProvider is returned for providers that are not configured
+var ErrUnknownProvider = errors.New("unknown identity provider")
+
+// ProviderIdentity is an account as reported by an identity provider
+type ProviderIdentity struct {
+	Provider      string
+	Subject       string // Stable account ID at the provider
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+}
+
+// Provider is an identity provider that logs users in with an OAuth 2.0
+// authorization code redirect
+type Provider interface {
+	Name() string
+	AuthCodeURL(state string, opts ...oauth2.AuthCodeOption) string
+	// Exchange redeems the authorization code and returns the account that logged in
+	Exchange(ctx context.Context, code, verifier string) (*ProviderIdentity, error)
+}
+
+var providers = map[string]Provider{}
+
+// RegisterProvider makes a provider available for login
+func RegisterProvider(provider Provider) {
+	providers[provider.Name()] = provider
+}
+
+// GetProvider returns a configured provider by name
+func GetProvider(name string) (Provider, error) {
+	provider, ok := providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return provider, nil
+}
+
+// ProviderNames lists the configured OAuth providers
+func ProviderNames() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// initProviders registers every provider that has credentials in the environment
+func initProviders() {
+	if clientID := os.Getenv("GOOGLE_CLIENT_ID"); clientID != "" {
+		RegisterProvider(&googleProvider{config: &oauth2.Config{
+			RedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"),
+			ClientID:     clientID,
+			ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
+			Scopes:       []string{"https://www.googleapis.com/auth/userinfo.email", "https://www.googleapis.com/auth/userinfo.profile"},
+			Endpoint:     google.Endpoint,
+		}})
+	}
+
+	if clientID := os.Getenv("GITHUB_CLIENT_ID"); clientID != "" {
+		RegisterProvider(&githubProvider{config: &oauth2.Config{
+			RedirectURL:  os.Getenv("GITHUB_REDIRECT_URL"),
+			ClientID:     clientID,
+			ClientSecret: os.Getenv("GITHUB_CLIENT_SECRET"),
+			Scopes:       []string{"read:user", "user:email"},
+			Endpoint:     github.Endpoint,
+		}})
+	}
+
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		name := os.Getenv("OIDC_PROVIDER_NAME")
+		if name == "" {
+			name = "oidc"
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		provider, err := discoverOIDC(ctx, name, issuer, &oauth2.Config{
+			RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			Scopes:       []string{"openid", "email", "profile"},
+		})
+		if err != nil {
+			log.Printf("Failed to configure OIDC provider %s: %v", name, err)
+		} else {
+			RegisterProvider(provider)
+		}
+	}
+
+	log.Printf("Identity providers: %v (email links enabled: %v)", ProviderNames(), mailer != nil)
+}
+
+// fetchJSON GETs a URL with the provider's access token and decodes the response
+func fetchJSON(ctx context.Context, client *http.Client, url string, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	response, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed getting %s: %w", url, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed getting %s: status %d", url, response.StatusCode)
+	}
+	if err := json.NewDecoder(response.Body).Decode(dst); err != nil {
+		return fmt.Errorf("failed reading response body: %w", err)
+	}
+	return nil
+}
+
+// googleProvider logs users in with their Google account
+type googleProvider struct {
+	config *oauth2.Config
+}
+
+func (p *googleProvider) Name() string { return "google" }
+
+func (p *googleProvider) AuthCodeURL(state string, opts ...oauth2.AuthCodeOption) string {
+	return p.config.AuthCodeURL(state, opts...)
+}
+
+func (p *googleProvider) Exchange(ctx context.Context, code, verifier string) (*ProviderIdentity, error) {
+	token, err := p.config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("code exchange wrong: %s", err.Error())
+	}
+
+	var user struct {
+		ID            string `json:"id"`
+		Email         string `json:"email"`
+		VerifiedEmail bool   `json:"verified_email"`
+		Name          string `json:"name"`
+		Picture       string `json:"picture"`
+	}
+	if err := fetchJSON(ctx, p.config.Client(ctx, token), "https://www.googleapis.com/oauth2/v2/userinfo", &user); err != nil {
+		return nil, err
+	}
+
+	return &ProviderIdentity{
+		Provider:      p.Name(),
+		Subject:       user.ID,
+		Email:         user.Email,
+		EmailVerified: user.VerifiedEmail,
+		Name:          user.Name,
+		Picture:       user.Picture,
+	}, nil
+}
+
+// githubProvider logs users in with their GitHub account
+type githubProvider struct {
+	config *oauth2.Config
+}
+
+func (p *githubProvider) Name() string { return "github" }
+
+func (p *githubProvider) AuthCodeURL(state string, opts ...oauth2.AuthCodeOption) string {
+	return p.config.AuthCodeURL(state, opts...)
+}
+
+func (p *githubProvider) Exchange(ctx context.Context, code, verifier string) (*ProviderIdentity, error) {
+	token, err := p.config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("code exchange wrong: %s", err.Error())
+	}
+	client := p.config.Client(ctx, token)
+
+	var user struct {
+		ID        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := fetchJSON(ctx, client, "https://api.github.com/user", &user); err != nil {
+		return nil, err
+	}
+
+	// The profile email may be hidden, so use the primary address instead
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := fetchJSON(ctx, client, "https://api.github.com/user/emails", &emails); err != nil {
+		return nil, err
+	}
+
+	identity := &ProviderIdentity{
+		Provider: p.Name(),
+		Subject:  strconv.FormatInt(user.ID, 10),
+		Name:     user.Name,
+		Picture:  user.AvatarURL,
+	}
+	if identity.Name == "" {
+		identity.Name = user.Login
+	}
+	for _, email := range emails {
+		if email.Primary {
+			identity.Email = email.Email
+			identity.EmailVerified = email.Verified
+		}
+	}
+	return identity, nil
+}
+
+// oidcProvider logs users in with any OpenID Connect provider. Its endpoints
+// are read from the issuer's discovery document.
+type oidcProvider struct {
+	name        string
+	config      *oauth2.Config
+	userInfoURL string
+}
+
+// discoverOIDC reads the issuer's /.well-known/openid-configuration
+func discoverOIDC(ctx context.Context, name, issuer string, config *oauth2.Config) (*oidcProvider, error) {
+	var discovery struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	}
+	url := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	if err := fetchJSON(ctx, http.DefaultClient, url, &discovery); err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return nil, fmt.Errorf("discovery document is for issuer %q", discovery.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.UserInfoEndpoint == "" {
+		return nil, errors.New("discovery document is missing endpoints")
+	}
+
+	config.Endpoint = oauth2.Endpoint{
+		AuthURL:  discovery.AuthorizationEndpoint,
+		TokenURL: discovery.TokenEndpoint,
+	}
+	return &oidcProvider{name: name, config: config, userInfoURL: discovery.UserInfoEndpoint}, nil
+}
+
+func (p *oidcProvider) Name() string { return p.name }
+
+func (p *oidcProvider) AuthCodeURL(state string, opts ...oauth2.AuthCodeOption) string {
+	return p.config.AuthCodeURL(state, opts...)
+}
+
+func (p *oidcProvider) Exchange(ctx context.Context, code, verifier string) (*ProviderIdentity, error) {
+	token, err := p.config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("code exchange wrong: %s", err.Error())
+	}
+
+	// The userinfo endpoint is fetched over TLS with the access token, so its
+	// claims come from the provider without verifying the ID token's signature
+	var claims struct {
+		Subject       string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+		Picture       string `json:"picture"`
+	}
+	if err := fetchJSON(ctx, p.config.Client(ctx, token), p.userInfoURL, &claims); err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("userinfo response has no subject")
+	}
+
+	return &ProviderIdentity{
+		Provider:      p.Name(),
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+		Picture:       claims.Picture,
+	}, nil
+}
@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+	"brew-detective-backend/internal/utils"
+)
+
+var (
+	// ErrInvalidRefreshToken is returned for refresh tokens that are malformed,
+	// expired or belong to a revoked session
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token is
+	// presented again. The session is revoked because the token may have leaked.
+	ErrRefreshTokenReused = errors.New("refresh token was already used")
+)
+
+var (
+	// accessTokenTTL is kept short because access tokens are only checked
+	// against the revocation list, not against the session
+	accessTokenTTL = utils.DurationFromEnv("ACCESS_TOKEN_TTL", 15*time.Minute)
+	// refreshTokenTTL is how long a session lasts without being refreshed
+	refreshTokenTTL = utils.DurationFromEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+)
+
+// TokenPair is returned when a session is created or refreshed
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // Lifetime of the access token in seconds
+}
+
+// IssueSession starts a new session for the user and returns its first tokens
+func IssueSession(ctx context.Context, user *models.User, userAgent, ipAddress string) (*TokenPair, error) {
+	sessionID, err := randomHex(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate session ID: %w", err)
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		ID:         sessionID,
+		UserID:     user.ID,
+		UserAgent:  userAgent,
+		IPAddress:  ipAddress,
+		CreatedAt:  now,
+		LastUsedAt: now,
+	}
+	pair, err := rotate(session, user, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := database.DB.Sessions().Save(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+	return pair, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. The refresh token is
+// rotated, so each one can only be used once.
+func Refresh(ctx context.Context, refreshToken, userAgent, ipAddress string) (*TokenPair, error) {
+	sessionID, _, ok := strings.Cut(refreshToken, ".")
+	if !ok || sessionID == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	var pair *TokenPair
+	reused := false
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		session, err := tx.Sessions().Get(ctx, sessionID)
+		if errors.Is(err, database.ErrNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+		user, err := tx.Users().Get(ctx, session.UserID)
+		if errors.Is(err, database.ErrNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		hash := hashSecret(refreshToken)
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(session.PreviousRefreshHash)) == 1 {
+			// Commit the revocation, then report the reuse
+			reused = true
+			return revoke(ctx, tx, session, now)
+		}
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(session.RefreshTokenHash)) != 1 {
+			return ErrInvalidRefreshToken
+		}
+
+		session.UserAgent = userAgent
+		session.IPAddress = ipAddress
+		session.LastUsedAt = now
+		pair, err = rotate(session, user, now)
+		if err != nil {
+			return err
+		}
+		return tx.Sessions().Save(ctx, session)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, ErrRefreshTokenReused
+	}
+	return pair, nil
+}
+
+// rotate issues a new refresh token and access token for the session and
+// extends its expiry. The caller saves the session.
+func rotate(session *models.Session, user *models.User, now time.Time) (*TokenPair, error) {
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	refreshToken := session.ID + "." + secret
+
+	accessToken, issued, err := generateAccessToken(user, session.ID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	// Only unexpired access tokens need to be revoked with the session
+	tokens := []models.IssuedToken{issued}
+	for _, token := range session.AccessTokens {
+		if token.ExpiresAt.After(now) {
+			tokens = append(tokens, token)
+		}
+	}
+
+	session.PreviousRefreshHash = session.RefreshTokenHash
+	session.RefreshTokenHash = hashSecret(refreshToken)
+	session.AccessTokens = tokens
+	session.ExpiresAt = now.Add(refreshTokenTTL)
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(accessTokenTTL.Seconds()),
+	}, nil
+}
+
+// IsActive reports whether the session can still be refreshed
+func IsActive(session *models.Session, now time.Time) bool {
+	return session.RevokedAt == nil && now.Before(session.ExpiresAt)
+}
+
+// RevokeSession ends a session: its refresh token stops working and its
+// access tokens are added to the revocation list
+func RevokeSession(ctx context.Context, sessionID string) error {
+	return database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		session, err := tx.Sessions().Get(ctx, sessionID)
+		if err != nil {
+			return err
+		}
+		if session.RevokedAt != nil {
+			return nil
+		}
+		return revoke(ctx, tx, session, time.Now())
+	})
+}
+
+// RevokeAllSessions ends every active session of a user and returns how many were revoked
+func RevokeAllSessions(ctx context.Context, userID string) (int, error) {
+	sessions, err := database.DB.Sessions().ListByUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for i := range sessions {
+		if !IsActive(&sessions[i], time.Now()) {
+			continue
+		}
+		if err := RevokeSession(ctx, sessions[i].ID); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// revoke only writes, so it can run at the end of a transaction
+func revoke(ctx context.Context, store database.Store, session *models.Session, now time.Time) error {
+	for _, token := range session.AccessTokens {
+		if !token.ExpiresAt.After(now) {
+			continue
+		}
+		if err := store.RevokedTokens().Add(ctx, token); err != nil {
+			return err
+		}
+	}
+
+	session.RevokedAt = &now
+	session.AccessTokens = nil
+	return store.Sessions().Save(ctx, session)
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+)
+
+func TestRefreshRotatesTokens(t *testing.T) {
+	useMemoryStore(t)
+	ctx := context.Background()
+	user := seedUser(t, &models.User{ID: "user-1", Name: "Ana"})
+
+	first, err := IssueSession(ctx, user, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("IssueSession: %v", err)
+	}
+	claims, err := validateAccessToken(ctx, first.AccessToken)
+	if err != nil || claims.UserID != user.ID || claims.SessionID == "" {
+		t.Fatalf("access token claims %+v: %v", claims, err)
+	}
+
+	second, err := Refresh(ctx, first.RefreshToken, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == first.AccessToken {
+		t.Fatal("Refresh did not rotate the tokens")
+	}
+	if _, err := Refresh(ctx, second.RefreshToken, "test", "127.0.0.1"); err != nil {
+		t.Errorf("refreshing with the rotated token: %v", err)
+	}
+
+	for _, token := range []string{"", "no-dot", "unknown.secret", claims.SessionID + ".wrong"} {
+		if _, err := Refresh(ctx, token, "test", "127.0.0.1"); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("Refresh(%q) = %v, want ErrInvalidRefreshToken", token, err)
+		}
+	}
+}
+
+func TestRefreshTokenReuseRevokesTheSession(t *testing.T) {
+	useMemoryStore(t)
+	ctx := context.Background()
+	user := seedUser(t, &models.User{ID: "user-1"})
+
+	first, err := IssueSession(ctx, user, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Refresh(ctx, first.RefreshToken, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The old token is presented again, as if it had leaked
+	if _, err := Refresh(ctx, first.RefreshToken, "attacker", "10.0.0.1"); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reusing a rotated token = %v, want ErrRefreshTokenReused", err)
+	}
+
+	// The whole session is gone: its current refresh token and every access token
+	if _, err := Refresh(ctx, second.RefreshToken, "test", "127.0.0.1"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("refreshing a revoked session = %v, want ErrInvalidRefreshToken", err)
+	}
+	for _, token := range []string{first.AccessToken, second.AccessToken} {
+		if _, err := validateAccessToken(ctx, token); !errors.Is(err, ErrTokenRevoked) {
+			t.Errorf("access token of a revoked session = %v, want ErrTokenRevoked", err)
+		}
+	}
+}
+
+func TestRevokeAllSessions(t *testing.T) {
+	useMemoryStore(t)
+	ctx := context.Background()
+	user := seedUser(t, &models.User{ID: "user-1"})
+	other := seedUser(t, &models.User{ID: "user-2"})
+
+	laptop, err := IssueSession(ctx, user, "laptop", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	phone, err := IssueSession(ctx, user, "phone", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, err := IssueSession(ctx, other, "laptop", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revoked, err := RevokeAllSessions(ctx, user.ID)
+	if err != nil || revoked != 2 {
+		t.Fatalf("RevokeAllSessions = %d, %v; want 2", revoked, err)
+	}
+	for _, pair := range []*TokenPair{laptop, phone} {
+		if _, err := validateAccessToken(ctx, pair.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+			t.Errorf("access token after logout = %v, want ErrTokenRevoked", err)
+		}
+		if _, err := Refresh(ctx, pair.RefreshToken, "test", ""); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("refresh after logout = %v, want ErrInvalidRefreshToken", err)
+		}
+	}
+	if _, err := validateAccessToken(ctx, kept.AccessToken); err != nil {
+		t.Errorf("another user's session was revoked: %v", err)
+	}
+
+	sessions, err := database.DB.Sessions().ListByUser(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, session := range sessions {
+		if IsActive(&session, session.LastUsedAt) {
+			t.Errorf("session %s is still active", session.ID)
+		}
+	}
+}
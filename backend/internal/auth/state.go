@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"brew-detective-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+)
+
+var (
+	// ErrUnknownState is returned for states that were never issued, expired or were already used
+	ErrUnknownState = errors.New("unknown or expired OAuth state")
+	// ErrStateMismatch is returned when the callback comes from a different browser than the login
+	// or for a different provider
+	ErrStateMismatch = errors.New("OAuth state was issued to a different browser")
+)
+
+// bindingCookie ties a login attempt to the browser that started it
+const bindingCookie = "oauth_binding"
+
+// stateTTL is how long a user has to complete the provider's login page
+var stateTTL = utils.DurationFromEnv("OAUTH_STATE_TTL", 10*time.Minute)
+
+// OAuthState is a pending login attempt
+type OAuthState struct {
+	Provider    string
+	BindingHash string // SHA-256 of the browser binding cookie
+	Verifier    string // PKCE code verifier
+	LinkUserID  string // Set when a signed-in user is linking a new identity
+	ExpiresAt   time.Time
+}
+
+// linkStatePrefix marks link tokens in the state store
+const linkStatePrefix = "link:"
+
+// StateStore keeps pending login attempts until the provider redirects back.
+// The default store is in memory; replace it with SetStateStore to share
+// states between server instances.
+type StateStore interface {
+	Save(ctx context.Context, state string, entry OAuthState) error
+	// Consume returns and deletes the entry so that each state can only be used once
+	Consume(ctx context.Context, state string) (*OAuthState, error)
+}
+
+var stateStore StateStore = NewMemoryStateStore()
+
+// SetStateStore replaces the store used for OAuth states
+func SetStateStore(store StateStore) {
+	stateStore = store
+}
+
+// MemoryStateStore is a StateStore kept in process memory
+type MemoryStateStore struct {
+	mu     sync.Mutex
+	states map[string]OAuthState
+}
+
+// NewMemoryStateStore creates an empty in-memory state store
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{states: make(map[string]OAuthState)}
+}
+
+func (s *MemoryStateStore) Save(ctx context.Context, state string, entry OAuthState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop abandoned logins so the map does not grow forever
+	now := time.Now()
+	for key, existing := range s.states {
+		if now.After(existing.ExpiresAt) {
+			delete(s.states, key)
+		}
+	}
+
+	s.states[state] = entry
+	return nil
+}
+
+func (s *MemoryStateStore) Consume(ctx context.Context, state string) (*OAuthState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.states[state]
+	delete(s.states, state)
+	if !ok || time.Now().After(entry.ExpiresAt) {
+		return nil, ErrUnknownState
+	}
+	return &entry, nil
+}
+
+func randomHex(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashSecret(binding string) string {
+	sum := sha256.Sum256([]byte(binding))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateLinkToken lets a signed-in user link another identity. The browser
+// passes the token to BeginLogin, since it cannot send the access token when
+// it navigates to the provider.
+func CreateLinkToken(ctx context.Context, provider, userID string) (string, error) {
+	token, err := randomHex(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate link token: %w", err)
+	}
+	entry := OAuthState{Provider: provider, LinkUserID: userID, ExpiresAt: time.Now().Add(stateTTL)}
+	if err := stateStore.Save(ctx, linkStatePrefix+token, entry); err != nil {
+		return "", fmt.Errorf("failed to store link token: %w", err)
+	}
+	return token, nil
+}
+
+// BeginLogin starts a login: it stores a fresh state with a PKCE verifier,
+// binds it to the browser with a cookie and returns the provider's login URL.
+// With a link token from CreateLinkToken the identity is linked to that user
+// instead of logging in.
+func BeginLogin(c *gin.Context, provider Provider, linkToken string) (string, error) {
+	linkUserID := ""
+	if linkToken != "" {
+		link, err := stateStore.Consume(c.Request.Context(), linkStatePrefix+linkToken)
+		if err != nil {
+			return "", err
+		}
+		if link.Provider != provider.Name() {
+			return "", ErrStateMismatch
+		}
+		linkUserID = link.LinkUserID
+	}
+
+	state, err := randomHex(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate OAuth state: %w", err)
+	}
+	binding, err := randomHex(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate OAuth binding: %w", err)
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	entry := OAuthState{
+		Provider:    provider.Name(),
+		LinkUserID:  linkUserID,
+		BindingHash: hashSecret(binding),
+		Verifier:    verifier,
+		ExpiresAt:   time.Now().Add(stateTTL),
+	}
+	if err := stateStore.Save(c.Request.Context(), state, entry); err != nil {
+		return "", fmt.Errorf("failed to store OAuth state: %w", err)
+	}
+
+	setBindingCookie(c, binding, int(stateTTL.Seconds()))
+	return provider.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// CompleteLogin checks the state returned by the provider against the store
+// and the browser binding, then exchanges the code using the PKCE verifier.
+// It returns the account that logged in and, when linking, the user to link it to.
+func CompleteLogin(c *gin.Context, provider Provider, state, code string) (*ProviderIdentity, string, error) {
+	if strings.HasPrefix(state, linkStatePrefix) {
+		return nil, "", ErrUnknownState
+	}
+	entry, err := stateStore.Consume(c.Request.Context(), state)
+	if err != nil {
+		return nil, "", err
+	}
+
+	binding, err := c.Cookie(bindingCookie)
+	setBindingCookie(c, "", -1)
+	if err != nil || subtle.ConstantTimeCompare([]byte(hashSecret(binding)), []byte(entry.BindingHash)) != 1 {
+		return nil, "", ErrStateMismatch
+	}
+	if entry.Provider != provider.Name() {
+		return nil, "", ErrStateMismatch
+	}
+
+	identity, err := provider.Exchange(c.Request.Context(), code, entry.Verifier)
+	if err != nil {
+		return nil, "", err
+	}
+	return identity, entry.LinkUserID, nil
+}
+
+func setBindingCookie(c *gin.Context, value string, maxAge int) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	// Lax cookies are still sent when the provider redirects the browser back
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(bindingCookie, value, maxAge, "/auth", "", secure, true)
+}
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+)
+
+// fakeProvider records the PKCE verifier its codes are exchanged with
+type fakeProvider struct {
+	name     string
+	config   oauth2.Config
+	verifier string
+}
+
+func newFakeProvider(name string) *fakeProvider {
+	return &fakeProvider{name: name, config: oauth2.Config{
+		ClientID: "client",
+		Endpoint: oauth2.Endpoint{AuthURL: "https://provider.test/authorize"},
+	}}
+}
+
+func (p *fakeProvider) Name() string { return p.name }
+
+func (p *fakeProvider) AuthCodeURL(state string, opts ...oauth2.AuthCodeOption) string {
+	return p.config.AuthCodeURL(state, opts...)
+}
+
+func (p *fakeProvider) Exchange(ctx context.Context, code, verifier string) (*ProviderIdentity, error) {
+	p.verifier = verifier
+	return &ProviderIdentity{Provider: p.name, Subject: "subject-" + code}, nil
+}
+
+// beginLogin starts a login and returns the state and the browser's binding cookie
+func beginLogin(t *testing.T, provider Provider, linkToken string) (string, string, *http.Cookie) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodGet, "/auth/"+provider.Name(), nil)
+
+	authURL, err := BeginLogin(c, provider, linkToken)
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == bindingCookie {
+			return parsed.Query().Get("state"), parsed.Query().Get("code_challenge"), cookie
+		}
+	}
+	t.Fatal("BeginLogin did not set the binding cookie")
+	return "", "", nil
+}
+
+// completeLogin sends the provider's callback from a browser with the cookie
+func completeLogin(provider Provider, state string, cookie *http.Cookie) (*ProviderIdentity, string, error) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/auth/"+provider.Name()+"/callback", nil)
+	if cookie != nil {
+		c.Request.AddCookie(cookie)
+	}
+	return CompleteLogin(c, provider, state, "code")
+}
+
+func TestLoginStateIsSingleUse(t *testing.T) {
+	provider := newFakeProvider("fake")
+	state, _, cookie := beginLogin(t, provider, "")
+
+	identity, _, err := completeLogin(provider, state, cookie)
+	if err != nil {
+		t.Fatalf("CompleteLogin: %v", err)
+	}
+	if identity.Subject != "subject-code" {
+		t.Errorf("logged in as %q", identity.Subject)
+	}
+
+	if _, _, err := completeLogin(provider, state, cookie); !errors.Is(err, ErrUnknownState) {
+		t.Errorf("replaying the state = %v, want ErrUnknownState", err)
+	}
+	if _, _, err := completeLogin(provider, "never-issued", cookie); !errors.Is(err, ErrUnknownState) {
+		t.Errorf("unknown state = %v, want ErrUnknownState", err)
+	}
+}
+
+func TestLoginStateIsBoundToTheBrowser(t *testing.T) {
+	provider := newFakeProvider("fake")
+
+	state, _, _ := beginLogin(t, provider, "")
+	if _, _, err := completeLogin(provider, state, nil); !errors.Is(err, ErrStateMismatch) {
+		t.Errorf("callback without the cookie = %v, want ErrStateMismatch", err)
+	}
+
+	state, _, cookie := beginLogin(t, provider, "")
+	forged := *cookie
+	forged.Value = "0000"
+	if _, _, err := completeLogin(provider, state, &forged); !errors.Is(err, ErrStateMismatch) {
+		t.Errorf("callback with another browser's cookie = %v, want ErrStateMismatch", err)
+	}
+	// A failed callback still uses up the state
+	if _, _, err := completeLogin(provider, state, cookie); !errors.Is(err, ErrUnknownState) {
+		t.Errorf("retrying the state = %v, want ErrUnknownState", err)
+	}
+
+	state, _, cookie = beginLogin(t, provider, "")
+	if _, _, err := completeLogin(newFakeProvider("other"), state, cookie); !errors.Is(err, ErrStateMismatch) {
+		t.Errorf("callback for another provider = %v, want ErrStateMismatch", err)
+	}
+}
+
+func TestLoginUsesPKCE(t *testing.T) {
+	provider := newFakeProvider("fake")
+	state, challenge, cookie := beginLogin(t, provider, "")
+	if challenge == "" {
+		t.Fatal("the login URL has no code_challenge")
+	}
+
+	if _, _, err := completeLogin(provider, state, cookie); err != nil {
+		t.Fatalf("CompleteLogin: %v", err)
+	}
+	if provider.verifier == "" || oauth2.S256ChallengeFromVerifier(provider.verifier) != challenge {
+		t.Errorf("code exchanged with verifier %q, which does not match the challenge %q", provider.verifier, challenge)
+	}
+}
+
+func TestLinkTokenIsSingleUse(t *testing.T) {
+	provider := newFakeProvider("fake")
+	token, err := CreateLinkToken(context.Background(), "fake", "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state, _, cookie := beginLogin(t, provider, token)
+	_, linkUserID, err := completeLogin(provider, state, cookie)
+	if err != nil || linkUserID != "user-1" {
+		t.Fatalf("CompleteLogin linked to %q: %v", linkUserID, err)
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/auth/fake", nil)
+	if _, err := BeginLogin(c, provider, token); !errors.Is(err, ErrUnknownState) {
+		t.Errorf("reusing the link token = %v, want ErrUnknownState", err)
+	}
+	// Link tokens cannot be passed off as login states
+	if _, _, err := completeLogin(provider, linkStatePrefix+token, cookie); !errors.Is(err, ErrUnknownState) {
+		t.Errorf("link token as state = %v, want ErrUnknownState", err)
+	}
+}
@@ -0,0 +1,124 @@
+// Package badges awards achievements to users based on declarative rules.
+package badges
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+)
+
+// DefaultDefinitions returns the badges seeded into an empty badges collection
+func DefaultDefinitions() []models.BadgeDefinition {
+	return []models.BadgeDefinition{
+		{ID: "first_case", Name: "Primer Caso", Icon: "🔍", Description: "Resolviste tu primer caso", Rule: "cases_solved >= 1"},
+		{ID: "accuracy_70", Name: "Precisión 70%", Icon: "🎯", Description: "Precisión promedio de al menos 70%", Rule: "accuracy >= 0.7"},
+		{ID: "taster_level_2", Name: "Catador Nivel 2", Icon: "💎", Description: "Precisión promedio de al menos 80%", Rule: "accuracy >= 0.8"},
+		{ID: "roast_expert", Name: "Experto en Tuestes", Icon: "🔥", Description: "Resolviste 5 casos", Rule: "cases_solved >= 5"},
+		{ID: "master_detective", Name: "Detective Maestro", Icon: "🏆", Description: "Acumulaste 2000 puntos", Rule: "points >= 2000"},
+		{ID: "streak_3", Name: "Racha de 3", Icon: "⚡", Description: "Tres casos seguidos con al menos 70% de precisión", Rule: "streak >= 3"},
+		{ID: "perfect_coffee", Name: "Paladar Perfecto", Icon: "☕", Description: "Acertaste todas las preguntas de un café", Rule: "perfect_coffees >= 1"},
+		{ID: "perfect_case", Name: "Caso Perfecto", Icon: "🌟", Description: "Resolviste un caso con 100% de precisión", Rule: "perfect_cases >= 1"},
+	}
+}
+
+// SeedDefaults stores the default definitions when no badges are defined yet
+func SeedDefaults(ctx context.Context, repo database.BadgeRepository) error {
+	existing, err := repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return nil
+	}
+
+	now := time.Now()
+	for i, badge := range DefaultDefinitions() {
+		badge.IsActive = true
+		badge.DisplayOrder = i + 1
+		badge.CreatedAt = now
+		badge.UpdatedAt = now
+		if err := repo.Save(ctx, &badge); err != nil {
+			return err
+		}
+	}
+	log.Printf("Seeded %d default badges", len(DefaultDefinitions()))
+	return nil
+}
+
+// MigrateLegacy converts the badges users were awarded as plain strings, before
+// badges had definitions, into structured badges. Strings naming a definition
+// become that badge; any other string is kept as the badge's name. Legacy awards
+// have no date, so they are dated now.
+func MigrateLegacy(ctx context.Context, store database.Store) error {
+	legacyStore, ok := store.(database.LegacyBadgeStore)
+	if !ok {
+		return nil
+	}
+	definitions, err := store.Badges().List(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	migrated, err := legacyStore.MigrateLegacyBadges(ctx, func(legacy string) models.UserBadge {
+		badge := LegacyBadge(definitions, legacy)
+		badge.AwardedAt = now
+		return badge
+	})
+	if migrated > 0 {
+		log.Printf("Migrated the legacy badges of %d users", migrated)
+	}
+	return err
+}
+
+// LegacyBadge returns the badge a legacy badge string refers to. The strings
+// were the icon and the name, e.g. "🔍 Primer Caso".
+func LegacyBadge(definitions []models.BadgeDefinition, legacy string) models.UserBadge {
+	legacy = strings.TrimSpace(legacy)
+	for _, definition := range definitions {
+		if legacy == definition.Name || legacy == definition.Icon+" "+definition.Name {
+			return models.UserBadge{ID: definition.ID, Name: definition.Name, Icon: definition.Icon}
+		}
+	}
+	return models.UserBadge{ID: legacy, Name: legacy}
+}
+
+// Evaluate awards the user every active badge whose rule the stats satisfy and
+// that they do not hold yet. Awarded badges are never taken away. It returns
+// the newly awarded badges, which are also appended to user.Badges.
+func Evaluate(definitions []models.BadgeDefinition, user *models.User, stats Stats, now time.Time) []models.UserBadge {
+	held := make(map[string]bool, len(user.Badges))
+	for _, badge := range user.Badges {
+		held[badge.ID] = true
+	}
+
+	awarded := []models.UserBadge{}
+	for _, definition := range definitions {
+		if !definition.IsActive || held[definition.ID] {
+			continue
+		}
+
+		rule, err := ParseRule(definition.Rule)
+		if err != nil {
+			log.Printf("Skipping badge %s with invalid rule: %v", definition.ID, err)
+			continue
+		}
+		if !rule.Matches(stats) {
+			continue
+		}
+
+		awarded = append(awarded, models.UserBadge{
+			ID:        definition.ID,
+			Name:      definition.Name,
+			Icon:      definition.Icon,
+			AwardedAt: now,
+		})
+	}
+
+	user.Badges = append(user.Badges, awarded...)
+	return awarded
+}
@@ -0,0 +1,129 @@
+package badges
+
+import (
+	"testing"
+	"time"
+
+	"brew-detective-backend/internal/models"
+	"brew-detective-backend/internal/scoring"
+)
+
+func TestLegacyBadge(t *testing.T) {
+	definitions := DefaultDefinitions()
+
+	tests := []struct {
+		legacy string
+		want   models.UserBadge
+	}{
+		{"🔍 Primer Caso", models.UserBadge{ID: "first_case", Name: "Primer Caso", Icon: "🔍"}},
+		{"Precisión 70%", models.UserBadge{ID: "accuracy_70", Name: "Precisión 70%", Icon: "🎯"}},
+		{"🥇 Campeón", models.UserBadge{ID: "🥇 Campeón", Name: "🥇 Campeón"}},
+	}
+	for _, tt := range tests {
+		if got := LegacyBadge(definitions, tt.legacy); got != tt.want {
+			t.Errorf("LegacyBadge(%q) = %+v, want %+v", tt.legacy, got, tt.want)
+		}
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		expr  string
+		stats Stats
+		want  bool
+	}{
+		{"cases_solved >= 5", Stats{CasesSolved: 5}, true},
+		{"cases_solved >= 5", Stats{CasesSolved: 4}, false},
+		{"cases_solved >= 5 && accuracy >= 0.8", Stats{CasesSolved: 6, Accuracy: 0.7}, false},
+		{"cases_solved >= 5 && accuracy >= 0.8", Stats{CasesSolved: 6, Accuracy: 0.8}, true},
+		// && binds tighter than ||
+		{"points > 100 || streak == 3 && perfect_cases != 0", Stats{BestStreak: 3}, false},
+		{"points > 100 || streak == 3 && perfect_cases != 0", Stats{BestStreak: 3, PerfectCases: 1}, true},
+		{"points > 100 || streak == 3 && perfect_cases != 0", Stats{Points: 101}, true},
+		{"perfect_coffees<1", Stats{}, true},
+		{"accuracy <= 0.5", Stats{Accuracy: 0.5}, true},
+	}
+	for _, tt := range tests {
+		rule, err := ParseRule(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseRule(%q): %v", tt.expr, err)
+		}
+		if got := rule.Matches(tt.stats); got != tt.want {
+			t.Errorf("%q matches %+v = %v, want %v", tt.expr, tt.stats, got, tt.want)
+		}
+	}
+}
+
+func TestParseRuleRejectsInvalidRules(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"level >= 2",
+		"cases_solved",
+		"cases_solved >= many",
+		"cases_solved >= 1 &&",
+		"(cases_solved >= 1)",
+	} {
+		if _, err := ParseRule(expr); err == nil {
+			t.Errorf("ParseRule(%q) succeeded", expr)
+		}
+	}
+}
+
+func TestEvaluateAwardsEachBadgeOnce(t *testing.T) {
+	definitions := DefaultDefinitions()
+	for i := range definitions {
+		definitions[i].IsActive = definitions[i].ID != "accuracy_70"
+	}
+	definitions = append(definitions, models.BadgeDefinition{ID: "broken", Rule: "nonsense", IsActive: true})
+
+	user := &models.User{}
+	now := time.Now()
+	stats := Stats{CasesSolved: 1, Accuracy: 0.75}
+
+	awarded := Evaluate(definitions, user, stats, now)
+	if len(awarded) != 1 || awarded[0].ID != "first_case" || !awarded[0].AwardedAt.Equal(now) {
+		t.Fatalf("awarded %+v, want only first_case", awarded)
+	}
+	if len(user.Badges) != 1 {
+		t.Fatalf("user holds %d badges, want 1", len(user.Badges))
+	}
+
+	// Held badges are not awarded again, nor taken away when stats drop
+	if again := Evaluate(definitions, user, Stats{CasesSolved: 1}, now); len(again) != 0 {
+		t.Errorf("awarded %+v again", again)
+	}
+	if len(user.Badges) != 1 {
+		t.Errorf("user holds %d badges, want 1", len(user.Badges))
+	}
+}
+
+func TestStatsFor(t *testing.T) {
+	start := time.Now()
+	perfect := []models.QuestionScore{
+		{CoffeeID: "c1", Result: scoring.ResultCorrect},
+		{CoffeeID: "c2", Result: scoring.ResultCorrect},
+		{Result: scoring.ResultIncorrect}, // Opinion questions do not count
+	}
+	partial := []models.QuestionScore{
+		{CoffeeID: "c1", Result: scoring.ResultCorrect},
+		{CoffeeID: "c1", Result: scoring.ResultIncorrect},
+		{CoffeeID: "c2", Result: scoring.ResultCorrect},
+	}
+	answered := []models.CoffeeAnswer{{CoffeeID: "c1"}}
+
+	// Listed out of order: streaks follow the submission time
+	history := []models.Submission{
+		{Accuracy: 0.9, SubmittedAt: start.Add(4 * time.Hour), Breakdown: partial},
+		{Accuracy: 1, SubmittedAt: start, CoffeeAnswers: answered, Breakdown: perfect},
+		{Accuracy: 0.8, SubmittedAt: start.Add(time.Hour), Breakdown: partial},
+		{Accuracy: 0.2, SubmittedAt: start.Add(2 * time.Hour)},
+		{Accuracy: 0.7, SubmittedAt: start.Add(3 * time.Hour)},
+	}
+	user := &models.User{CasesCount: 5, Points: 900, Accuracy: 0.72}
+
+	got := StatsFor(user, history)
+	want := Stats{CasesSolved: 5, Points: 900, Accuracy: 0.72, BestStreak: 2, PerfectCoffees: 4, PerfectCases: 1}
+	if got != want {
+		t.Errorf("StatsFor = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,124 @@
+package badges
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Rule is a parsed badge rule expression. Rules compare stats metrics against
+// numbers, e.g. "cases_solved >= 5 && accuracy >= 0.8". Comparisons can be
+// joined with && and ||; && binds tighter than ||. Parentheses are not supported.
+type Rule struct {
+	// Any clause must hold, and a clause holds when all its comparisons do
+	clauses [][]comparison
+}
+
+type comparison struct {
+	metric string
+	op     string
+	value  float64
+}
+
+var operators = []string{">=", "<=", "==", "!=", ">", "<"}
+
+// ParseRule parses a rule expression, checking that every metric is known
+func ParseRule(expr string) (*Rule, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, fmt.Errorf("rule is empty")
+	}
+
+	rule := &Rule{}
+	for _, clauseExpr := range strings.Split(expr, "||") {
+		var clause []comparison
+		for _, cmpExpr := range strings.Split(clauseExpr, "&&") {
+			cmp, err := parseComparison(strings.TrimSpace(cmpExpr))
+			if err != nil {
+				return nil, err
+			}
+			clause = append(clause, cmp)
+		}
+		rule.clauses = append(rule.clauses, clause)
+	}
+	return rule, nil
+}
+
+func parseComparison(expr string) (comparison, error) {
+	if expr == "" {
+		return comparison{}, fmt.Errorf("missing comparison around && or ||")
+	}
+
+	for _, op := range operators {
+		i := strings.Index(expr, op)
+		if i < 0 {
+			continue
+		}
+
+		metric := strings.TrimSpace(expr[:i])
+		if !isIdentifier(metric) {
+			return comparison{}, fmt.Errorf("invalid metric %q in %q", metric, expr)
+		}
+		if _, ok := metrics[metric]; !ok {
+			return comparison{}, fmt.Errorf("unknown metric %q, expected one of %s", metric, strings.Join(MetricNames(), ", "))
+		}
+
+		value, err := strconv.ParseFloat(strings.TrimSpace(expr[i+len(op):]), 64)
+		if err != nil {
+			return comparison{}, fmt.Errorf("invalid number in %q", expr)
+		}
+
+		return comparison{metric: metric, op: op, value: value}, nil
+	}
+
+	return comparison{}, fmt.Errorf("missing comparison operator in %q", expr)
+}
+
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches reports whether the stats satisfy the rule
+func (r *Rule) Matches(stats Stats) bool {
+	for _, clause := range r.clauses {
+		if clauseMatches(clause, stats) {
+			return true
+		}
+	}
+	return false
+}
+
+func clauseMatches(clause []comparison, stats Stats) bool {
+	for _, cmp := range clause {
+		if !cmp.matches(metrics[cmp.metric](stats)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c comparison) matches(actual float64) bool {
+	switch c.op {
+	case ">=":
+		return actual >= c.value
+	case "<=":
+		return actual <= c.value
+	case "==":
+		return actual == c.value
+	case "!=":
+		return actual != c.value
+	case ">":
+		return actual > c.value
+	case "<":
+		return actual < c.value
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package badges
+
+import (
+	"sort"
+
+	"brew-detective-backend/internal/models"
+	"brew-detective-backend/internal/scoring"
+)
+
+// StreakAccuracy is the minimum accuracy a submission needs to extend a streak
+const StreakAccuracy = 0.7
+
+// Stats are the metrics badge rules are evaluated against
+type Stats struct {
+	CasesSolved    int
+	Points         int
+	Accuracy       float64
+	BestStreak     int // Longest run of consecutive submissions with at least StreakAccuracy
+	PerfectCoffees int // Coffees with every graded question answered correctly
+	PerfectCases   int // Submissions with 100% accuracy
+}
+
+// metrics maps rule metric names to stats values
+var metrics = map[string]func(Stats) float64{
+	"cases_solved":    func(s Stats) float64 { return float64(s.CasesSolved) },
+	"points":          func(s Stats) float64 { return float64(s.Points) },
+	"accuracy":        func(s Stats) float64 { return s.Accuracy },
+	"streak":          func(s Stats) float64 { return float64(s.BestStreak) },
+	"perfect_coffees": func(s Stats) float64 { return float64(s.PerfectCoffees) },
+	"perfect_cases":   func(s Stats) float64 { return float64(s.PerfectCases) },
+}
+
+// MetricNames lists the metrics usable in rules
+func MetricNames() []string {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StatsFor computes a user's stats. Totals come from the user document while
+// streaks and perfect scores come from the submission history.
+func StatsFor(user *models.User, submissions []models.Submission) Stats {
+	stats := Stats{
+		CasesSolved: user.CasesCount,
+		Points:      user.Points,
+		Accuracy:    user.Accuracy,
+	}
+
+	history := append([]models.Submission(nil), submissions...)
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].SubmittedAt.Before(history[j].SubmittedAt)
+	})
+
+	streak := 0
+	for _, submission := range history {
+		if submission.Accuracy >= StreakAccuracy {
+			streak++
+			if streak > stats.BestStreak {
+				stats.BestStreak = streak
+			}
+		} else {
+			streak = 0
+		}
+
+		if len(submission.CoffeeAnswers) > 0 && submission.Accuracy >= 1 {
+			stats.PerfectCases++
+		}
+		stats.PerfectCoffees += perfectCoffees(submission.Breakdown)
+	}
+
+	return stats
+}
+
+// perfectCoffees counts the coffees of a breakdown whose questions were all correct
+func perfectCoffees(breakdown []models.QuestionScore) int {
+	perfect := make(map[string]bool)
+	for _, score := range breakdown {
+		if score.CoffeeID == "" {
+			continue // Opinion questions are not about a coffee
+		}
+		if _, seen := perfect[score.CoffeeID]; !seen {
+			perfect[score.CoffeeID] = true
+		}
+		if score.Result != scoring.ResultCorrect {
+			perfect[score.CoffeeID] = false
+		}
+	}
+
+	count := 0
+	for _, ok := range perfect {
+		if ok {
+			count++
+		}
+	}
+	return count
+}
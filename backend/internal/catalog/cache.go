@@ -0,0 +1,92 @@
+package catalog
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/utils"
+)
+
+// cacheTTL is how long the index of the active catalog is reused before being
+// read again. Changes made on this instance invalidate it at once.
+var cacheTTL = utils.DurationFromEnv("CATALOG_CACHE_TTL", time.Minute)
+
+var (
+	cacheMu  sync.Mutex
+	cached   *Index
+	loadedAt time.Time
+)
+
+// Load returns the index of the active catalog items, from the cache when it
+// was read recently
+func Load(ctx context.Context) (*Index, error) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	if cached != nil && time.Since(loadedAt) < cacheTTL {
+		return cached, nil
+	}
+
+	items, err := database.DB.Catalog().ListActive(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	cached = NewIndex(items)
+	loadedAt = time.Now()
+	return cached, nil
+}
+
+// Invalidate drops the cached index after the catalog changes
+func Invalidate() {
+	cacheMu.Lock()
+	cached = nil
+	cacheMu.Unlock()
+}
+
+// Cached resolves text against the cached index. It is meant for code without
+// a context, such as graders; when the catalog cannot be read, text is only folded.
+type Cached struct{}
+
+func (Cached) Canonical(category, text string) string {
+	index := loadIndex()
+	if index == nil {
+		return Fold(text)
+	}
+	return index.Canonical(category, text)
+}
+
+// Parent returns the parent of value in the cached index, or "" when the
+// catalog cannot be read
+func (Cached) Parent(category, value string) string {
+	index := loadIndex()
+	if index == nil {
+		return ""
+	}
+	return index.Parent(category, value)
+}
+
+// Resolve returns the value text refers to in the cached index. When the
+// catalog cannot be read, every text resolves to its folded form.
+func (Cached) Resolve(category, text string) (string, bool) {
+	index := loadIndex()
+	if index == nil {
+		folded := Fold(text)
+		return folded, folded != ""
+	}
+	return index.Resolve(category, text)
+}
+
+func loadIndex() *Index {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	index, err := Load(ctx)
+	if err != nil {
+		log.Printf("Failed to load catalog: %v", err)
+		return nil
+	}
+	return index
+}
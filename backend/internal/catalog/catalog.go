@@ -0,0 +1,150 @@
+// Package catalog matches free text against the catalog of regions,
+// varieties, processes, brewing methods and tasting notes.
+package catalog
+
+import (
+	"strings"
+	"unicode"
+
+	"brew-detective-backend/internal/models"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Categories
+const (
+	Region        = "region"
+	Variety       = "variety"
+	Process       = "process"
+	BrewingMethod = "brewing_method"
+	TastingNote   = "tasting_note"
+)
+
+var categories = []string{Region, Variety, Process, BrewingMethod, TastingNote}
+
+// maxDepth bounds walks up the tasting note hierarchy, in case stored items form a cycle
+const maxDepth = 16
+
+// IsCategory reports whether category is a known catalog category
+func IsCategory(category string) bool {
+	for _, known := range categories {
+		if known == category {
+			return true
+		}
+	}
+	return false
+}
+
+// Categories lists the catalog categories
+func Categories() []string {
+	return append([]string(nil), categories...)
+}
+
+// Fold normalizes text for matching, ignoring case, accents, surrounding space
+// and the separators in values: "Valle  Central", "valle_central" and
+// "valle-central" all fold to "valle central".
+func Fold(text string) string {
+	// Decompose accented letters and drop the accents
+	stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), text)
+	if err != nil {
+		stripped = text
+	}
+	words := strings.FieldsFunc(strings.ToLower(stripped), func(r rune) bool {
+		return unicode.IsSpace(r) || r == '_' || r == '-'
+	})
+	return strings.Join(words, " ")
+}
+
+// Index resolves the values, labels and synonyms of catalog items to the
+// items' values, which are the canonical IDs answers and cases refer to
+type Index struct {
+	terms   map[string]map[string]string // Category, then folded term, to value
+	parents map[string]map[string]string // Category, then value, to the parent's value
+}
+
+// NewIndex indexes catalog items. When two items share a term, values take
+// precedence over labels and synonyms, then the first item wins.
+func NewIndex(items []models.CatalogItem) *Index {
+	index := &Index{
+		terms:   make(map[string]map[string]string),
+		parents: make(map[string]map[string]string),
+	}
+	for _, item := range items {
+		index.add(item.Category, item.Value, item.Value)
+		if item.Parent != "" {
+			if index.parents[item.Category] == nil {
+				index.parents[item.Category] = make(map[string]string)
+			}
+			index.parents[item.Category][item.Value] = item.Parent
+		}
+	}
+	for _, item := range items {
+		for _, term := range Terms(item) {
+			index.add(item.Category, term, item.Value)
+		}
+	}
+	return index
+}
+
+func (i *Index) add(category, term, value string) {
+	folded := Fold(term)
+	if folded == "" {
+		return
+	}
+	if i.terms[category] == nil {
+		i.terms[category] = make(map[string]string)
+	}
+	if _, exists := i.terms[category][folded]; !exists {
+		i.terms[category][folded] = value
+	}
+}
+
+// Terms returns every text that refers to the item
+func Terms(item models.CatalogItem) []string {
+	return append([]string{item.Value, item.Label}, item.Synonyms...)
+}
+
+// Resolve returns the value of the item text refers to
+func (i *Index) Resolve(category, text string) (string, bool) {
+	value, ok := i.terms[category][Fold(text)]
+	return value, ok
+}
+
+// Canonical returns the value text refers to, or the folded text when it is
+// not in the catalog, so that two texts match when their canonical forms are equal
+func (i *Index) Canonical(category, text string) string {
+	if value, ok := i.Resolve(category, text); ok {
+		return value
+	}
+	return Fold(text)
+}
+
+// Parent returns the value of the broader item value belongs under, or "" for
+// a top-level item
+func (i *Index) Parent(category, value string) string {
+	return i.parents[category][value]
+}
+
+// Ancestors returns the values above value in its category's hierarchy,
+// nearest first. parent looks up the parent of a value.
+func Ancestors(value string, parent func(value string) string) []string {
+	var ancestors []string
+	for next := parent(value); next != "" && len(ancestors) < maxDepth; next = parent(next) {
+		if next == value || contains(ancestors, next) {
+			break
+		}
+		ancestors = append(ancestors, next)
+	}
+	return ancestors
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
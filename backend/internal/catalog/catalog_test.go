@@ -0,0 +1,91 @@
+package catalog
+
+import (
+	"context"
+	"testing"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+)
+
+func TestFold(t *testing.T) {
+	tests := map[string]string{
+		"Valle  Central": "valle central",
+		"valle_central":  "valle central",
+		"valle-central":  "valle central",
+		" NARIÑO ":       "narino",
+		"Café":           "cafe",
+		"":               "",
+		" _ ":            "",
+	}
+	for text, want := range tests {
+		if got := Fold(text); got != want {
+			t.Errorf("Fold(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestIndexResolvesValuesLabelsAndSynonyms(t *testing.T) {
+	index := NewIndex([]models.CatalogItem{
+		{Category: Region, Value: "valle_central", Label: "Valle Central", Synonyms: []string{"Central Valley"}},
+		{Category: Region, Value: "narino", Label: "Nariño"},
+		// A label clashing with another item's value never shadows it
+		{Category: Region, Value: "huila", Label: "Narino"},
+		{Category: Process, Value: "washed", Label: "Lavado"},
+	})
+
+	tests := []struct {
+		category, text string
+		want           string
+		ok             bool
+	}{
+		{Region, "Valle Central", "valle_central", true},
+		{Region, "central valley", "valle_central", true},
+		{Region, "NARIÑO", "narino", true},
+		{Region, "Huila", "huila", true},
+		{Region, "Lavado", "", false}, // Another category
+		{Process, "lavado", "washed", true},
+		{Region, "Antioquia", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := index.Resolve(tt.category, tt.text)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Resolve(%s, %q) = %q, %v, want %q, %v", tt.category, tt.text, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if got := index.Canonical(Region, "Central Valley"); got != "valle_central" {
+		t.Errorf("Canonical of a synonym = %q", got)
+	}
+	if got := index.Canonical(Region, "Sierra  Nevada"); got != "sierra nevada" {
+		t.Errorf("Canonical of an unknown region = %q, want it folded", got)
+	}
+}
+
+func TestLoadCachesUntilInvalidated(t *testing.T) {
+	ctx := context.Background()
+	database.DB = database.NewMemoryStore()
+	Invalidate()
+
+	save := func(item models.CatalogItem) {
+		t.Helper()
+		item.IsActive = true
+		if err := database.DB.Catalog().Save(ctx, &item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	save(models.CatalogItem{ID: "r1", Category: Region, Value: "huila", Label: "Huila"})
+
+	if value, ok := (Cached{}).Resolve(Region, "HUILA"); !ok || value != "huila" {
+		t.Fatalf("Resolve = %q, %v", value, ok)
+	}
+
+	save(models.CatalogItem{ID: "r2", Category: Region, Value: "cauca", Label: "Cauca"})
+	if _, ok := (Cached{}).Resolve(Region, "Cauca"); ok {
+		t.Error("resolved an item added after the index was cached")
+	}
+	Invalidate()
+	if value, ok := (Cached{}).Resolve(Region, "Cauca"); !ok || value != "cauca" {
+		t.Errorf("Resolve after invalidating = %q, %v", value, ok)
+	}
+}
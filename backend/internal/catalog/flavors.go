@@ -0,0 +1,200 @@
+package catalog
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+)
+
+// flavor is a note of the flavor wheel with the more specific notes under it
+type flavor struct {
+	value    string
+	label    string
+	synonyms []string
+	children []flavor
+}
+
+// flavorWheel follows the tiers of the SCA Coffee Taster's Flavor Wheel
+var flavorWheel = []flavor{
+	{"fruity", "Frutal", []string{"Fruity", "Afrutado"}, []flavor{
+		{"berry", "Frutos rojos", []string{"Berry", "Berries", "Frutos del bosque"}, []flavor{
+			{"blackberry", "Mora", []string{"Blackberry"}, nil},
+			{"raspberry", "Frambuesa", []string{"Raspberry"}, nil},
+			{"blueberry", "Arándano", []string{"Blueberry"}, nil},
+			{"strawberry", "Fresa", []string{"Strawberry", "Frutilla"}, nil},
+		}},
+		{"dried_fruit", "Fruta seca", []string{"Dried fruit", "Fruta deshidratada"}, []flavor{
+			{"raisin", "Uva pasa", []string{"Raisin", "Pasas"}, nil},
+			{"prune", "Ciruela pasa", []string{"Prune"}, nil},
+		}},
+		{"other_fruit", "Otras frutas", []string{"Other fruit"}, []flavor{
+			{"coconut", "Coco", []string{"Coconut"}, nil},
+			{"cherry", "Cereza", []string{"Cherry"}, nil},
+			{"pomegranate", "Granada", []string{"Pomegranate"}, nil},
+			{"pineapple", "Piña", []string{"Pineapple"}, nil},
+			{"grape", "Uva", []string{"Grape"}, nil},
+			{"apple", "Manzana", []string{"Apple"}, nil},
+			{"peach", "Melocotón", []string{"Peach", "Durazno"}, nil},
+			{"pear", "Pera", []string{"Pear"}, nil},
+		}},
+		{"citrus_fruit", "Cítrico", []string{"Citrus", "Citrus fruit", "Cítricos"}, []flavor{
+			{"grapefruit", "Toronja", []string{"Grapefruit", "Pomelo"}, nil},
+			{"orange", "Naranja", []string{"Orange"}, nil},
+			{"lemon", "Limón", []string{"Lemon"}, nil},
+			{"lime", "Lima", []string{"Lime"}, nil},
+		}},
+	}},
+	{"sour_fermented", "Ácido/Fermentado", []string{"Sour/Fermented"}, []flavor{
+		{"sour", "Ácido", []string{"Sour", "Agrio"}, []flavor{
+			{"sour_aromatics", "Aromas ácidos", []string{"Sour aromatics"}, nil},
+			{"acetic_acid", "Ácido acético", []string{"Acetic acid", "Vinagre"}, nil},
+			{"butyric_acid", "Ácido butírico", []string{"Butyric acid"}, nil},
+			{"isovaleric_acid", "Ácido isovalérico", []string{"Isovaleric acid"}, nil},
+			{"citric_acid", "Ácido cítrico", []string{"Citric acid"}, nil},
+			{"malic_acid", "Ácido málico", []string{"Malic acid"}, nil},
+		}},
+		{"alcohol_fermented", "Alcohólico/Fermentado", []string{"Alcohol/Fermented"}, []flavor{
+			{"winey", "Vino", []string{"Winey", "Vinoso"}, nil},
+			{"whiskey", "Whisky", []string{"Whiskey"}, nil},
+			{"fermented", "Fermentado", []string{"Fermented"}, nil},
+			{"overripe", "Sobremaduro", []string{"Overripe"}, nil},
+		}},
+	}},
+	{"green_vegetative", "Verde/Vegetal", []string{"Green/Vegetative", "Vegetal"}, []flavor{
+		{"olive_oil", "Aceite de oliva", []string{"Olive oil"}, nil},
+		{"raw", "Crudo", []string{"Raw"}, nil},
+		{"under_ripe", "Inmaduro", []string{"Under-ripe", "Verde"}, nil},
+		{"peapod", "Vaina de guisante", []string{"Peapod"}, nil},
+		{"fresh", "Fresco", []string{"Fresh"}, nil},
+		{"dark_green", "Verde oscuro", []string{"Dark green"}, nil},
+		{"vegetative", "Vegetativo", []string{"Vegetative"}, nil},
+		{"hay_like", "Heno", []string{"Hay-like", "Hay"}, nil},
+		{"herb_like", "Hierbas", []string{"Herb-like", "Herbal", "Herbáceo"}, nil},
+		{"beany", "Leguminoso", []string{"Beany"}, nil},
+	}},
+	{"other", "Otros", []string{"Other"}, []flavor{
+		{"papery_musty", "Papel/Humedad", []string{"Papery/Musty"}, []flavor{
+			{"stale", "Rancio", []string{"Stale"}, nil},
+			{"cardboard", "Cartón", []string{"Cardboard"}, nil},
+			{"papery", "Papel", []string{"Papery"}, nil},
+			{"woody", "Madera", []string{"Woody", "Amaderado"}, nil},
+			{"moldy_damp", "Moho", []string{"Moldy/Damp", "Humedad"}, nil},
+			{"musty_dusty", "Polvo", []string{"Musty/Dusty", "Polvoriento"}, nil},
+			{"musty_earthy", "Terroso", []string{"Musty/Earthy", "Tierra"}, nil},
+			{"animalic", "Animal", []string{"Animalic"}, nil},
+			{"meaty_brothy", "Caldo de carne", []string{"Meaty/Brothy"}, nil},
+			{"phenolic", "Fenólico", []string{"Phenolic"}, nil},
+		}},
+		{"chemical", "Químico", []string{"Chemical"}, []flavor{
+			{"bitter", "Amargo", []string{"Bitter"}, nil},
+			{"salty", "Salado", []string{"Salty"}, nil},
+			{"medicinal", "Medicinal", []string{"Medicinal"}, nil},
+			{"petroleum", "Petróleo", []string{"Petroleum"}, nil},
+			{"skunky", "Zorrillo", []string{"Skunky"}, nil},
+			{"rubber", "Caucho", []string{"Rubber", "Hule"}, nil},
+		}},
+	}},
+	{"roasted", "Tostado", []string{"Roasted"}, []flavor{
+		{"pipe_tobacco", "Tabaco de pipa", []string{"Pipe tobacco"}, nil},
+		{"tobacco", "Tabaco", []string{"Tobacco"}, nil},
+		{"burnt", "Quemado", []string{"Burnt"}, []flavor{
+			{"acrid", "Acre", []string{"Acrid"}, nil},
+			{"ashy", "Ceniza", []string{"Ashy", "Ash"}, nil},
+			{"smoky", "Ahumado", []string{"Smoky"}, nil},
+			{"brown_roast", "Tueste oscuro", []string{"Brown, roast"}, nil},
+		}},
+		{"cereal", "Cereal", []string{"Cereal"}, []flavor{
+			{"grain", "Grano", []string{"Grain"}, nil},
+			{"malt", "Malta", []string{"Malt"}, nil},
+		}},
+	}},
+	{"spices", "Especias", []string{"Spices", "Especiado", "Spicy"}, []flavor{
+		{"pungent", "Picante", []string{"Pungent"}, nil},
+		{"pepper", "Pimienta", []string{"Pepper"}, nil},
+		{"brown_spice", "Especias dulces", []string{"Brown spice"}, []flavor{
+			{"anise", "Anís", []string{"Anise"}, nil},
+			{"nutmeg", "Nuez moscada", []string{"Nutmeg"}, nil},
+			{"cinnamon", "Canela", []string{"Cinnamon"}, nil},
+			{"clove", "Clavo", []string{"Clove"}, nil},
+		}},
+	}},
+	{"nutty_cocoa", "Nuez/Cacao", []string{"Nutty/Cocoa"}, []flavor{
+		{"nutty", "Nuez", []string{"Nutty", "Nueces", "Frutos secos"}, []flavor{
+			{"peanuts", "Maní", []string{"Peanuts", "Cacahuate"}, nil},
+			{"hazelnut", "Avellana", []string{"Hazelnut"}, nil},
+			{"almond", "Almendra", []string{"Almond"}, nil},
+		}},
+		{"cocoa", "Cacao", []string{"Cocoa"}, []flavor{
+			{"chocolate", "Chocolate", []string{}, nil},
+			{"dark_chocolate", "Chocolate oscuro", []string{"Dark chocolate", "Chocolate amargo"}, nil},
+		}},
+	}},
+	{"sweet", "Dulce", []string{"Sweet"}, []flavor{
+		{"brown_sugar", "Azúcar morena", []string{"Brown sugar", "Panela", "Tapa de dulce"}, []flavor{
+			{"molasses", "Melaza", []string{"Molasses"}, nil},
+			{"maple_syrup", "Jarabe de arce", []string{"Maple syrup", "Maple"}, nil},
+			{"caramelized", "Caramelo", []string{"Caramelized", "Caramel", "Caramelizado"}, nil},
+			{"honey", "Miel", []string{"Honey"}, nil},
+		}},
+		{"vanilla", "Vainilla", []string{"Vanilla"}, nil},
+		{"vanillin", "Vainillina", []string{"Vanillin"}, nil},
+		{"overall_sweet", "Dulzor general", []string{"Overall sweet"}, nil},
+		{"sweet_aromatics", "Aromas dulces", []string{"Sweet aromatics"}, nil},
+	}},
+	{"floral", "Floral", []string{}, []flavor{
+		{"black_tea", "Té negro", []string{"Black tea"}, nil},
+		{"flowers", "Flores", []string{"Flowers"}, []flavor{
+			{"chamomile", "Manzanilla", []string{"Chamomile"}, nil},
+			{"rose", "Rosa", []string{"Rose"}, nil},
+			{"jasmine", "Jazmín", []string{"Jasmine"}, nil},
+		}},
+	}},
+}
+
+// FlavorWheel returns the tasting notes seeded into a catalog without any
+func FlavorWheel() []models.CatalogItem {
+	var items []models.CatalogItem
+	var walk func(flavors []flavor, parent string)
+	walk = func(flavors []flavor, parent string) {
+		for _, f := range flavors {
+			items = append(items, models.CatalogItem{
+				ID:           TastingNote + "_" + f.value,
+				Value:        f.value,
+				Label:        f.label,
+				Category:     TastingNote,
+				Parent:       parent,
+				Synonyms:     append([]string{}, f.synonyms...),
+				IsActive:     true,
+				DisplayOrder: len(items) + 1,
+			})
+			walk(f.children, f.value)
+		}
+	}
+	walk(flavorWheel, "")
+	return items
+}
+
+// SeedTastingNotes stores the flavor wheel when the catalog has no tasting notes yet
+func SeedTastingNotes(ctx context.Context, repo database.CatalogRepository) error {
+	existing, err := repo.List(ctx, TastingNote, 1, 0)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return nil
+	}
+
+	now := time.Now()
+	items := FlavorWheel()
+	for i := range items {
+		items[i].CreatedAt = now
+		if err := repo.Save(ctx, &items[i]); err != nil {
+			return err
+		}
+	}
+	log.Printf("Seeded %d tasting notes", len(items))
+	return nil
+}
@@ -0,0 +1,64 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"brew-detective-backend/internal/database"
+)
+
+func TestFlavorWheelIsATree(t *testing.T) {
+	items := FlavorWheel()
+	values := make(map[string]bool)
+	for _, item := range items {
+		if values[item.Value] {
+			t.Errorf("%s appears twice", item.Value)
+		}
+		values[item.Value] = true
+	}
+	for _, item := range items {
+		if item.Parent != "" && !values[item.Parent] {
+			t.Errorf("%s is under unknown parent %s", item.Value, item.Parent)
+		}
+	}
+
+	index := NewIndex(items)
+	parent := func(value string) string { return index.Parent(TastingNote, value) }
+	if got := fmt.Sprint(Ancestors("blackberry", parent)); got != "[berry fruity]" {
+		t.Errorf("Ancestors(blackberry) = %s, want [berry fruity]", got)
+	}
+	if value, ok := index.Resolve(TastingNote, "Frutilla"); !ok || value != "strawberry" {
+		t.Errorf("Resolve(Frutilla) = %q, %v", value, ok)
+	}
+}
+
+func TestAncestorsStopAtCycles(t *testing.T) {
+	parents := map[string]string{"a": "b", "b": "c", "c": "a"}
+	parent := func(value string) string { return parents[value] }
+
+	if got := fmt.Sprint(Ancestors("a", parent)); got != "[b c]" {
+		t.Errorf("Ancestors(a) = %s, want [b c]", got)
+	}
+	if got := Ancestors("top", parent); len(got) != 0 {
+		t.Errorf("Ancestors of a top-level note = %v", got)
+	}
+}
+
+func TestSeedTastingNotesOnlyOnce(t *testing.T) {
+	ctx := context.Background()
+	store := database.NewMemoryStore()
+
+	for i := 0; i < 2; i++ {
+		if err := SeedTastingNotes(ctx, store.Catalog()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	notes, err := store.Catalog().ListActive(ctx, TastingNote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != len(FlavorWheel()) {
+		t.Errorf("%d tasting notes, want %d", len(notes), len(FlavorWheel()))
+	}
+}
@@ -48,19 +48,22 @@ func InitFirestore() error {
 	return nil
 }
 
-// CloseFirestore closes the Firestore client
-func CloseFirestore() {
-	if FirestoreClient != nil {
-		FirestoreClient.Close()
-		log.Println("Firestore client closed")
-	}
-}
-
 // Collections
 const (
-	UsersCollection       = "users"
-	CasesCollection       = "cases"
-	SubmissionsCollection = "submissions"
-	OrdersCollection      = "orders"
-	CatalogCollection     = "catalog"
+	UsersCollection         = "users"
+	CasesCollection         = "cases"
+	SubmissionsCollection   = "submissions"
+	DraftsCollection        = "drafts"
+	TeamsCollection         = "teams"
+	OrdersCollection        = "orders"
+	CatalogCollection       = "catalog"
+	BadgesCollection        = "badges"
+	StandingsCollection     = "standings"
+	SeasonsCollection       = "seasons"
+	SessionsCollection      = "sessions"
+	RevokedTokensCollection = "revoked_tokens"
+	SigningKeysCollection   = "signing_keys"
+	IdentitiesCollection    = "identities"
+	LoginLinksCollection    = "login_links"
+	AuditCollection         = "audit_log"
 )
\ No newline at end of file
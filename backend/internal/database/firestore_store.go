@@ -0,0 +1,779 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"brew-detective-backend/internal/models"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"google.golang.org/api/iterator"
+)
+
+// FirestoreStore is the Store backed by Cloud Firestore
+type FirestoreStore struct {
+	conn firestoreConn
+}
+
+// NewFirestoreStore wraps an initialized Firestore client
+func NewFirestoreStore(client *firestore.Client) *FirestoreStore {
+	return &FirestoreStore{conn: firestoreConn{client: client}}
+}
+
+func (s *FirestoreStore) Users() UserRepository             { return &firestoreUsers{s.conn} }
+func (s *FirestoreStore) Cases() CaseRepository             { return &firestoreCases{s.conn} }
+func (s *FirestoreStore) Submissions() SubmissionRepository { return &firestoreSubmissions{s.conn} }
+func (s *FirestoreStore) Drafts() DraftRepository           { return &firestoreDrafts{s.conn} }
+func (s *FirestoreStore) Teams() TeamRepository             { return &firestoreTeams{s.conn} }
+func (s *FirestoreStore) Orders() OrderRepository           { return &firestoreOrders{s.conn} }
+func (s *FirestoreStore) Catalog() CatalogRepository        { return &firestoreCatalog{s.conn} }
+func (s *FirestoreStore) Badges() BadgeRepository           { return &firestoreBadges{s.conn} }
+func (s *FirestoreStore) Standings() StandingRepository     { return &firestoreStandings{s.conn} }
+func (s *FirestoreStore) Seasons() SeasonRepository         { return &firestoreSeasons{s.conn} }
+func (s *FirestoreStore) Sessions() SessionRepository       { return &firestoreSessions{s.conn} }
+func (s *FirestoreStore) RevokedTokens() RevokedTokenRepository {
+	return &firestoreRevokedTokens{s.conn}
+}
+func (s *FirestoreStore) SigningKeys() SigningKeyRepository {
+	return &firestoreSigningKeys{s.conn}
+}
+func (s *FirestoreStore) Identities() IdentityRepository  { return &firestoreIdentities{s.conn} }
+func (s *FirestoreStore) LoginLinks() LoginLinkRepository { return &firestoreLoginLinks{s.conn} }
+func (s *FirestoreStore) Audit() AuditRepository          { return &firestoreAudit{s.conn} }
+
+// RunTransaction runs fn inside a Firestore transaction, retrying on contention.
+// Firestore requires every read in fn to happen before its first write.
+func (s *FirestoreStore) RunTransaction(ctx context.Context, fn func(ctx context.Context, tx Store) error) error {
+	if s.conn.tx != nil {
+		return fn(ctx, s)
+	}
+	return s.conn.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		return fn(ctx, &FirestoreStore{conn: firestoreConn{client: s.conn.client, tx: tx}})
+	})
+}
+
+func (s *FirestoreStore) Close() error {
+	if s.conn.tx != nil {
+		return nil
+	}
+	return s.conn.client.Close()
+}
+
+// firestoreConn routes reads and writes through the transaction when one is open
+type firestoreConn struct {
+	client *firestore.Client
+	tx     *firestore.Transaction
+}
+
+func (c firestoreConn) collection(name string) *firestore.CollectionRef {
+	return c.client.Collection(name)
+}
+
+// get reads a single document into dst, mapping missing documents to ErrNotFound
+func (c firestoreConn) get(ctx context.Context, ref *firestore.DocumentRef, dst interface{}) error {
+	var doc *firestore.DocumentSnapshot
+	var err error
+	if c.tx != nil {
+		doc, err = c.tx.Get(ref)
+	} else {
+		doc, err = ref.Get(ctx)
+	}
+	if doc != nil && !doc.Exists() {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return doc.DataTo(dst)
+}
+
+func (c firestoreConn) documents(ctx context.Context, query firestore.Query) *firestore.DocumentIterator {
+	if c.tx != nil {
+		return c.tx.Documents(query)
+	}
+	return query.Documents(ctx)
+}
+
+func (c firestoreConn) set(ctx context.Context, ref *firestore.DocumentRef, data interface{}) error {
+	if c.tx != nil {
+		return c.tx.Set(ref, data)
+	}
+	_, err := ref.Set(ctx, data)
+	return err
+}
+
+func (c firestoreConn) update(ctx context.Context, ref *firestore.DocumentRef, updates map[string]interface{}) error {
+	if c.tx != nil {
+		return c.tx.Update(ref, toUpdates(updates))
+	}
+	_, err := ref.Update(ctx, toUpdates(updates))
+	return err
+}
+
+func (c firestoreConn) delete(ctx context.Context, ref *firestore.DocumentRef) error {
+	if c.tx != nil {
+		return c.tx.Delete(ref)
+	}
+	_, err := ref.Delete(ctx)
+	return err
+}
+
+// getAll drains a document iterator into a typed slice
+func getAll[T any](iter *firestore.DocumentIterator) ([]T, error) {
+	defer iter.Stop()
+
+	var items []T
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var item T
+		if err := doc.DataTo(&item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// toUpdates converts a field map into Firestore updates
+func toUpdates(updates map[string]interface{}) []firestore.Update {
+	var firestoreUpdates []firestore.Update
+	for key, value := range updates {
+		firestoreUpdates = append(firestoreUpdates, firestore.Update{
+			Path:  key,
+			Value: value,
+		})
+	}
+	return firestoreUpdates
+}
+
+// MigrateLegacyBadges reads the users as raw documents, since users with
+// string badges cannot be decoded into models.User
+func (s *FirestoreStore) MigrateLegacyBadges(ctx context.Context, convert func(legacy string) models.UserBadge) (int, error) {
+	iter := s.conn.collection(UsersCollection).Documents(ctx)
+	defer iter.Stop()
+
+	migrated := 0
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return migrated, err
+		}
+
+		stored, _ := doc.Data()["badges"].([]interface{})
+		badges := make([]interface{}, len(stored))
+		legacy := false
+		for i, badge := range stored {
+			if name, ok := badge.(string); ok {
+				badges[i] = convert(name)
+				legacy = true
+				continue
+			}
+			badges[i] = badge
+		}
+		if !legacy {
+			continue
+		}
+
+		// Skip users changed since they were read; the next start migrates them
+		_, err = doc.Ref.Update(ctx, []firestore.Update{{Path: "badges", Value: badges}}, firestore.LastUpdateTime(doc.UpdateTime))
+		if err != nil {
+			return migrated, fmt.Errorf("migrating badges of user %s: %w", doc.Ref.ID, err)
+		}
+		migrated++
+	}
+	return migrated, nil
+}
+
+type firestoreUsers struct {
+	conn firestoreConn
+}
+
+func (r *firestoreUsers) Get(ctx context.Context, id string) (*models.User, error) {
+	var user models.User
+	if err := r.conn.get(ctx, r.conn.collection(UsersCollection).Doc(id), &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *firestoreUsers) GetByVerifiedEmail(ctx context.Context, email string) (*models.User, error) {
+	users, err := getAll[models.User](r.conn.documents(ctx, r.conn.collection(UsersCollection).
+		Where("email", "==", email).Where("email_verified", "==", true).Limit(1)))
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, ErrNotFound
+	}
+	return &users[0], nil
+}
+
+func (r *firestoreUsers) List(ctx context.Context) ([]models.User, error) {
+	return getAll[models.User](r.conn.documents(ctx, r.conn.collection(UsersCollection).
+		OrderBy("name", firestore.Asc)))
+}
+
+func (r *firestoreUsers) ListHiddenFromLeaderboards(ctx context.Context) ([]models.User, error) {
+	return getAll[models.User](r.conn.documents(ctx, r.conn.collection(UsersCollection).
+		Where("privacy.hide_from_leaderboards", "==", true)))
+}
+
+func (r *firestoreUsers) Save(ctx context.Context, user *models.User) error {
+	return r.conn.set(ctx, r.conn.collection(UsersCollection).Doc(user.ID), user)
+}
+
+type firestoreCases struct {
+	conn firestoreConn
+}
+
+func (r *firestoreCases) Get(ctx context.Context, id string) (*models.CoffeeCase, error) {
+	var coffeeCase models.CoffeeCase
+	if err := r.conn.get(ctx, r.conn.collection(CasesCollection).Doc(id), &coffeeCase); err != nil {
+		return nil, err
+	}
+	return &coffeeCase, nil
+}
+
+func (r *firestoreCases) GetActive(ctx context.Context) (*models.CoffeeCase, error) {
+	cases, err := getAll[models.CoffeeCase](r.conn.documents(ctx, r.conn.collection(CasesCollection).
+		Where("is_active", "==", true).
+		Limit(1)))
+	if err != nil {
+		return nil, err
+	}
+	if len(cases) == 0 {
+		return nil, ErrNotFound
+	}
+	return &cases[0], nil
+}
+
+func (r *firestoreCases) ListActive(ctx context.Context) ([]models.CoffeeCase, error) {
+	return getAll[models.CoffeeCase](r.conn.documents(ctx, r.conn.collection(CasesCollection).
+		Where("is_active", "==", true)))
+}
+
+func (r *firestoreCases) List(ctx context.Context, limit, offset int) ([]models.CoffeeCase, error) {
+	return getAll[models.CoffeeCase](r.conn.documents(ctx, r.conn.collection(CasesCollection).
+		OrderBy("created_at", firestore.Desc).
+		Limit(limit).
+		Offset(offset)))
+}
+
+func (r *firestoreCases) ListCreatedBetween(ctx context.Context, from, to time.Time) ([]models.CoffeeCase, error) {
+	return getAll[models.CoffeeCase](r.conn.documents(ctx, r.conn.collection(CasesCollection).
+		Where("created_at", ">=", from).
+		Where("created_at", "<", to)))
+}
+
+func (r *firestoreCases) ListOpeningBy(ctx context.Context, at time.Time) ([]models.CoffeeCase, error) {
+	cases, err := getAll[models.CoffeeCase](r.conn.documents(ctx, r.conn.collection(CasesCollection).
+		Where("opens_at", "<=", at).
+		OrderBy("opens_at", firestore.Asc)))
+	if err != nil {
+		return nil, err
+	}
+	// Filtered here to avoid a composite index
+	inactive := cases[:0]
+	for _, coffeeCase := range cases {
+		if !coffeeCase.IsActive {
+			inactive = append(inactive, coffeeCase)
+		}
+	}
+	return inactive, nil
+}
+
+func (r *firestoreCases) ListClosedBy(ctx context.Context, at time.Time) ([]models.CoffeeCase, error) {
+	return getAll[models.CoffeeCase](r.conn.documents(ctx, r.conn.collection(CasesCollection).
+		Where("closed_at", "<=", at)))
+}
+
+func (r *firestoreCases) Save(ctx context.Context, coffeeCase *models.CoffeeCase) error {
+	return r.conn.set(ctx, r.conn.collection(CasesCollection).Doc(coffeeCase.ID), coffeeCase)
+}
+
+func (r *firestoreCases) Update(ctx context.Context, id string, updates map[string]interface{}) error {
+	return r.conn.update(ctx, r.conn.collection(CasesCollection).Doc(id), updates)
+}
+
+func (r *firestoreCases) Delete(ctx context.Context, id string) error {
+	return r.conn.delete(ctx, r.conn.collection(CasesCollection).Doc(id))
+}
+
+type firestoreSubmissions struct {
+	conn firestoreConn
+}
+
+func (r *firestoreSubmissions) Get(ctx context.Context, id string) (*models.Submission, error) {
+	var submission models.Submission
+	if err := r.conn.get(ctx, r.conn.collection(SubmissionsCollection).Doc(id), &submission); err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
+
+func (r *firestoreSubmissions) Save(ctx context.Context, submission *models.Submission) error {
+	return r.conn.set(ctx, r.conn.collection(SubmissionsCollection).Doc(submission.ID), submission)
+}
+
+func (r *firestoreSubmissions) ListByUser(ctx context.Context, userID string, limit, offset int) ([]models.Submission, error) {
+	query := r.conn.collection(SubmissionsCollection).
+		Where("user_id", "==", userID).
+		OrderBy("submitted_at", firestore.Desc).
+		Offset(offset)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	return getAll[models.Submission](r.conn.documents(ctx, query))
+}
+
+func (r *firestoreSubmissions) ListByCase(ctx context.Context, caseID string) ([]models.Submission, error) {
+	return getAll[models.Submission](r.conn.documents(ctx, r.conn.collection(SubmissionsCollection).
+		Where("case_id", "==", caseID)))
+}
+
+func (r *firestoreSubmissions) ListByTeam(ctx context.Context, teamID string) ([]models.Submission, error) {
+	return getAll[models.Submission](r.conn.documents(ctx, r.conn.collection(SubmissionsCollection).
+		Where("team_id", "==", teamID)))
+}
+
+type firestoreTeams struct {
+	conn firestoreConn
+}
+
+func (r *firestoreTeams) Get(ctx context.Context, id string) (*models.Team, error) {
+	var team models.Team
+	if err := r.conn.get(ctx, r.conn.collection(TeamsCollection).Doc(id), &team); err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+func (r *firestoreTeams) GetByInviteCode(ctx context.Context, code string) (*models.Team, error) {
+	teams, err := getAll[models.Team](r.conn.documents(ctx, r.conn.collection(TeamsCollection).
+		Where("invite_code", "==", code).
+		Limit(1)))
+	if err != nil {
+		return nil, err
+	}
+	if len(teams) == 0 {
+		return nil, ErrNotFound
+	}
+	return &teams[0], nil
+}
+
+func (r *firestoreTeams) List(ctx context.Context) ([]models.Team, error) {
+	return getAll[models.Team](r.conn.documents(ctx, r.conn.collection(TeamsCollection).Query))
+}
+
+func (r *firestoreTeams) ListByMember(ctx context.Context, userID string) ([]models.Team, error) {
+	return getAll[models.Team](r.conn.documents(ctx, r.conn.collection(TeamsCollection).
+		Where("member_ids", "array-contains", userID).
+		OrderBy("created_at", firestore.Desc)))
+}
+
+func (r *firestoreTeams) ListByCase(ctx context.Context, caseID string) ([]models.Team, error) {
+	return getAll[models.Team](r.conn.documents(ctx, r.conn.collection(TeamsCollection).
+		Where("case_id", "==", caseID)))
+}
+
+func (r *firestoreTeams) Save(ctx context.Context, team *models.Team) error {
+	return r.conn.set(ctx, r.conn.collection(TeamsCollection).Doc(team.ID), team)
+}
+
+type firestoreDrafts struct {
+	conn firestoreConn
+}
+
+func (r *firestoreDrafts) Get(ctx context.Context, id string) (*models.Draft, error) {
+	var draft models.Draft
+	if err := r.conn.get(ctx, r.conn.collection(DraftsCollection).Doc(id), &draft); err != nil {
+		return nil, err
+	}
+	return &draft, nil
+}
+
+func (r *firestoreDrafts) ListByUser(ctx context.Context, userID string) ([]models.Draft, error) {
+	return getAll[models.Draft](r.conn.documents(ctx, r.conn.collection(DraftsCollection).
+		Where("user_id", "==", userID).
+		OrderBy("updated_at", firestore.Desc)))
+}
+
+func (r *firestoreDrafts) Save(ctx context.Context, draft *models.Draft) error {
+	return r.conn.set(ctx, r.conn.collection(DraftsCollection).Doc(draft.ID), draft)
+}
+
+type firestoreOrders struct {
+	conn firestoreConn
+}
+
+func (r *firestoreOrders) Get(ctx context.Context, id string) (*models.Order, error) {
+	var order models.Order
+	if err := r.conn.get(ctx, r.conn.collection(OrdersCollection).Doc(id), &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *firestoreOrders) GetByOrderID(ctx context.Context, orderID string) (*models.Order, error) {
+	orders, err := getAll[models.Order](r.conn.documents(ctx, r.conn.collection(OrdersCollection).
+		Where("order_id", "==", orderID).
+		Limit(1)))
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, ErrNotFound
+	}
+	return &orders[0], nil
+}
+
+func (r *firestoreOrders) List(ctx context.Context, limit, offset int) ([]models.Order, error) {
+	return getAll[models.Order](r.conn.documents(ctx, r.conn.collection(OrdersCollection).
+		OrderBy("created_at", firestore.Desc).
+		Limit(limit).
+		Offset(offset)))
+}
+
+func (r *firestoreOrders) Save(ctx context.Context, order *models.Order) error {
+	return r.conn.set(ctx, r.conn.collection(OrdersCollection).Doc(order.ID), order)
+}
+
+type firestoreCatalog struct {
+	conn firestoreConn
+}
+
+func (r *firestoreCatalog) Get(ctx context.Context, id string) (*models.CatalogItem, error) {
+	var item models.CatalogItem
+	if err := r.conn.get(ctx, r.conn.collection(CatalogCollection).Doc(id), &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *firestoreCatalog) ListActive(ctx context.Context, category string) ([]models.CatalogItem, error) {
+	query := r.conn.collection(CatalogCollection).Query
+	if category != "" {
+		query = query.Where("category", "==", category)
+	}
+	return getAll[models.CatalogItem](r.conn.documents(ctx, query.Where("is_active", "==", true)))
+}
+
+func (r *firestoreCatalog) List(ctx context.Context, category string, limit, offset int) ([]models.CatalogItem, error) {
+	query := r.conn.collection(CatalogCollection).
+		OrderBy("category", firestore.Asc).
+		OrderBy("display_order", firestore.Asc).
+		Limit(limit).
+		Offset(offset)
+
+	if category != "" {
+		query = query.Where("category", "==", category)
+	}
+
+	return getAll[models.CatalogItem](r.conn.documents(ctx, query))
+}
+
+func (r *firestoreCatalog) Save(ctx context.Context, item *models.CatalogItem) error {
+	return r.conn.set(ctx, r.conn.collection(CatalogCollection).Doc(item.ID), item)
+}
+
+func (r *firestoreCatalog) Update(ctx context.Context, id string, updates map[string]interface{}) error {
+	return r.conn.update(ctx, r.conn.collection(CatalogCollection).Doc(id), updates)
+}
+
+func (r *firestoreCatalog) Delete(ctx context.Context, id string) error {
+	return r.conn.delete(ctx, r.conn.collection(CatalogCollection).Doc(id))
+}
+
+type firestoreBadges struct {
+	conn firestoreConn
+}
+
+func (r *firestoreBadges) Get(ctx context.Context, id string) (*models.BadgeDefinition, error) {
+	var badge models.BadgeDefinition
+	if err := r.conn.get(ctx, r.conn.collection(BadgesCollection).Doc(id), &badge); err != nil {
+		return nil, err
+	}
+	return &badge, nil
+}
+
+func (r *firestoreBadges) List(ctx context.Context) ([]models.BadgeDefinition, error) {
+	return getAll[models.BadgeDefinition](r.conn.documents(ctx, r.conn.collection(BadgesCollection).
+		OrderBy("display_order", firestore.Asc)))
+}
+
+func (r *firestoreBadges) Save(ctx context.Context, badge *models.BadgeDefinition) error {
+	return r.conn.set(ctx, r.conn.collection(BadgesCollection).Doc(badge.ID), badge)
+}
+
+func (r *firestoreBadges) Delete(ctx context.Context, id string) error {
+	return r.conn.delete(ctx, r.conn.collection(BadgesCollection).Doc(id))
+}
+
+type firestoreStandings struct {
+	conn firestoreConn
+}
+
+func (r *firestoreStandings) Get(ctx context.Context, board, userID string) (*models.Standing, error) {
+	var standing models.Standing
+	if err := r.conn.get(ctx, r.conn.collection(StandingsCollection).Doc(StandingID(board, userID)), &standing); err != nil {
+		return nil, err
+	}
+	return &standing, nil
+}
+
+func (r *firestoreStandings) ListByUser(ctx context.Context, userID string) ([]models.Standing, error) {
+	return getAll[models.Standing](r.conn.documents(ctx, r.conn.collection(StandingsCollection).Where("user_id", "==", userID)))
+}
+
+// ranked orders a board's standings as described by RanksBefore
+func (r *firestoreStandings) ranked(board string) firestore.Query {
+	return r.conn.collection(StandingsCollection).
+		Where("board", "==", board).
+		OrderBy("points", firestore.Desc).
+		OrderBy("accuracy", firestore.Desc).
+		OrderBy("reached_at", firestore.Asc).
+		OrderBy("user_id", firestore.Asc)
+}
+
+func (r *firestoreStandings) Page(ctx context.Context, board string, after *models.Standing, limit int) ([]models.Standing, error) {
+	query := r.ranked(board)
+	if after != nil {
+		query = query.StartAfter(after.Points, after.Accuracy, after.ReachedAt, after.UserID)
+	}
+	return getAll[models.Standing](r.conn.documents(ctx, query.Limit(limit)))
+}
+
+func (r *firestoreStandings) CountAhead(ctx context.Context, standing *models.Standing) (int, error) {
+	// One count per tie-break level keeps each query to a single inequality
+	board := r.conn.collection(StandingsCollection).Where("board", "==", standing.Board)
+	samePoints := board.Where("points", "==", standing.Points)
+	sameAccuracy := samePoints.Where("accuracy", "==", standing.Accuracy)
+	queries := []firestore.Query{
+		board.Where("points", ">", standing.Points),
+		samePoints.Where("accuracy", ">", standing.Accuracy),
+		sameAccuracy.Where("reached_at", "<", standing.ReachedAt),
+		sameAccuracy.Where("reached_at", "==", standing.ReachedAt).Where("user_id", "<", standing.UserID),
+	}
+
+	ahead := 0
+	for _, query := range queries {
+		count, err := r.count(ctx, query)
+		if err != nil {
+			return 0, err
+		}
+		ahead += count
+	}
+	return ahead, nil
+}
+
+func (r *firestoreStandings) Count(ctx context.Context, board string) (int, error) {
+	return r.count(ctx, r.conn.collection(StandingsCollection).Where("board", "==", board))
+}
+
+func (r *firestoreStandings) count(ctx context.Context, query firestore.Query) (int, error) {
+	result, err := query.NewAggregationQuery().WithCount("count").Get(ctx)
+	if err != nil {
+		return 0, err
+	}
+	value, ok := result["count"].(*firestorepb.Value)
+	if !ok {
+		return 0, fmt.Errorf("unexpected count result %T", result["count"])
+	}
+	return int(value.GetIntegerValue()), nil
+}
+
+func (r *firestoreStandings) Save(ctx context.Context, standing *models.Standing) error {
+	standing.ID = StandingID(standing.Board, standing.UserID)
+	return r.conn.set(ctx, r.conn.collection(StandingsCollection).Doc(standing.ID), standing)
+}
+
+func (r *firestoreStandings) Delete(ctx context.Context, board, userID string) error {
+	return r.conn.delete(ctx, r.conn.collection(StandingsCollection).Doc(StandingID(board, userID)))
+}
+
+type firestoreSeasons struct {
+	conn firestoreConn
+}
+
+func (r *firestoreSeasons) Get(ctx context.Context, id string) (*models.Season, error) {
+	var season models.Season
+	if err := r.conn.get(ctx, r.conn.collection(SeasonsCollection).Doc(id), &season); err != nil {
+		return nil, err
+	}
+	return &season, nil
+}
+
+func (r *firestoreSeasons) List(ctx context.Context) ([]models.Season, error) {
+	return getAll[models.Season](r.conn.documents(ctx, r.conn.collection(SeasonsCollection).
+		OrderBy("starts_at", firestore.Asc)))
+}
+
+func (r *firestoreSeasons) Save(ctx context.Context, season *models.Season) error {
+	return r.conn.set(ctx, r.conn.collection(SeasonsCollection).Doc(season.ID), season)
+}
+
+func (r *firestoreSeasons) Delete(ctx context.Context, id string) error {
+	return r.conn.delete(ctx, r.conn.collection(SeasonsCollection).Doc(id))
+}
+
+type firestoreSessions struct {
+	conn firestoreConn
+}
+
+func (r *firestoreSessions) Get(ctx context.Context, id string) (*models.Session, error) {
+	var session models.Session
+	if err := r.conn.get(ctx, r.conn.collection(SessionsCollection).Doc(id), &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *firestoreSessions) ListByUser(ctx context.Context, userID string) ([]models.Session, error) {
+	return getAll[models.Session](r.conn.documents(ctx, r.conn.collection(SessionsCollection).Where("user_id", "==", userID)))
+}
+
+func (r *firestoreSessions) Save(ctx context.Context, session *models.Session) error {
+	return r.conn.set(ctx, r.conn.collection(SessionsCollection).Doc(session.ID), session)
+}
+
+// firestoreRevokedTokens stores one document per revoked JWT ID. A Firestore
+// TTL policy on expires_at can clean up entries once the tokens have expired.
+type firestoreRevokedTokens struct {
+	conn firestoreConn
+}
+
+func (r *firestoreRevokedTokens) Add(ctx context.Context, token models.IssuedToken) error {
+	return r.conn.set(ctx, r.conn.collection(RevokedTokensCollection).Doc(token.JTI), token)
+}
+
+func (r *firestoreRevokedTokens) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	var token models.IssuedToken
+	err := r.conn.get(ctx, r.conn.collection(RevokedTokensCollection).Doc(jti), &token)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+type firestoreSigningKeys struct {
+	conn firestoreConn
+}
+
+func (r *firestoreSigningKeys) List(ctx context.Context) ([]models.SigningKey, error) {
+	return getAll[models.SigningKey](r.conn.documents(ctx, r.conn.collection(SigningKeysCollection).
+		OrderBy("not_before", firestore.Asc)))
+}
+
+func (r *firestoreSigningKeys) Save(ctx context.Context, key *models.SigningKey) error {
+	return r.conn.set(ctx, r.conn.collection(SigningKeysCollection).Doc(key.ID), key)
+}
+
+func (r *firestoreSigningKeys) Delete(ctx context.Context, id string) error {
+	return r.conn.delete(ctx, r.conn.collection(SigningKeysCollection).Doc(id))
+}
+
+type firestoreIdentities struct {
+	conn firestoreConn
+}
+
+func (r *firestoreIdentities) Get(ctx context.Context, id string) (*models.Identity, error) {
+	var identity models.Identity
+	if err := r.conn.get(ctx, r.conn.collection(IdentitiesCollection).Doc(id), &identity); err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *firestoreIdentities) ListByUser(ctx context.Context, userID string) ([]models.Identity, error) {
+	return getAll[models.Identity](r.conn.documents(ctx, r.conn.collection(IdentitiesCollection).Where("user_id", "==", userID)))
+}
+
+func (r *firestoreIdentities) Save(ctx context.Context, identity *models.Identity) error {
+	return r.conn.set(ctx, r.conn.collection(IdentitiesCollection).Doc(identity.ID), identity)
+}
+
+func (r *firestoreIdentities) Delete(ctx context.Context, id string) error {
+	return r.conn.delete(ctx, r.conn.collection(IdentitiesCollection).Doc(id))
+}
+
+type firestoreLoginLinks struct {
+	conn firestoreConn
+}
+
+func (r *firestoreLoginLinks) Get(ctx context.Context, id string) (*models.LoginLink, error) {
+	var link models.LoginLink
+	if err := r.conn.get(ctx, r.conn.collection(LoginLinksCollection).Doc(id), &link); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *firestoreLoginLinks) Save(ctx context.Context, link *models.LoginLink) error {
+	return r.conn.set(ctx, r.conn.collection(LoginLinksCollection).Doc(link.ID), link)
+}
+
+func (r *firestoreLoginLinks) Delete(ctx context.Context, id string) error {
+	return r.conn.delete(ctx, r.conn.collection(LoginLinksCollection).Doc(id))
+}
+
+type firestoreAudit struct {
+	conn firestoreConn
+}
+
+func (r *firestoreAudit) Append(ctx context.Context, entry *models.AuditEntry) error {
+	ref := r.conn.collection(AuditCollection).Doc(entry.ID)
+	if r.conn.tx != nil {
+		return r.conn.tx.Create(ref, entry)
+	}
+	_, err := ref.Create(ctx, entry)
+	return err
+}
+
+func (r *firestoreAudit) Query(ctx context.Context, filter AuditFilter) ([]models.AuditEntry, error) {
+	query := r.conn.collection(AuditCollection).Query
+	for field, value := range map[string]string{
+		"actor":       filter.Actor,
+		"action":      filter.Action,
+		"target_type": filter.TargetType,
+		"target_id":   filter.TargetID,
+	} {
+		if value != "" {
+			query = query.Where(field, "==", value)
+		}
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at", ">=", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at", "<", filter.To)
+	}
+	query = query.OrderBy("created_at", firestore.Desc)
+
+	if filter.After != "" {
+		cursor, err := r.conn.collection(AuditCollection).Doc(filter.After).Get(ctx)
+		if cursor != nil && !cursor.Exists() {
+			return nil, ErrNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		query = query.StartAfter(cursor)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	return getAll[models.AuditEntry](r.conn.documents(ctx, query))
+}
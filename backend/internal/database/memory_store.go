@@ -0,0 +1,1222 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"brew-detective-backend/internal/models"
+)
+
+// MemoryStore is a Store that keeps every collection in process memory.
+// It lets the API boot without GCP credentials and is meant for local development.
+type MemoryStore struct {
+	*memoryData
+	// inTx is set on the view handed to a transaction, which already holds the write lock
+	inTx bool
+}
+
+type memoryData struct {
+	mu          sync.RWMutex
+	users       map[string]models.User
+	cases       map[string]models.CoffeeCase
+	submissions map[string]models.Submission
+	drafts      map[string]models.Draft
+	teams       map[string]models.Team
+	orders      map[string]models.Order
+	catalog     map[string]models.CatalogItem
+	badges      map[string]models.BadgeDefinition
+	standings   map[string]models.Standing
+	seasons     map[string]models.Season
+	sessions    map[string]models.Session
+	revoked     map[string]models.IssuedToken
+	signingKeys map[string]models.SigningKey
+	identities  map[string]models.Identity
+	loginLinks  map[string]models.LoginLink
+	audit       []models.AuditEntry // In insertion order
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{memoryData: &memoryData{
+		users:       make(map[string]models.User),
+		cases:       make(map[string]models.CoffeeCase),
+		submissions: make(map[string]models.Submission),
+		drafts:      make(map[string]models.Draft),
+		teams:       make(map[string]models.Team),
+		orders:      make(map[string]models.Order),
+		catalog:     make(map[string]models.CatalogItem),
+		badges:      make(map[string]models.BadgeDefinition),
+		standings:   make(map[string]models.Standing),
+		seasons:     make(map[string]models.Season),
+		sessions:    make(map[string]models.Session),
+		revoked:     make(map[string]models.IssuedToken),
+		signingKeys: make(map[string]models.SigningKey),
+		identities:  make(map[string]models.Identity),
+		loginLinks:  make(map[string]models.LoginLink),
+	}}
+}
+
+func (s *MemoryStore) Users() UserRepository             { return &memoryUsers{s} }
+func (s *MemoryStore) Cases() CaseRepository             { return &memoryCases{s} }
+func (s *MemoryStore) Submissions() SubmissionRepository { return &memorySubmissions{s} }
+func (s *MemoryStore) Drafts() DraftRepository           { return &memoryDrafts{s} }
+func (s *MemoryStore) Teams() TeamRepository             { return &memoryTeams{s} }
+func (s *MemoryStore) Orders() OrderRepository           { return &memoryOrders{s} }
+func (s *MemoryStore) Catalog() CatalogRepository        { return &memoryCatalog{s} }
+func (s *MemoryStore) Badges() BadgeRepository           { return &memoryBadges{s} }
+func (s *MemoryStore) Standings() StandingRepository     { return &memoryStandings{s} }
+func (s *MemoryStore) Seasons() SeasonRepository         { return &memorySeasons{s} }
+func (s *MemoryStore) Sessions() SessionRepository       { return &memorySessions{s} }
+func (s *MemoryStore) RevokedTokens() RevokedTokenRepository {
+	return &memoryRevokedTokens{s}
+}
+func (s *MemoryStore) SigningKeys() SigningKeyRepository { return &memorySigningKeys{s} }
+func (s *MemoryStore) Identities() IdentityRepository    { return &memoryIdentities{s} }
+func (s *MemoryStore) LoginLinks() LoginLinkRepository   { return &memoryLoginLinks{s} }
+func (s *MemoryStore) Audit() AuditRepository            { return &memoryAudit{s} }
+
+// RunTransaction runs fn while holding the store's write lock and restores
+// every collection to its previous state if fn returns an error
+func (s *MemoryStore) RunTransaction(ctx context.Context, fn func(ctx context.Context, tx Store) error) error {
+	if s.inTx {
+		return fn(ctx, s)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.snapshot()
+	if err := fn(ctx, &MemoryStore{memoryData: s.memoryData, inTx: true}); err != nil {
+		s.users = snapshot.users
+		s.cases = snapshot.cases
+		s.submissions = snapshot.submissions
+		s.drafts = snapshot.drafts
+		s.teams = snapshot.teams
+		s.orders = snapshot.orders
+		s.catalog = snapshot.catalog
+		s.badges = snapshot.badges
+		s.standings = snapshot.standings
+		s.seasons = snapshot.seasons
+		s.sessions = snapshot.sessions
+		s.revoked = snapshot.revoked
+		s.signingKeys = snapshot.signingKeys
+		s.identities = snapshot.identities
+		s.loginLinks = snapshot.loginLinks
+		s.audit = snapshot.audit
+		return err
+	}
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// snapshot copies the collection maps. Stored values are never mutated in place,
+// so copying the maps is enough to roll a transaction back.
+func (s *MemoryStore) snapshot() *memoryData {
+	return &memoryData{
+		users:       copyMap(s.users),
+		cases:       copyMap(s.cases),
+		submissions: copyMap(s.submissions),
+		drafts:      copyMap(s.drafts),
+		teams:       copyMap(s.teams),
+		orders:      copyMap(s.orders),
+		catalog:     copyMap(s.catalog),
+		badges:      copyMap(s.badges),
+		standings:   copyMap(s.standings),
+		seasons:     copyMap(s.seasons),
+		sessions:    copyMap(s.sessions),
+		revoked:     copyMap(s.revoked),
+		signingKeys: copyMap(s.signingKeys),
+		identities:  copyMap(s.identities),
+		loginLinks:  copyMap(s.loginLinks),
+		audit:       s.audit[:len(s.audit):len(s.audit)],
+	}
+}
+
+func (s *MemoryStore) rlock() {
+	if !s.inTx {
+		s.mu.RLock()
+	}
+}
+
+func (s *MemoryStore) runlock() {
+	if !s.inTx {
+		s.mu.RUnlock()
+	}
+}
+
+func (s *MemoryStore) lock() {
+	if !s.inTx {
+		s.mu.Lock()
+	}
+}
+
+func (s *MemoryStore) unlock() {
+	if !s.inTx {
+		s.mu.Unlock()
+	}
+}
+
+func copyMap[K comparable, V any](m map[K]V) map[K]V {
+	copied := make(map[K]V, len(m))
+	for key, value := range m {
+		copied[key] = value
+	}
+	return copied
+}
+
+// paginate applies offset and limit the same way a Firestore query does
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// mergeFields applies a partial update keyed by document field names onto dst
+func mergeFields(dst interface{}, updates map[string]interface{}) error {
+	data, err := json.Marshal(dst)
+	if err != nil {
+		return err
+	}
+
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for key, value := range updates {
+		fields[key] = value
+	}
+
+	if data, err = json.Marshal(fields); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+func cloneUser(user models.User) models.User {
+	user.Badges = append([]models.UserBadge(nil), user.Badges...)
+	user.Roles = append([]string(nil), user.Roles...)
+	return user
+}
+
+func cloneCase(coffeeCase models.CoffeeCase) models.CoffeeCase {
+	coffeeCase.Coffees = append([]models.CoffeeItem(nil), coffeeCase.Coffees...)
+	for i := range coffeeCase.Coffees {
+		coffeeCase.Coffees[i].TastingNoteIDs = append([]string(nil), coffeeCase.Coffees[i].TastingNoteIDs...)
+	}
+	if coffeeCase.ScoringRules != nil {
+		rules := *coffeeCase.ScoringRules
+		coffeeCase.ScoringRules = &rules
+	}
+	if coffeeCase.OpensAt != nil {
+		opensAt := *coffeeCase.OpensAt
+		coffeeCase.OpensAt = &opensAt
+	}
+	if coffeeCase.ClosesAt != nil {
+		closesAt := *coffeeCase.ClosesAt
+		coffeeCase.ClosesAt = &closesAt
+	}
+	if coffeeCase.ClosedAt != nil {
+		closedAt := *coffeeCase.ClosedAt
+		coffeeCase.ClosedAt = &closedAt
+	}
+	if coffeeCase.FinalizedAt != nil {
+		finalizedAt := *coffeeCase.FinalizedAt
+		coffeeCase.FinalizedAt = &finalizedAt
+	}
+	if coffeeCase.ResultsRevealedAt != nil {
+		revealedAt := *coffeeCase.ResultsRevealedAt
+		coffeeCase.ResultsRevealedAt = &revealedAt
+	}
+	return coffeeCase
+}
+
+func cloneSubmission(submission models.Submission) models.Submission {
+	submission.CoffeeAnswers = append([]models.CoffeeAnswer(nil), submission.CoffeeAnswers...)
+	submission.Breakdown = append([]models.QuestionScore(nil), submission.Breakdown...)
+	if submission.ProcessedAt != nil {
+		processedAt := *submission.ProcessedAt
+		submission.ProcessedAt = &processedAt
+	}
+	return submission
+}
+
+func cloneDraft(draft models.Draft) models.Draft {
+	draft.CoffeeAnswers = append([]models.CoffeeAnswer(nil), draft.CoffeeAnswers...)
+	if draft.FinalizedAt != nil {
+		finalizedAt := *draft.FinalizedAt
+		draft.FinalizedAt = &finalizedAt
+	}
+	return draft
+}
+
+func cloneTeam(team models.Team) models.Team {
+	team.MemberIDs = append([]string(nil), team.MemberIDs...)
+	return team
+}
+
+func cloneOrder(order models.Order) models.Order {
+	if order.SubmissionUsedAt != nil {
+		usedAt := *order.SubmissionUsedAt
+		order.SubmissionUsedAt = &usedAt
+	}
+	order.StatusHistory = append([]models.OrderStatusChange(nil), order.StatusHistory...)
+	return order
+}
+
+type memoryUsers struct {
+	s *MemoryStore
+}
+
+func (r *memoryUsers) Get(ctx context.Context, id string) (*models.User, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	user, ok := r.s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	user = cloneUser(user)
+	return &user, nil
+}
+
+func (r *memoryUsers) GetByVerifiedEmail(ctx context.Context, email string) (*models.User, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	for _, user := range r.s.users {
+		if user.Email == email && user.EmailVerified {
+			user = cloneUser(user)
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryUsers) List(ctx context.Context) ([]models.User, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	users := make([]models.User, 0, len(r.s.users))
+	for _, user := range r.s.users {
+		users = append(users, cloneUser(user))
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Name < users[j].Name
+	})
+	return users, nil
+}
+
+func (r *memoryUsers) ListHiddenFromLeaderboards(ctx context.Context) ([]models.User, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	var users []models.User
+	for _, user := range r.s.users {
+		if user.Privacy.HideFromLeaderboards {
+			users = append(users, cloneUser(user))
+		}
+	}
+	return users, nil
+}
+
+func (r *memoryUsers) Save(ctx context.Context, user *models.User) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	r.s.users[user.ID] = cloneUser(*user)
+	return nil
+}
+
+type memoryCases struct {
+	s *MemoryStore
+}
+
+func (r *memoryCases) Get(ctx context.Context, id string) (*models.CoffeeCase, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	coffeeCase, ok := r.s.cases[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	coffeeCase = cloneCase(coffeeCase)
+	return &coffeeCase, nil
+}
+
+func (r *memoryCases) GetActive(ctx context.Context) (*models.CoffeeCase, error) {
+	cases, err := r.ListActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(cases) == 0 {
+		return nil, ErrNotFound
+	}
+	return &cases[0], nil
+}
+
+func (r *memoryCases) ListActive(ctx context.Context) ([]models.CoffeeCase, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	var cases []models.CoffeeCase
+	for _, coffeeCase := range r.s.cases {
+		if coffeeCase.IsActive {
+			cases = append(cases, cloneCase(coffeeCase))
+		}
+	}
+	sort.Slice(cases, func(i, j int) bool {
+		return cases[i].ID < cases[j].ID
+	})
+	return cases, nil
+}
+
+func (r *memoryCases) List(ctx context.Context, limit, offset int) ([]models.CoffeeCase, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	cases := make([]models.CoffeeCase, 0, len(r.s.cases))
+	for _, coffeeCase := range r.s.cases {
+		cases = append(cases, cloneCase(coffeeCase))
+	}
+	sort.Slice(cases, func(i, j int) bool {
+		return cases[i].CreatedAt.After(cases[j].CreatedAt)
+	})
+	return paginate(cases, limit, offset), nil
+}
+
+func (r *memoryCases) ListCreatedBetween(ctx context.Context, from, to time.Time) ([]models.CoffeeCase, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	var cases []models.CoffeeCase
+	for _, coffeeCase := range r.s.cases {
+		if !coffeeCase.CreatedAt.Before(from) && coffeeCase.CreatedAt.Before(to) {
+			cases = append(cases, cloneCase(coffeeCase))
+		}
+	}
+	return cases, nil
+}
+
+func (r *memoryCases) ListOpeningBy(ctx context.Context, at time.Time) ([]models.CoffeeCase, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	var cases []models.CoffeeCase
+	for _, coffeeCase := range r.s.cases {
+		if !coffeeCase.IsActive && coffeeCase.OpensAt != nil && !coffeeCase.OpensAt.After(at) {
+			cases = append(cases, cloneCase(coffeeCase))
+		}
+	}
+	sort.Slice(cases, func(i, j int) bool {
+		return cases[i].OpensAt.Before(*cases[j].OpensAt)
+	})
+	return cases, nil
+}
+
+func (r *memoryCases) ListClosedBy(ctx context.Context, at time.Time) ([]models.CoffeeCase, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	var cases []models.CoffeeCase
+	for _, coffeeCase := range r.s.cases {
+		if coffeeCase.ClosedAt != nil && !coffeeCase.ClosedAt.After(at) {
+			cases = append(cases, cloneCase(coffeeCase))
+		}
+	}
+	return cases, nil
+}
+
+func (r *memoryCases) Save(ctx context.Context, coffeeCase *models.CoffeeCase) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	r.s.cases[coffeeCase.ID] = cloneCase(*coffeeCase)
+	return nil
+}
+
+func (r *memoryCases) Update(ctx context.Context, id string, updates map[string]interface{}) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	coffeeCase, ok := r.s.cases[id]
+	if !ok {
+		return ErrNotFound
+	}
+	coffeeCase = cloneCase(coffeeCase)
+	if err := mergeFields(&coffeeCase, updates); err != nil {
+		return err
+	}
+	r.s.cases[id] = coffeeCase
+	return nil
+}
+
+func (r *memoryCases) Delete(ctx context.Context, id string) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	delete(r.s.cases, id)
+	return nil
+}
+
+type memorySubmissions struct {
+	s *MemoryStore
+}
+
+func (r *memorySubmissions) Get(ctx context.Context, id string) (*models.Submission, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	submission, ok := r.s.submissions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	submission = cloneSubmission(submission)
+	return &submission, nil
+}
+
+func (r *memorySubmissions) Save(ctx context.Context, submission *models.Submission) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	r.s.submissions[submission.ID] = cloneSubmission(*submission)
+	return nil
+}
+
+func (r *memorySubmissions) ListByUser(ctx context.Context, userID string, limit, offset int) ([]models.Submission, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	var submissions []models.Submission
+	for _, submission := range r.s.submissions {
+		if submission.UserID == userID {
+			submissions = append(submissions, cloneSubmission(submission))
+		}
+	}
+	sort.Slice(submissions, func(i, j int) bool {
+		return submissions[i].SubmittedAt.After(submissions[j].SubmittedAt)
+	})
+	return paginate(submissions, limit, offset), nil
+}
+
+func (r *memorySubmissions) ListByCase(ctx context.Context, caseID string) ([]models.Submission, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	var submissions []models.Submission
+	for _, submission := range r.s.submissions {
+		if submission.CaseID == caseID {
+			submissions = append(submissions, cloneSubmission(submission))
+		}
+	}
+	sort.Slice(submissions, func(i, j int) bool {
+		return submissions[i].SubmittedAt.Before(submissions[j].SubmittedAt)
+	})
+	return submissions, nil
+}
+
+func (r *memorySubmissions) ListByTeam(ctx context.Context, teamID string) ([]models.Submission, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	var submissions []models.Submission
+	for _, submission := range r.s.submissions {
+		if submission.TeamID == teamID {
+			submissions = append(submissions, cloneSubmission(submission))
+		}
+	}
+	return submissions, nil
+}
+
+type memoryTeams struct {
+	s *MemoryStore
+}
+
+func (r *memoryTeams) Get(ctx context.Context, id string) (*models.Team, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	team, ok := r.s.teams[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	team = cloneTeam(team)
+	return &team, nil
+}
+
+func (r *memoryTeams) GetByInviteCode(ctx context.Context, code string) (*models.Team, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	for _, team := range r.s.teams {
+		if team.InviteCode == code {
+			team = cloneTeam(team)
+			return &team, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryTeams) List(ctx context.Context) ([]models.Team, error) {
+	return r.filter(func(models.Team) bool { return true }), nil
+}
+
+func (r *memoryTeams) ListByMember(ctx context.Context, userID string) ([]models.Team, error) {
+	teams := r.filter(func(team models.Team) bool {
+		for _, memberID := range team.MemberIDs {
+			if memberID == userID {
+				return true
+			}
+		}
+		return false
+	})
+	sort.Slice(teams, func(i, j int) bool {
+		return teams[i].CreatedAt.After(teams[j].CreatedAt)
+	})
+	return teams, nil
+}
+
+func (r *memoryTeams) ListByCase(ctx context.Context, caseID string) ([]models.Team, error) {
+	return r.filter(func(team models.Team) bool { return team.CaseID == caseID }), nil
+}
+
+func (r *memoryTeams) filter(match func(models.Team) bool) []models.Team {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	var teams []models.Team
+	for _, team := range r.s.teams {
+		if match(team) {
+			teams = append(teams, cloneTeam(team))
+		}
+	}
+	return teams
+}
+
+func (r *memoryTeams) Save(ctx context.Context, team *models.Team) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	r.s.teams[team.ID] = cloneTeam(*team)
+	return nil
+}
+
+type memoryDrafts struct {
+	s *MemoryStore
+}
+
+func (r *memoryDrafts) Get(ctx context.Context, id string) (*models.Draft, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	draft, ok := r.s.drafts[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	draft = cloneDraft(draft)
+	return &draft, nil
+}
+
+func (r *memoryDrafts) ListByUser(ctx context.Context, userID string) ([]models.Draft, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	var drafts []models.Draft
+	for _, draft := range r.s.drafts {
+		if draft.UserID == userID {
+			drafts = append(drafts, cloneDraft(draft))
+		}
+	}
+	sort.Slice(drafts, func(i, j int) bool {
+		return drafts[i].UpdatedAt.After(drafts[j].UpdatedAt)
+	})
+	return drafts, nil
+}
+
+func (r *memoryDrafts) Save(ctx context.Context, draft *models.Draft) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	r.s.drafts[draft.ID] = cloneDraft(*draft)
+	return nil
+}
+
+type memoryOrders struct {
+	s *MemoryStore
+}
+
+func (r *memoryOrders) Get(ctx context.Context, id string) (*models.Order, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	order, ok := r.s.orders[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	order = cloneOrder(order)
+	return &order, nil
+}
+
+func (r *memoryOrders) GetByOrderID(ctx context.Context, orderID string) (*models.Order, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	for _, order := range r.s.orders {
+		if order.OrderID == orderID {
+			order = cloneOrder(order)
+			return &order, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryOrders) List(ctx context.Context, limit, offset int) ([]models.Order, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	orders := make([]models.Order, 0, len(r.s.orders))
+	for _, order := range r.s.orders {
+		orders = append(orders, cloneOrder(order))
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].CreatedAt.After(orders[j].CreatedAt)
+	})
+	return paginate(orders, limit, offset), nil
+}
+
+func (r *memoryOrders) Save(ctx context.Context, order *models.Order) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	r.s.orders[order.ID] = cloneOrder(*order)
+	return nil
+}
+
+type memoryCatalog struct {
+	s *MemoryStore
+}
+
+func (r *memoryCatalog) Get(ctx context.Context, id string) (*models.CatalogItem, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	item, ok := r.s.catalog[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	item = cloneCatalogItem(item)
+	return &item, nil
+}
+
+func (r *memoryCatalog) ListActive(ctx context.Context, category string) ([]models.CatalogItem, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	var items []models.CatalogItem
+	for _, item := range r.s.catalog {
+		if item.IsActive && (category == "" || item.Category == category) {
+			items = append(items, cloneCatalogItem(item))
+		}
+	}
+	return items, nil
+}
+
+func (r *memoryCatalog) List(ctx context.Context, category string, limit, offset int) ([]models.CatalogItem, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	var items []models.CatalogItem
+	for _, item := range r.s.catalog {
+		if category == "" || item.Category == category {
+			items = append(items, cloneCatalogItem(item))
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Category != items[j].Category {
+			return items[i].Category < items[j].Category
+		}
+		return items[i].DisplayOrder < items[j].DisplayOrder
+	})
+	return paginate(items, limit, offset), nil
+}
+
+func (r *memoryCatalog) Save(ctx context.Context, item *models.CatalogItem) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	r.s.catalog[item.ID] = cloneCatalogItem(*item)
+	return nil
+}
+
+func (r *memoryCatalog) Update(ctx context.Context, id string, updates map[string]interface{}) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	item, ok := r.s.catalog[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := mergeFields(&item, updates); err != nil {
+		return err
+	}
+	r.s.catalog[id] = item
+	return nil
+}
+
+func (r *memoryCatalog) Delete(ctx context.Context, id string) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	delete(r.s.catalog, id)
+	return nil
+}
+
+func cloneCatalogItem(item models.CatalogItem) models.CatalogItem {
+	item.Synonyms = append([]string(nil), item.Synonyms...)
+	return item
+}
+
+type memoryBadges struct {
+	s *MemoryStore
+}
+
+func (r *memoryBadges) Get(ctx context.Context, id string) (*models.BadgeDefinition, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	badge, ok := r.s.badges[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &badge, nil
+}
+
+func (r *memoryBadges) List(ctx context.Context) ([]models.BadgeDefinition, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	badges := make([]models.BadgeDefinition, 0, len(r.s.badges))
+	for _, badge := range r.s.badges {
+		badges = append(badges, badge)
+	}
+	sort.Slice(badges, func(i, j int) bool {
+		return badges[i].DisplayOrder < badges[j].DisplayOrder
+	})
+	return badges, nil
+}
+
+func (r *memoryBadges) Save(ctx context.Context, badge *models.BadgeDefinition) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	r.s.badges[badge.ID] = *badge
+	return nil
+}
+
+func (r *memoryBadges) Delete(ctx context.Context, id string) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	delete(r.s.badges, id)
+	return nil
+}
+
+type memoryStandings struct {
+	s *MemoryStore
+}
+
+func (r *memoryStandings) Get(ctx context.Context, board, userID string) (*models.Standing, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	standing, ok := r.s.standings[StandingID(board, userID)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	standing = cloneStanding(standing)
+	return &standing, nil
+}
+
+func (r *memoryStandings) ListByUser(ctx context.Context, userID string) ([]models.Standing, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	var standings []models.Standing
+	for _, standing := range r.s.standings {
+		if standing.UserID == userID {
+			standings = append(standings, cloneStanding(standing))
+		}
+	}
+	return standings, nil
+}
+
+// ranked returns a board's standings in rank order
+func (r *memoryStandings) ranked(board string) []models.Standing {
+	var standings []models.Standing
+	for _, standing := range r.s.standings {
+		if standing.Board == board {
+			standings = append(standings, cloneStanding(standing))
+		}
+	}
+	sort.Slice(standings, func(i, j int) bool {
+		return RanksBefore(&standings[i], &standings[j])
+	})
+	return standings
+}
+
+func (r *memoryStandings) Page(ctx context.Context, board string, after *models.Standing, limit int) ([]models.Standing, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	standings := r.ranked(board)
+	start := 0
+	if after != nil {
+		start = sort.Search(len(standings), func(i int) bool {
+			return RanksBefore(after, &standings[i])
+		})
+	}
+	return paginate(standings, limit, start), nil
+}
+
+func (r *memoryStandings) CountAhead(ctx context.Context, standing *models.Standing) (int, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	ahead := 0
+	for _, other := range r.s.standings {
+		if other.Board == standing.Board && RanksBefore(&other, standing) {
+			ahead++
+		}
+	}
+	return ahead, nil
+}
+
+func (r *memoryStandings) Count(ctx context.Context, board string) (int, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	count := 0
+	for _, standing := range r.s.standings {
+		if standing.Board == board {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryStandings) Save(ctx context.Context, standing *models.Standing) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	standing.ID = StandingID(standing.Board, standing.UserID)
+	r.s.standings[standing.ID] = cloneStanding(*standing)
+	return nil
+}
+
+func (r *memoryStandings) Delete(ctx context.Context, board, userID string) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	delete(r.s.standings, StandingID(board, userID))
+	return nil
+}
+
+func cloneStanding(standing models.Standing) models.Standing {
+	standing.Badges = append([]models.UserBadge(nil), standing.Badges...)
+	return standing
+}
+
+type memorySeasons struct {
+	s *MemoryStore
+}
+
+func (r *memorySeasons) Get(ctx context.Context, id string) (*models.Season, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	season, ok := r.s.seasons[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &season, nil
+}
+
+func (r *memorySeasons) List(ctx context.Context) ([]models.Season, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	seasons := make([]models.Season, 0, len(r.s.seasons))
+	for _, season := range r.s.seasons {
+		seasons = append(seasons, season)
+	}
+	sort.Slice(seasons, func(i, j int) bool {
+		return seasons[i].StartsAt.Before(seasons[j].StartsAt)
+	})
+	return seasons, nil
+}
+
+func (r *memorySeasons) Save(ctx context.Context, season *models.Season) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	r.s.seasons[season.ID] = *season
+	return nil
+}
+
+func (r *memorySeasons) Delete(ctx context.Context, id string) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	delete(r.s.seasons, id)
+	return nil
+}
+
+type memorySessions struct {
+	s *MemoryStore
+}
+
+func (r *memorySessions) Get(ctx context.Context, id string) (*models.Session, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	session, ok := r.s.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	session = cloneSession(session)
+	return &session, nil
+}
+
+func (r *memorySessions) ListByUser(ctx context.Context, userID string) ([]models.Session, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	var sessions []models.Session
+	for _, session := range r.s.sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, cloneSession(session))
+		}
+	}
+	return sessions, nil
+}
+
+func (r *memorySessions) Save(ctx context.Context, session *models.Session) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	r.s.sessions[session.ID] = cloneSession(*session)
+	return nil
+}
+
+func cloneSession(session models.Session) models.Session {
+	session.AccessTokens = append([]models.IssuedToken(nil), session.AccessTokens...)
+	if session.RevokedAt != nil {
+		revokedAt := *session.RevokedAt
+		session.RevokedAt = &revokedAt
+	}
+	return session
+}
+
+type memoryRevokedTokens struct {
+	s *MemoryStore
+}
+
+func (r *memoryRevokedTokens) Add(ctx context.Context, token models.IssuedToken) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	// Forget tokens that have expired on their own
+	now := time.Now()
+	for jti, revoked := range r.s.revoked {
+		if now.After(revoked.ExpiresAt) {
+			delete(r.s.revoked, jti)
+		}
+	}
+
+	r.s.revoked[token.JTI] = token
+	return nil
+}
+
+func (r *memoryRevokedTokens) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	_, ok := r.s.revoked[jti]
+	return ok, nil
+}
+
+type memorySigningKeys struct {
+	s *MemoryStore
+}
+
+func (r *memorySigningKeys) List(ctx context.Context) ([]models.SigningKey, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	keys := make([]models.SigningKey, 0, len(r.s.signingKeys))
+	for _, key := range r.s.signingKeys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].NotBefore.Before(keys[j].NotBefore)
+	})
+	return keys, nil
+}
+
+func (r *memorySigningKeys) Save(ctx context.Context, key *models.SigningKey) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	r.s.signingKeys[key.ID] = *key
+	return nil
+}
+
+func (r *memorySigningKeys) Delete(ctx context.Context, id string) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	delete(r.s.signingKeys, id)
+	return nil
+}
+
+type memoryIdentities struct {
+	s *MemoryStore
+}
+
+func (r *memoryIdentities) Get(ctx context.Context, id string) (*models.Identity, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	identity, ok := r.s.identities[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &identity, nil
+}
+
+func (r *memoryIdentities) ListByUser(ctx context.Context, userID string) ([]models.Identity, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	var identities []models.Identity
+	for _, identity := range r.s.identities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+	return identities, nil
+}
+
+func (r *memoryIdentities) Save(ctx context.Context, identity *models.Identity) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	r.s.identities[identity.ID] = *identity
+	return nil
+}
+
+func (r *memoryIdentities) Delete(ctx context.Context, id string) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	delete(r.s.identities, id)
+	return nil
+}
+
+type memoryLoginLinks struct {
+	s *MemoryStore
+}
+
+func (r *memoryLoginLinks) Get(ctx context.Context, id string) (*models.LoginLink, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	link, ok := r.s.loginLinks[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &link, nil
+}
+
+func (r *memoryLoginLinks) Save(ctx context.Context, link *models.LoginLink) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	r.s.loginLinks[link.ID] = *link
+	return nil
+}
+
+func (r *memoryLoginLinks) Delete(ctx context.Context, id string) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	delete(r.s.loginLinks, id)
+	return nil
+}
+
+type memoryAudit struct {
+	s *MemoryStore
+}
+
+func (r *memoryAudit) Append(ctx context.Context, entry *models.AuditEntry) error {
+	r.s.lock()
+	defer r.s.unlock()
+
+	r.s.audit = append(r.s.audit, cloneAuditEntry(*entry))
+	return nil
+}
+
+func (r *memoryAudit) Query(ctx context.Context, filter AuditFilter) ([]models.AuditEntry, error) {
+	r.s.rlock()
+	defer r.s.runlock()
+
+	entries := []models.AuditEntry{}
+	found := filter.After == ""
+	// Walk newest first, the order results are returned in
+	for i := len(r.s.audit) - 1; i >= 0; i-- {
+		entry := r.s.audit[i]
+		if !found {
+			found = entry.ID == filter.After
+			continue
+		}
+		if !matchesAudit(&entry, &filter) {
+			continue
+		}
+		entries = append(entries, cloneAuditEntry(entry))
+		if filter.Limit > 0 && len(entries) == filter.Limit {
+			break
+		}
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	return entries, nil
+}
+
+func matchesAudit(entry *models.AuditEntry, filter *AuditFilter) bool {
+	switch {
+	case filter.Actor != "" && entry.Actor != filter.Actor,
+		filter.Action != "" && entry.Action != filter.Action,
+		filter.TargetType != "" && entry.TargetType != filter.TargetType,
+		filter.TargetID != "" && entry.TargetID != filter.TargetID,
+		!filter.From.IsZero() && entry.CreatedAt.Before(filter.From),
+		!filter.To.IsZero() && !entry.CreatedAt.Before(filter.To):
+		return false
+	}
+	return true
+}
+
+func cloneAuditEntry(entry models.AuditEntry) models.AuditEntry {
+	entry.Changes = copyMap(entry.Changes)
+	return entry
+}
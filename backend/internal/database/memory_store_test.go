@@ -0,0 +1,225 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"brew-detective-backend/internal/models"
+)
+
+func TestMemoryStoreReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	user := &models.User{ID: "u1", Roles: []string{"support"}}
+	if err := store.Users().Save(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	user.Roles[0] = "admin"
+
+	saved, err := store.Users().Get(ctx, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved.Roles = append(saved.Roles, "admin")
+
+	again, err := store.Users().Get(ctx, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Roles) != 1 || again.Roles[0] != "support" {
+		t.Errorf("roles = %v, want [support]", again.Roles)
+	}
+
+	if _, err := store.Users().Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a missing user = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryStoreTransactionRollsBack(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	if err := store.Orders().Save(ctx, &models.Order{ID: "o1", OrderID: "ABC123", Status: models.OrderStatusPending}); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := errors.New("failed")
+	err := store.RunTransaction(ctx, func(ctx context.Context, tx Store) error {
+		if err := tx.Orders().Save(ctx, &models.Order{ID: "o1", OrderID: "ABC123", Status: models.OrderStatusConfirmed}); err != nil {
+			return err
+		}
+		if err := tx.Users().Save(ctx, &models.User{ID: "u1"}); err != nil {
+			return err
+		}
+		if err := tx.Audit().Append(ctx, &models.AuditEntry{ID: "a1"}); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("RunTransaction = %v, want %v", err, failed)
+	}
+
+	order, err := store.Orders().Get(ctx, "o1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != models.OrderStatusPending {
+		t.Errorf("order status = %s, want %s", order.Status, models.OrderStatusPending)
+	}
+	if _, err := store.Users().Get(ctx, "u1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("user written in a failed transaction: %v", err)
+	}
+	if entries, _ := store.Audit().Query(ctx, AuditFilter{}); len(entries) != 0 {
+		t.Errorf("%d audit entries written in a failed transaction", len(entries))
+	}
+}
+
+func TestMemoryStoreTransactionCommits(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	err := store.RunTransaction(ctx, func(ctx context.Context, tx Store) error {
+		// Nested transactions join the outer one instead of deadlocking
+		return tx.RunTransaction(ctx, func(ctx context.Context, tx Store) error {
+			return tx.Users().Save(ctx, &models.User{ID: "u1"})
+		})
+	})
+	if err != nil {
+		t.Fatalf("RunTransaction: %v", err)
+	}
+	if _, err := store.Users().Get(ctx, "u1"); err != nil {
+		t.Errorf("user not committed: %v", err)
+	}
+}
+
+func TestMemoryStoreUpdateMergesFields(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	if err := store.Cases().Save(ctx, &models.CoffeeCase{ID: "case1", Name: "Caso", Price: 10, IsActive: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Cases().Update(ctx, "case1", map[string]interface{}{"is_active": false, "price": 12}); err != nil {
+		t.Fatal(err)
+	}
+	coffeeCase, err := store.Cases().Get(ctx, "case1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if coffeeCase.IsActive || coffeeCase.Price != 12 || coffeeCase.Name != "Caso" {
+		t.Errorf("case = %+v, want inactive, priced 12 and named Caso", coffeeCase)
+	}
+
+	if err := store.Cases().Update(ctx, "missing", map[string]interface{}{"name": "x"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update of a missing case = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryStoreStandingsPageInRankOrder(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	reached := time.Now()
+
+	standings := []models.Standing{
+		{Board: "global", UserID: "a", Points: 100, Accuracy: 0.5, ReachedAt: reached},
+		{Board: "global", UserID: "b", Points: 300, ReachedAt: reached},
+		{Board: "global", UserID: "c", Points: 100, Accuracy: 0.9, ReachedAt: reached},
+		{Board: "global", UserID: "d", Points: 100, Accuracy: 0.5, ReachedAt: reached.Add(-time.Minute)},
+		{Board: "other", UserID: "e", Points: 500, ReachedAt: reached},
+	}
+	for i := range standings {
+		if err := store.Standings().Save(ctx, &standings[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ranked []string
+	var after *models.Standing
+	for {
+		page, err := store.Standings().Page(ctx, "global", after, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		for _, standing := range page {
+			ranked = append(ranked, standing.UserID)
+		}
+		after = &page[len(page)-1]
+	}
+	if got, want := fmt.Sprint(ranked), "[b c d a]"; got != want {
+		t.Errorf("ranking = %s, want %s", got, want)
+	}
+
+	ahead, err := store.Standings().CountAhead(ctx, &standings[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ahead != 3 {
+		t.Errorf("%d standings ahead of a, want 3", ahead)
+	}
+	if count, _ := store.Standings().Count(ctx, "global"); count != 4 {
+		t.Errorf("global board has %d standings, want 4", count)
+	}
+}
+
+func TestMemoryStoreAuditQuery(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	start := time.Now()
+
+	for i := 0; i < 5; i++ {
+		entry := &models.AuditEntry{
+			ID:         fmt.Sprintf("a%d", i),
+			Actor:      "admin",
+			Action:     "case.update",
+			TargetType: "case",
+			TargetID:   fmt.Sprintf("case%d", i%2),
+			CreatedAt:  start.Add(time.Duration(i) * time.Minute),
+		}
+		if err := store.Audit().Append(ctx, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids := func(entries []models.AuditEntry) string {
+		var ids []string
+		for _, entry := range entries {
+			ids = append(ids, entry.ID)
+		}
+		return fmt.Sprint(ids)
+	}
+
+	tests := []struct {
+		name   string
+		filter AuditFilter
+		want   string
+	}{
+		{"newest first", AuditFilter{}, "[a4 a3 a2 a1 a0]"},
+		{"by target", AuditFilter{TargetID: "case1"}, "[a3 a1]"},
+		{"time range", AuditFilter{From: start.Add(time.Minute), To: start.Add(3 * time.Minute)}, "[a2 a1]"},
+		{"first page", AuditFilter{Limit: 2}, "[a4 a3]"},
+		{"next page", AuditFilter{Limit: 2, After: "a3"}, "[a2 a1]"},
+		{"no match", AuditFilter{Actor: "someone"}, "[]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := store.Audit().Query(ctx, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(entries); got != tt.want {
+				t.Errorf("entries = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := store.Audit().Query(ctx, AuditFilter{After: "unknown"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Query after an unknown entry = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,290 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"brew-detective-backend/internal/models"
+)
+
+// ErrNotFound is returned by repositories when the requested document does not exist
+var ErrNotFound = errors.New("document not found")
+
+// Storage backends selectable through the STORAGE_BACKEND environment variable
+const (
+	BackendFirestore = "firestore"
+	BackendMemory    = "memory"
+)
+
+// DB is the storage backend used by the handlers
+var DB Store
+
+// Store groups the repositories for every collection
+type Store interface {
+	Users() UserRepository
+	Cases() CaseRepository
+	Submissions() SubmissionRepository
+	Drafts() DraftRepository
+	Teams() TeamRepository
+	Orders() OrderRepository
+	Catalog() CatalogRepository
+	Badges() BadgeRepository
+	Standings() StandingRepository
+	Seasons() SeasonRepository
+	Sessions() SessionRepository
+	RevokedTokens() RevokedTokenRepository
+	SigningKeys() SigningKeyRepository
+	Identities() IdentityRepository
+	LoginLinks() LoginLinkRepository
+	Audit() AuditRepository
+	// RunTransaction runs fn atomically: either every write made through tx
+	// is applied or none is. Reads must happen before the first write.
+	RunTransaction(ctx context.Context, fn func(ctx context.Context, tx Store) error) error
+	Close() error
+}
+
+// UserRepository persists users
+type UserRepository interface {
+	Get(ctx context.Context, id string) (*models.User, error)
+	// GetByVerifiedEmail returns the first user whose email is the given one
+	// and has been verified
+	GetByVerifiedEmail(ctx context.Context, email string) (*models.User, error)
+	// List returns every user ordered by name
+	List(ctx context.Context) ([]models.User, error)
+	// ListHiddenFromLeaderboards returns the users whose privacy settings hide
+	// them from leaderboards
+	ListHiddenFromLeaderboards(ctx context.Context) ([]models.User, error)
+	Save(ctx context.Context, user *models.User) error
+}
+
+// CaseRepository persists coffee cases
+type CaseRepository interface {
+	Get(ctx context.Context, id string) (*models.CoffeeCase, error)
+	// GetActive returns the case flagged as active. The scheduler keeps at most one.
+	GetActive(ctx context.Context) (*models.CoffeeCase, error)
+	ListActive(ctx context.Context) ([]models.CoffeeCase, error)
+	// List returns cases ordered by creation date, newest first
+	List(ctx context.Context, limit, offset int) ([]models.CoffeeCase, error)
+	// ListCreatedBetween returns the cases created in [from, to)
+	ListCreatedBetween(ctx context.Context, from, to time.Time) ([]models.CoffeeCase, error)
+	// ListOpeningBy returns the inactive cases whose opens_at is at or before at,
+	// earliest first
+	ListOpeningBy(ctx context.Context, at time.Time) ([]models.CoffeeCase, error)
+	// ListClosedBy returns the cases whose closed_at is at or before at
+	ListClosedBy(ctx context.Context, at time.Time) ([]models.CoffeeCase, error)
+	Save(ctx context.Context, coffeeCase *models.CoffeeCase) error
+	// Update applies a partial update keyed by document field names
+	Update(ctx context.Context, id string, updates map[string]interface{}) error
+	Delete(ctx context.Context, id string) error
+}
+
+// SubmissionRepository persists case submissions
+type SubmissionRepository interface {
+	Get(ctx context.Context, id string) (*models.Submission, error)
+	Save(ctx context.Context, submission *models.Submission) error
+	// ListByUser returns a user's submissions, most recent first. A limit of 0 returns them all.
+	ListByUser(ctx context.Context, userID string, limit, offset int) ([]models.Submission, error)
+	ListByCase(ctx context.Context, caseID string) ([]models.Submission, error)
+	ListByTeam(ctx context.Context, teamID string) ([]models.Submission, error)
+}
+
+// DraftRepository persists submission drafts, keyed by order code
+type DraftRepository interface {
+	Get(ctx context.Context, id string) (*models.Draft, error)
+	// ListByUser returns a user's drafts, most recently updated first
+	ListByUser(ctx context.Context, userID string) ([]models.Draft, error)
+	Save(ctx context.Context, draft *models.Draft) error
+}
+
+// TeamRepository persists the teams sharing an order
+type TeamRepository interface {
+	Get(ctx context.Context, id string) (*models.Team, error)
+	GetByInviteCode(ctx context.Context, code string) (*models.Team, error)
+	// List returns every team
+	List(ctx context.Context) ([]models.Team, error)
+	// ListByMember returns the teams a user belongs to, newest first
+	ListByMember(ctx context.Context, userID string) ([]models.Team, error)
+	ListByCase(ctx context.Context, caseID string) ([]models.Team, error)
+	Save(ctx context.Context, team *models.Team) error
+}
+
+// OrderRepository persists orders
+type OrderRepository interface {
+	Get(ctx context.Context, id string) (*models.Order, error)
+	// GetByOrderID looks an order up by its 6-character customer code
+	GetByOrderID(ctx context.Context, orderID string) (*models.Order, error)
+	// List returns orders ordered by creation date, newest first
+	List(ctx context.Context, limit, offset int) ([]models.Order, error)
+	Save(ctx context.Context, order *models.Order) error
+}
+
+// CatalogRepository persists catalog items
+type CatalogRepository interface {
+	Get(ctx context.Context, id string) (*models.CatalogItem, error)
+	// ListActive returns active items, optionally restricted to one category
+	ListActive(ctx context.Context, category string) ([]models.CatalogItem, error)
+	// List returns items ordered by category and display order
+	List(ctx context.Context, category string, limit, offset int) ([]models.CatalogItem, error)
+	Save(ctx context.Context, item *models.CatalogItem) error
+	// Update applies a partial update keyed by document field names
+	Update(ctx context.Context, id string, updates map[string]interface{}) error
+	Delete(ctx context.Context, id string) error
+}
+
+// BadgeRepository persists badge definitions
+type BadgeRepository interface {
+	Get(ctx context.Context, id string) (*models.BadgeDefinition, error)
+	// List returns every badge definition ordered by display order
+	List(ctx context.Context) ([]models.BadgeDefinition, error)
+	Save(ctx context.Context, badge *models.BadgeDefinition) error
+	Delete(ctx context.Context, id string) error
+}
+
+// StandingRepository persists precomputed leaderboard standings
+type StandingRepository interface {
+	Get(ctx context.Context, board, userID string) (*models.Standing, error)
+	// ListByUser returns a user's standings on every board
+	ListByUser(ctx context.Context, userID string) ([]models.Standing, error)
+	// Page returns up to limit standings of a board in rank order, starting
+	// after the given standing, or from the top when after is nil
+	Page(ctx context.Context, board string, after *models.Standing, limit int) ([]models.Standing, error)
+	// CountAhead returns how many standings of its board rank before the given one
+	CountAhead(ctx context.Context, standing *models.Standing) (int, error)
+	Count(ctx context.Context, board string) (int, error)
+	Save(ctx context.Context, standing *models.Standing) error
+	Delete(ctx context.Context, board, userID string) error
+}
+
+// SeasonRepository persists seasons
+type SeasonRepository interface {
+	Get(ctx context.Context, id string) (*models.Season, error)
+	// List returns every season ordered by start date
+	List(ctx context.Context) ([]models.Season, error)
+	Save(ctx context.Context, season *models.Season) error
+	Delete(ctx context.Context, id string) error
+}
+
+// SessionRepository persists sign-in sessions
+type SessionRepository interface {
+	Get(ctx context.Context, id string) (*models.Session, error)
+	// ListByUser returns a user's sessions, including revoked and expired ones
+	ListByUser(ctx context.Context, userID string) ([]models.Session, error)
+	Save(ctx context.Context, session *models.Session) error
+}
+
+// RevokedTokenRepository is the revocation list of access tokens, keyed by JWT ID
+type RevokedTokenRepository interface {
+	// Add revokes a token until it expires on its own
+	Add(ctx context.Context, token models.IssuedToken) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// SigningKeyRepository persists the keys access tokens are signed with
+type SigningKeyRepository interface {
+	// List returns every key ordered by NotBefore
+	List(ctx context.Context) ([]models.SigningKey, error)
+	Save(ctx context.Context, key *models.SigningKey) error
+	Delete(ctx context.Context, id string) error
+}
+
+// IdentityRepository persists the provider accounts linked to users
+type IdentityRepository interface {
+	Get(ctx context.Context, id string) (*models.Identity, error)
+	ListByUser(ctx context.Context, userID string) ([]models.Identity, error)
+	Save(ctx context.Context, identity *models.Identity) error
+	Delete(ctx context.Context, id string) error
+}
+
+// LoginLinkRepository persists pending email logins
+type LoginLinkRepository interface {
+	Get(ctx context.Context, id string) (*models.LoginLink, error)
+	Save(ctx context.Context, link *models.LoginLink) error
+	Delete(ctx context.Context, id string) error
+}
+
+// AuditRepository is the append-only audit log. Entries cannot be changed or deleted.
+type AuditRepository interface {
+	Append(ctx context.Context, entry *models.AuditEntry) error
+	// Query returns the entries matching the filter, newest first
+	Query(ctx context.Context, filter AuditFilter) ([]models.AuditEntry, error)
+}
+
+// LegacyBadgeStore is implemented by backends that may hold users saved before
+// badges were structured, whose badges are plain strings such as "🔍 Primer Caso"
+type LegacyBadgeStore interface {
+	// MigrateLegacyBadges replaces every string badge of every user with the
+	// badge convert returns for it, and returns how many users were rewritten
+	MigrateLegacyBadges(ctx context.Context, convert func(legacy string) models.UserBadge) (int, error)
+}
+
+// AuditFilter selects audit entries. Empty fields match every entry.
+type AuditFilter struct {
+	Actor      string
+	Action     string
+	TargetType string
+	TargetID   string
+	From       time.Time // Inclusive
+	To         time.Time // Exclusive
+	After      string    // ID of the last entry of the previous page
+	Limit      int
+}
+
+// StandingID is the document ID of a user's standing on a board
+func StandingID(board, userID string) string {
+	return board + "_" + userID
+}
+
+// RanksBefore reports whether standing a ranks ahead of b: more points first,
+// then higher accuracy, then whoever reached it first. The user ID keeps the
+// order total.
+func RanksBefore(a, b *models.Standing) bool {
+	if a.Points != b.Points {
+		return a.Points > b.Points
+	}
+	if a.Accuracy != b.Accuracy {
+		return a.Accuracy > b.Accuracy
+	}
+	if !a.ReachedAt.Equal(b.ReachedAt) {
+		return a.ReachedAt.Before(b.ReachedAt)
+	}
+	return a.UserID < b.UserID
+}
+
+// Init selects and initializes the storage backend from STORAGE_BACKEND (defaults to firestore)
+func Init() error {
+	backend := os.Getenv("STORAGE_BACKEND")
+	if backend == "" {
+		backend = BackendFirestore
+	}
+
+	switch backend {
+	case BackendFirestore:
+		if err := InitFirestore(); err != nil {
+			return err
+		}
+		DB = NewFirestoreStore(FirestoreClient)
+	case BackendMemory:
+		DB = NewMemoryStore()
+		log.Println("Using in-memory storage backend, data will not be persisted")
+	default:
+		return fmt.Errorf("unknown STORAGE_BACKEND %q", backend)
+	}
+
+	return nil
+}
+
+// Close releases the storage backend
+func Close() {
+	if DB == nil {
+		return
+	}
+	if err := DB.Close(); err != nil {
+		log.Printf("Failed to close storage backend: %v", err)
+		return
+	}
+	log.Println("Storage backend closed")
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"brew-detective-backend/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// auditExportPageSize is how many entries the export reads at a time
+const auditExportPageSize = 500
+
+// auditFilter reads the audit log filters from the query string
+func auditFilter(c *gin.Context) (database.AuditFilter, error) {
+	filter := database.AuditFilter{
+		Actor:      c.Query("actor"),
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+		After:      c.Query("cursor"),
+	}
+
+	var err error
+	if from := c.Query("from"); from != "" {
+		if filter.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return filter, errors.New("from must be an RFC 3339 timestamp")
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		if filter.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return filter, errors.New("to must be an RFC 3339 timestamp")
+		}
+	}
+	return filter, nil
+}
+
+// GetAuditLog returns audit entries matching the filters, newest first. Pass
+// next_cursor as cursor to get the following page.
+func GetAuditLog(c *gin.Context) {
+	filter, err := auditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filter", "details": err.Error()})
+		return
+	}
+
+	filter.Limit = 50 // Default limit
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= 200 {
+		filter.Limit = l
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	entries, err := database.DB.Audit().Query(ctx, filter)
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching audit log: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log", "details": err.Error()})
+		return
+	}
+
+	nextCursor := ""
+	if len(entries) == filter.Limit {
+		nextCursor = entries[len(entries)-1].ID
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries":     entries,
+		"count":       len(entries),
+		"next_cursor": nextCursor,
+	})
+}
+
+// ExportAuditLog streams every audit entry matching the filters as JSON Lines,
+// newest first
+func ExportAuditLog(c *gin.Context) {
+	filter, err := auditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filter", "details": err.Error()})
+		return
+	}
+	filter.Limit = auditExportPageSize
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	// Read the first page before answering so that errors get a proper status
+	entries, err := database.DB.Audit().Query(ctx, filter)
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export audit log", "details": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", `attachment; filename="audit-log.jsonl"`)
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	for {
+		for i := range entries {
+			if err := encoder.Encode(&entries[i]); err != nil {
+				log.Printf("Failed to write audit export: %v", err)
+				return
+			}
+		}
+		c.Writer.Flush()
+
+		if len(entries) < filter.Limit {
+			return
+		}
+		filter.After = entries[len(entries)-1].ID
+		if entries, err = database.DB.Audit().Query(ctx, filter); err != nil {
+			// The status was already sent, so the export just ends early
+			log.Printf("Failed to export audit log: %v", err)
+			return
+		}
+	}
+}
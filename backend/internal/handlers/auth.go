@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -8,33 +10,60 @@ import (
 
 	"brew-detective-backend/internal/auth"
 	"brew-detective-backend/internal/database"
-	"brew-detective-backend/internal/models"
+	"brew-detective-backend/internal/roles"
 
 	"github.com/gin-gonic/gin"
 )
 
-func GoogleLogin(c *gin.Context) {
-	// Generate a cryptographically secure random state
-	state := auth.GenerateOAuthState(c)
-	url := auth.GetGoogleOauthConfig().AuthCodeURL(state)
+// GetAuthProviders lists the ways users can log in
+func GetAuthProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"providers": auth.ProviderNames(),
+		"email":     auth.EmailLoginEnabled(),
+	})
+}
+
+// ProviderLogin starts a login with an identity provider. With ?redirect=true
+// the browser is sent straight to the provider, otherwise the login URL is
+// returned as JSON. A ?link token from LinkIdentity links the account instead.
+func ProviderLogin(c *gin.Context) {
+	provider, err := auth.GetProvider(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+		return
+	}
+
+	url, err := auth.BeginLogin(c, provider, c.Query("link"))
+	if errors.Is(err, auth.ErrUnknownState) || errors.Is(err, auth.ErrStateMismatch) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid link token", "details": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+
+	if c.Query("redirect") == "true" {
+		c.Redirect(http.StatusTemporaryRedirect, url)
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"auth_url": url})
 }
 
-func GoogleCallback(c *gin.Context) {
+// ProviderCallback finishes a login: the provider redirects here with the
+// authorization code
+func ProviderCallback(c *gin.Context) {
+	provider, err := auth.GetProvider(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+		return
+	}
+
 	queryState := c.Query("state")
-	
-	// For now, use a simple validation approach
-	// In a production app with sessions, you'd validate against stored state
 	if queryState == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "OAuth state parameter missing"})
 		return
 	}
-	
-	// Basic state validation - ensure it's a reasonable hex string
-	if len(queryState) < 16 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid OAuth state format"})
-		return
-	}
 
 	code := c.Query("code")
 	if code == "" {
@@ -42,89 +71,128 @@ func GoogleCallback(c *gin.Context) {
 		return
 	}
 
-	googleUser, err := auth.GetUserDataFromGoogle(code)
+	// The state must have been issued to this browser and not used before
+	identity, linkUserID, err := auth.CompleteLogin(c, provider, queryState, code)
+	if errors.Is(err, auth.ErrUnknownState) || errors.Is(err, auth.ErrStateMismatch) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid OAuth state", "details": err.Error()})
+		return
+	}
 	if err != nil {
-		fmt.Printf("Error getting user data from Google: %v\n", err)
+		fmt.Printf("Error getting user data from %s: %v\n", provider.Name(), err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user data", "details": err.Error()})
 		return
 	}
 
-	// Check if user exists in database
-	var user *models.User
-	userRef := database.FirestoreClient.Collection(database.UsersCollection).Doc(googleUser.ID)
-	doc, err := userRef.Get(c.Request.Context())
-
-	if err != nil || !doc.Exists() {
-		// Create new user
-		user = &models.User{
-			ID:             googleUser.ID,
-			Email:          googleUser.Email,
-			Name:           googleUser.Name,
-			Picture:        googleUser.Picture,
-			Type:           "regular", // Default to regular user
-			CreatedAt:      time.Now(),
-			UpdatedAt:      time.Now(),
-			Score:          0,
-			Badges:         []string{},
-			CasesAttempted: 0,
-			CasesSolved:    0,
-		}
+	finishLogin(c, identity, linkUserID)
+}
 
-		_, err = userRef.Set(c.Request.Context(), user)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user", "details": err.Error()})
-			return
-		}
-	} else {
-		// Update existing user
-		if err := doc.DataTo(&user); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse user data"})
-			return
-		}
+// RequestEmailLogin emails a login link. The response does not reveal whether
+// the address belongs to a user.
+func RequestEmailLogin(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email is required"})
+		return
+	}
 
-		// Debug: Log user type before update
-		fmt.Printf("DEBUG: User type before update: '%s'\n", user.Type)
-
-		// Update user info from Google (preserve Type and Name fields)
-		userType := user.Type // Preserve the type field
-		userName := user.Name // Preserve custom name if set
-		user.Email = googleUser.Email
-		// Only update name from Google if user hasn't set a custom name
-		if userName == "" || userName == googleUser.Name {
-			user.Name = googleUser.Name
-		} else {
-			user.Name = userName // Keep custom name
-		}
-		user.Picture = googleUser.Picture
-		user.UpdatedAt = time.Now()
-		
-		// Ensure type is preserved (set default if empty)
-		if userType != "" {
-			user.Type = userType
-		} else if user.Type == "" {
-			user.Type = "regular"
-		}
+	sendLoginLink(c, req.Email, "")
+}
 
-		_, err = userRef.Set(c.Request.Context(), user)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
-			return
-		}
+// sendLoginLink emails a login link that comes back to VerifyEmailLogin
+func sendLoginLink(c *gin.Context, email, linkUserID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := auth.SendLoginLink(ctx, email, linkUserID, emailVerifyURL(c))
+	if errors.Is(err, auth.ErrEmailLoginDisabled) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email login is not enabled"})
+		return
+	}
+	if errors.Is(err, auth.ErrInvalidEmail) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email address"})
+		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send login link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Login link sent"})
+}
 
-	// Generate JWT token
-	token, err := auth.GenerateJWT(user.ID, user.Email, user.Name)
+// emailVerifyURL is the address of VerifyEmailLogin, from EMAIL_LINK_URL or
+// the host the request was sent to
+func emailVerifyURL(c *gin.Context) string {
+	if url := os.Getenv("EMAIL_LINK_URL"); url != "" {
+		return url
+	}
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/auth/email/verify", scheme, c.Request.Host)
+}
+
+// VerifyEmailLogin redeems an emailed login link
+func VerifyEmailLogin(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	identity, linkUserID, err := auth.ConsumeLoginLink(ctx, c.Query("token"))
+	if errors.Is(err, auth.ErrInvalidLoginLink) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired login link"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify login link"})
 		return
 	}
 
-	// Redirect back to frontend with token in URL fragment
+	finishLogin(c, identity, linkUserID)
+}
+
+// finishLogin logs in as the user an identity belongs to, or links the
+// identity to linkUserID, and redirects back to the frontend
+func finishLogin(c *gin.Context, identity *auth.ProviderIdentity, linkUserID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Redirect back to frontend with the result in the URL fragment
 	frontendURL := os.Getenv("FRONTEND_URL")
 	if frontendURL == "" {
 		frontendURL = "http://localhost:8080" // Default for local development
 	}
-	redirectURL := fmt.Sprintf("%s/#token=%s", frontendURL, token)
+
+	if linkUserID != "" {
+		_, err := auth.LinkIdentity(ctx, linkUserID, identity)
+		if errors.Is(err, auth.ErrIdentityInUse) {
+			c.JSON(http.StatusConflict, gin.H{"error": "This account is already linked to another user"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link account"})
+			return
+		}
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("%s/#linked=%s", frontendURL, identity.Provider))
+		return
+	}
+
+	user, err := auth.ResolveUser(ctx, identity)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user", "details": err.Error()})
+		return
+	}
+
+	// Start a session for this browser
+	tokens, err := auth.IssueSession(ctx, user, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	redirectURL := fmt.Sprintf("%s/#token=%s&refresh_token=%s", frontendURL, tokens.AccessToken, tokens.RefreshToken)
 	c.Redirect(http.StatusTemporaryRedirect, redirectURL)
 }
 
@@ -135,25 +203,35 @@ func GetProfile(c *gin.Context) {
 		return
 	}
 
-	userRef := database.FirestoreClient.Collection(database.UsersCollection).Doc(userID.(string))
-	doc, err := userRef.Get(c.Request.Context())
-
-	if err != nil || !doc.Exists() {
+	user, err := database.DB.Users().Get(c.Request.Context(), userID.(string))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
-	var user models.User
-	if err := doc.DataTo(&user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse user data"})
-		return
-	}
-
+	// Include the admin role of users whose legacy type is admin
+	user.Roles = roles.Of(user)
 	c.JSON(http.StatusOK, user)
 }
 
-func Logout(c *gin.Context) {
-	// For JWT tokens, logout is handled client-side by removing the token
-	// We could implement a token blacklist here if needed
-	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+// GetJWKS publishes the public keys that verify access tokens
+func GetJWKS(c *gin.Context) {
+	// Verifiers may cache the keys; new keys are announced well before they sign
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": auth.JWKS()})
+}
+
+// RotateSigningKeys retires the current signing key and starts signing with a
+// new one, e.g. after a suspected leak (admin only). Tokens signed with the old
+// key stay valid until they expire.
+func RotateSigningKeys(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := auth.RotateKeys(ctx, true); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate signing keys", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Signing keys rotated successfully", "keys": auth.JWKS()})
 }
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"brew-detective-backend/internal/audit"
+	"brew-detective-backend/internal/badges"
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetBadges returns the active badge definitions
+func GetBadges(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	definitions, err := database.DB.Badges().List(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch badges", "details": err.Error()})
+		return
+	}
+
+	active := []models.BadgeDefinition{}
+	for _, definition := range definitions {
+		if definition.IsActive {
+			active = append(active, definition)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"badges": active})
+}
+
+// GetAllBadges returns every badge definition and the metrics rules can use (admin only)
+func GetAllBadges(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	definitions, err := database.DB.Badges().List(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch badges", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"badges":  definitions,
+		"metrics": badges.MetricNames(),
+	})
+}
+
+// errBadgeExists is returned from a creation's transaction when the ID is taken
+var errBadgeExists = errors.New("badge already exists")
+
+// CreateBadge creates a badge definition (admin only)
+func CreateBadge(c *gin.Context) {
+	var badge models.BadgeDefinition
+	if err := c.ShouldBindJSON(&badge); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid badge data"})
+		return
+	}
+
+	badge.ID = strings.TrimSpace(badge.ID)
+	if badge.ID == "" || badge.Name == "" || badge.Rule == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID, name and rule are required"})
+		return
+	}
+	if _, err := badges.ParseRule(badge.Rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid badge rule", "details": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	badge.CreatedAt = time.Now()
+	badge.UpdatedAt = badge.CreatedAt
+
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		if _, err := tx.Badges().Get(ctx, badge.ID); err == nil {
+			return errBadgeExists
+		} else if !errors.Is(err, database.ErrNotFound) {
+			return err
+		}
+		if err := tx.Badges().Save(ctx, &badge); err != nil {
+			return err
+		}
+		return audit.Write(ctx, tx, c, "badge.create", audit.Badges, badge.ID, nil, &badge)
+	})
+	if errors.Is(err, errBadgeExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A badge with this ID already exists"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create badge"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, badge)
+}
+
+// badgeUpdate holds the badge fields an admin can change
+type badgeUpdate struct {
+	Name         *string `json:"name"`
+	Description  *string `json:"description"`
+	Icon         *string `json:"icon"`
+	Rule         *string `json:"rule"`
+	IsActive     *bool   `json:"is_active"`
+	DisplayOrder *int    `json:"display_order"`
+}
+
+// UpdateBadge updates a badge definition (admin only). Users who already hold
+// the badge keep it even if the new rule no longer matches.
+func UpdateBadge(c *gin.Context) {
+	badgeID := c.Param("id")
+
+	var updates badgeUpdate
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid update data"})
+		return
+	}
+
+	if updates.Rule != nil {
+		if _, err := badges.ParseRule(*updates.Rule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid badge rule", "details": err.Error()})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var badge *models.BadgeDefinition
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		before, err := tx.Badges().Get(ctx, badgeID)
+		if err != nil {
+			return err
+		}
+
+		updated := *before
+		if updates.Name != nil {
+			updated.Name = *updates.Name
+		}
+		if updates.Description != nil {
+			updated.Description = *updates.Description
+		}
+		if updates.Icon != nil {
+			updated.Icon = *updates.Icon
+		}
+		if updates.Rule != nil {
+			updated.Rule = *updates.Rule
+		}
+		if updates.IsActive != nil {
+			updated.IsActive = *updates.IsActive
+		}
+		if updates.DisplayOrder != nil {
+			updated.DisplayOrder = *updates.DisplayOrder
+		}
+		updated.UpdatedAt = time.Now()
+
+		if err := tx.Badges().Save(ctx, &updated); err != nil {
+			return err
+		}
+		badge = &updated
+		return audit.Write(ctx, tx, c, "badge.update", audit.Badges, badgeID, before, badge)
+	})
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Badge not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update badge"})
+		return
+	}
+
+	c.JSON(http.StatusOK, badge)
+}
+
+// DeleteBadge deletes a badge definition (admin only). Badges already awarded stay on user profiles.
+func DeleteBadge(c *gin.Context) {
+	badgeID := c.Param("id")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		before, err := tx.Badges().Get(ctx, badgeID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Badges().Delete(ctx, badgeID); err != nil {
+			return err
+		}
+		return audit.Write(ctx, tx, c, "badge.delete", audit.Badges, badgeID, before, nil)
+	})
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Badge not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete badge"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Badge deleted successfully"})
+}
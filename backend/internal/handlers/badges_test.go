@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+)
+
+func TestBadgeChangesAreAudited(t *testing.T) {
+	useMemoryStore(t)
+	badge := map[string]interface{}{"id": "first", "name": "Primer caso", "rule": "cases_solved >= 1", "is_active": true}
+
+	if rec := serve(t, "admin", http.MethodPost, "/admin/badges", "/admin/badges", badge, CreateBadge); rec.Code != http.StatusCreated {
+		t.Fatalf("create got %d: %s", rec.Code, rec.Body.String())
+	}
+	if code := serve(t, "admin", http.MethodPost, "/admin/badges", "/admin/badges", badge, CreateBadge).Code; code != http.StatusConflict {
+		t.Errorf("creating it again got %d, want 409", code)
+	}
+	if code := serve(t, "admin", http.MethodPut, "/admin/badges/:id", "/admin/badges/first",
+		map[string]interface{}{"name": "Detective novato"}, UpdateBadge).Code; code != http.StatusOK {
+		t.Fatalf("update got %d", code)
+	}
+	if code := serve(t, "admin", http.MethodDelete, "/admin/badges/:id", "/admin/badges/first", nil, DeleteBadge).Code; code != http.StatusOK {
+		t.Fatalf("delete got %d", code)
+	}
+	if code := serve(t, "admin", http.MethodDelete, "/admin/badges/:id", "/admin/badges/first", nil, DeleteBadge).Code; code != http.StatusNotFound {
+		t.Errorf("deleting it again got %d, want 404", code)
+	}
+
+	if _, err := database.DB.Badges().Get(context.Background(), "first"); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("deleted badge: %v", err)
+	}
+
+	// Newest first, one entry per change that was made
+	entries, err := database.DB.Audit().Query(context.Background(), database.AuditFilter{TargetType: "badge", TargetID: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, entry := range entries {
+		actions = append(actions, entry.Action)
+	}
+	if len(actions) != 3 || actions[0] != "badge.delete" || actions[1] != "badge.update" || actions[2] != "badge.create" {
+		t.Fatalf("actions = %v, want delete, update and create", actions)
+	}
+	update := entries[1].Changes
+	if update["name"] != (models.FieldChange{Before: "Primer caso", After: "Detective novato"}) {
+		t.Errorf("name change = %+v", update["name"])
+	}
+	if entries[0].Changes["name"].After != nil {
+		t.Errorf("delete recorded name %v", entries[0].Changes["name"].After)
+	}
+}
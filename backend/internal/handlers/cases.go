@@ -1,61 +1,282 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"brew-detective-backend/internal/audit"
+	"brew-detective-backend/internal/catalog"
 	"brew-detective-backend/internal/database"
 	"brew-detective-backend/internal/models"
+	"brew-detective-backend/internal/schedule"
+	"brew-detective-backend/internal/scoring"
 
-	"cloud.google.com/go/firestore"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"google.golang.org/api/iterator"
 )
 
 // Admin case management functions
 
-// CreateCase creates a new coffee case (admin only)
-func CreateCase(c *gin.Context) {
-	var newCase models.CoffeeCase
+// caseFields holds the case fields an admin can set. On update, omitted
+// fields keep their value and enabled_questions is replaced as a whole.
+type caseFields struct {
+	Name             *string                  `json:"name"`
+	Description      *string                  `json:"description"`
+	Price            *int                     `json:"price"`
+	Coffees          *[]models.CoffeeItem     `json:"coffees"`
+	EnabledQuestions *models.EnabledQuestions `json:"enabled_questions"`
+	ScoringRules     json.RawMessage          `json:"scoring_rules"` // null resets the case to the default rules
+	IsActive         *bool                    `json:"is_active"`
+	OpensAt          json.RawMessage          `json:"opens_at"`  // null removes the scheduled opening
+	ClosesAt         json.RawMessage          `json:"closes_at"` // null removes the scheduled close
+}
 
-	if err := c.ShouldBindJSON(&newCase); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid case data"})
-		return
+// apply copies the set fields onto the case and returns them as document updates
+func (f *caseFields) apply(coffeeCase *models.CoffeeCase) (map[string]interface{}, fieldErrors) {
+	updates := make(map[string]interface{})
+	errs := fieldErrors{}
+
+	if f.Name != nil {
+		coffeeCase.Name = strings.TrimSpace(*f.Name)
+		updates["name"] = coffeeCase.Name
+	}
+	if f.Description != nil {
+		coffeeCase.Description = strings.TrimSpace(*f.Description)
+		updates["description"] = coffeeCase.Description
+	}
+	if f.Price != nil {
+		coffeeCase.Price = *f.Price
+		updates["price"] = coffeeCase.Price
+	}
+	if f.Coffees != nil {
+		coffeeCase.Coffees = assignCoffeeIDs(*f.Coffees)
+		updates["coffees"] = coffeeCase.Coffees
+	}
+	if f.EnabledQuestions != nil {
+		coffeeCase.EnabledQuestions = *f.EnabledQuestions
+		updates["enabled_questions"] = coffeeCase.EnabledQuestions
+	}
+	if len(f.ScoringRules) > 0 {
+		coffeeCase.ScoringRules = nil
+		if string(f.ScoringRules) != "null" {
+			var rules models.ScoringRules
+			for field, message := range decodeStrict(bytes.NewReader(f.ScoringRules), &rules) {
+				errs.add("scoring_rules."+field, message)
+			}
+			coffeeCase.ScoringRules = &rules
+		}
+		updates["scoring_rules"] = coffeeCase.ScoringRules
 	}
+	if f.IsActive != nil {
+		coffeeCase.IsActive = *f.IsActive
+		updates["is_active"] = coffeeCase.IsActive
+	}
+	if len(f.OpensAt) > 0 {
+		coffeeCase.OpensAt = optionalTime(f.OpensAt, "opens_at", errs)
+		updates["opens_at"] = coffeeCase.OpensAt
+	}
+	if len(f.ClosesAt) > 0 {
+		coffeeCase.ClosesAt = optionalTime(f.ClosesAt, "closes_at", errs)
+		updates["closes_at"] = coffeeCase.ClosesAt
+	}
+	return updates, errs
+}
 
-	// Validate required fields
-	if newCase.Name == "" || newCase.Description == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Name and description are required"})
-		return
+// optionalTime decodes an RFC 3339 timestamp that may be null
+func optionalTime(raw json.RawMessage, field string, errs fieldErrors) *time.Time {
+	if string(raw) == "null" {
+		return nil
+	}
+	var at time.Time
+	if err := json.Unmarshal(raw, &at); err != nil {
+		errs.add(field, "must be an RFC 3339 timestamp or null")
+		return nil
 	}
+	return &at
+}
 
-	// Generate case ID and set timestamps
-	newCase.ID = uuid.New().String()
-	newCase.CreatedAt = time.Now()
-	newCase.UpdatedAt = time.Now()
+// assignCoffeeIDs gives coffees without a proper ID a UUID. The admin form
+// sends placeholder IDs like "coffee_1" for new coffees.
+func assignCoffeeIDs(coffees []models.CoffeeItem) []models.CoffeeItem {
+	coffees = append([]models.CoffeeItem(nil), coffees...)
+	for i := range coffees {
+		if coffees[i].ID == "" || strings.HasPrefix(coffees[i].ID, "coffee_") {
+			coffees[i].ID = uuid.New().String()
+		}
+	}
+	return coffees
+}
+
+// validateCase checks a complete case and adds what is wrong with it to errs.
+// Unless index is nil, coffee regions, varieties and processes must refer to
+// active catalog items and are replaced with the items' values.
+func validateCase(coffeeCase *models.CoffeeCase, index *catalog.Index, errs fieldErrors) {
+	if coffeeCase.Name == "" {
+		errs.add("name", "is required")
+	}
+	if coffeeCase.Description == "" {
+		errs.add("description", "is required")
+	}
+	if coffeeCase.Price < 0 {
+		errs.add("price", "must not be negative")
+	}
+
+	questions := coffeeCase.EnabledQuestions
+	if !questions.Region && !questions.Variety && !questions.Process && !questions.TasteNote1 &&
+		!questions.TasteNote2 && !questions.FavoriteCoffee && !questions.BrewingMethod {
+		errs.add("enabled_questions", "at least one question must be enabled")
+	}
+
+	if len(coffeeCase.Coffees) == 0 {
+		errs.add("coffees", "at least one coffee is required")
+	}
+	seen := make(map[string]bool)
+	for i := range coffeeCase.Coffees {
+		coffee := &coffeeCase.Coffees[i]
+		path := fmt.Sprintf("coffees[%d]", i)
+		if seen[coffee.ID] {
+			errs.add(path+".id", "duplicate coffee ID %q", coffee.ID)
+		}
+		seen[coffee.ID] = true
+
+		for _, field := range []struct {
+			category string
+			value    *string
+			enabled  bool
+		}{
+			{catalog.Region, &coffee.Region, questions.Region},
+			{catalog.Variety, &coffee.Variety, questions.Variety},
+			{catalog.Process, &coffee.Process, questions.Process},
+		} {
+			if *field.value == "" {
+				if field.enabled {
+					errs.add(path+"."+field.category, "is required because the %s question is enabled", field.category)
+				}
+				continue
+			}
+			if index == nil {
+				continue
+			}
+			value, ok := index.Resolve(field.category, *field.value)
+			if !ok {
+				errs.add(path+"."+field.category, "%q is not an active %s in the catalog", *field.value, field.category)
+				continue
+			}
+			*field.value = value
+		}
 
-	// Generate UUIDs for each coffee if they don't have proper ones
-	for i := range newCase.Coffees {
-		if newCase.Coffees[i].ID == "" || strings.HasPrefix(newCase.Coffees[i].ID, "coffee_") {
-			newCase.Coffees[i].ID = uuid.New().String()
+		validateTastingNotes(coffee, path, index, questions.TasteNote1 || questions.TasteNote2, errs)
+	}
+
+	if coffeeCase.OpensAt != nil && coffeeCase.ClosesAt != nil && !coffeeCase.ClosesAt.After(*coffeeCase.OpensAt) {
+		errs.add("closes_at", "must be after opens_at")
+	}
+
+	if coffeeCase.ScoringRules != nil {
+		if err := scoring.ValidateRules(*coffeeCase.ScoringRules); err != nil {
+			errs.add("scoring_rules", "%v", err)
 		}
 	}
+}
 
-	// Default to inactive when created
-	if !newCase.IsActive {
-		newCase.IsActive = false
+// validateTastingNotes resolves a coffee's tasting notes to tasting note
+// values in the catalog. Notes given as legacy comma-separated text are
+// converted when the catalog is checked.
+func validateTastingNotes(coffee *models.CoffeeItem, path string, index *catalog.Index, required bool, errs fieldErrors) {
+	if index != nil && len(coffee.TastingNoteIDs) == 0 && coffee.TastingNotes != "" {
+		for _, note := range strings.Split(coffee.TastingNotes, ",") {
+			if note = strings.TrimSpace(note); note != "" {
+				coffee.TastingNoteIDs = append(coffee.TastingNoteIDs, note)
+			}
+		}
+		coffee.TastingNotes = ""
+	}
+	if len(coffee.TastingNoteIDs) == 0 && coffee.TastingNotes == "" {
+		if required {
+			errs.add(path+".tasting_note_ids", "at least one note is required because a tasting note question is enabled")
+		}
+		return
+	}
+	if index == nil {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for i, note := range coffee.TastingNoteIDs {
+		value, ok := index.Resolve(catalog.TastingNote, note)
+		if !ok {
+			errs.add(fmt.Sprintf("%s.tasting_note_ids[%d]", path, i), "%q is not an active tasting note in the catalog", note)
+			continue
+		}
+		if seen[value] {
+			errs.add(fmt.Sprintf("%s.tasting_note_ids[%d]", path, i), "duplicate tasting note %q", value)
+		}
+		seen[value] = true
+		coffee.TastingNoteIDs[i] = value
+	}
+}
+
+// validateOpening checks that a case can be opened now
+func validateOpening(coffeeCase *models.CoffeeCase, now time.Time, errs fieldErrors) {
+	if coffeeCase.ClosesAt != nil && !coffeeCase.ClosesAt.After(now) {
+		errs.add("closes_at", "has passed, so the case cannot be opened")
+	}
+}
+
+// CreateCase creates a new coffee case (admin only)
+func CreateCase(c *gin.Context) {
+	var fields caseFields
+	if errs := decodeStrict(c.Request.Body, &fields); errs != nil {
+		respondInvalid(c, "Invalid case data", errs)
+		return
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Save case to Firestore
-	_, err := database.FirestoreClient.Collection(database.CasesCollection).
-		Doc(newCase.ID).Set(ctx, newCase)
+	index, err := catalog.Load(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch catalog"})
+		return
+	}
+
+	// New cases are inactive unless is_active is set
+	var newCase models.CoffeeCase
+	_, errs := fields.apply(&newCase)
+	validateCase(&newCase, index, errs)
+	now := time.Now()
+	if newCase.IsActive {
+		validateOpening(&newCase, now, errs)
+	}
+	if len(errs) > 0 {
+		respondInvalid(c, "Invalid case data", errs)
+		return
+	}
+
+	// Generate case ID and set timestamps
+	newCase.ID = uuid.New().String()
+	newCase.CreatedAt = now
+	newCase.UpdatedAt = newCase.CreatedAt
+
+	// Save case, closing the active case if this one opens right away
+	err = database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		if newCase.IsActive {
+			if err := schedule.CloseOthers(ctx, tx, newCase.ID, now); err != nil {
+				return err
+			}
+		}
+		if err := tx.Cases().Save(ctx, &newCase); err != nil {
+			return err
+		}
+		return audit.Write(ctx, tx, c, "case.create", audit.Cases, newCase.ID, nil, &newCase)
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create case"})
 		return
@@ -67,59 +288,104 @@ func CreateCase(c *gin.Context) {
 	})
 }
 
-// UpdateCase updates an existing coffee case (admin only)
+// Reasons an update is rejected, returned from its transaction
+var (
+	errInvalidCase   = errors.New("invalid case")
+	errNoCaseUpdates = errors.New("no fields to update")
+	errCaseRevealed  = errors.New("revealed cases cannot be reopened")
+)
+
+// UpdateCase updates an existing coffee case (admin only). The case must be
+// valid once the update is applied.
 func UpdateCase(c *gin.Context) {
 	caseID := c.Param("id")
 
-	var updates map[string]interface{}
-	if err := c.ShouldBindJSON(&updates); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid update data"})
+	var fields caseFields
+	if errs := decodeStrict(c.Request.Body, &fields); errs != nil {
+		respondInvalid(c, "Invalid update data", errs)
 		return
 	}
 
-	// Handle coffee UUIDs if coffees are being updated
-	if coffeesData, exists := updates["coffees"]; exists {
-		if coffeesSlice, ok := coffeesData.([]interface{}); ok {
-			for i, coffeeData := range coffeesSlice {
-				if coffeeMap, ok := coffeeData.(map[string]interface{}); ok {
-					if id, hasID := coffeeMap["id"]; hasID {
-						if idStr, ok := id.(string); ok && (idStr == "" || strings.HasPrefix(idStr, "coffee_")) {
-							coffeeMap["id"] = uuid.New().String()
-							coffeesSlice[i] = coffeeMap
-						}
-					}
-				}
-			}
-			updates["coffees"] = coffeesSlice
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Coffees that are not changed keep values that may since have been
+	// retired from the catalog. The catalog is read before the transaction,
+	// since it is not part of it.
+	var index *catalog.Index
+	if fields.Coffees != nil {
+		var err error
+		if index, err = catalog.Load(ctx); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch catalog"})
+			return
 		}
 	}
 
-	// Add updated timestamp
-	updates["updated_at"] = time.Now()
+	// Read, validate and write in one transaction so that concurrent updates
+	// and the scheduler cannot overwrite each other's phase or fields
+	var errs fieldErrors
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		existing, err := tx.Cases().Get(ctx, caseID)
+		if err != nil {
+			return err
+		}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+		updated := *existing
+		var updates map[string]interface{}
+		updates, errs = fields.apply(&updated)
+		validateCase(&updated, index, errs)
+		if len(errs) > 0 {
+			return errInvalidCase
+		}
+		if fields.Coffees != nil {
+			updates["coffees"] = updated.Coffees // With catalog values
+		}
+		if len(updates) == 0 {
+			return errNoCaseUpdates
+		}
 
-	// Check if case exists
-	doc, err := database.FirestoreClient.Collection(database.CasesCollection).Doc(caseID).Get(ctx)
-	if err != nil || !doc.Exists() {
+		// Add updated timestamp
+		now := time.Now()
+		updates["updated_at"] = now
+
+		opening := updated.IsActive && !existing.IsActive
+		if opening {
+			if existing.FinalizedAt != nil || existing.ResultsRevealedAt != nil {
+				return errCaseRevealed
+			}
+			if validateOpening(&updated, now, errs); len(errs) > 0 {
+				return errInvalidCase
+			}
+			// Opening it closes the case that was active
+			updates["closed_at"] = nil
+			if err := schedule.Open(ctx, tx, caseID, updates, now); err != nil {
+				return err
+			}
+			return audit.WriteUpdate(ctx, tx, c, "case.update", audit.Cases, caseID, existing, updates)
+		}
+		if existing.IsActive && !updated.IsActive {
+			// Track when the case closes so late submissions get a grace window
+			updates["closed_at"] = now
+		}
+		if err := tx.Cases().Update(ctx, caseID, updates); err != nil {
+			return err
+		}
+		return audit.WriteUpdate(ctx, tx, c, "case.update", audit.Cases, caseID, existing, updates)
+	})
+	switch {
+	case errors.Is(err, database.ErrNotFound):
 		c.JSON(http.StatusNotFound, gin.H{"error": "Case not found"})
 		return
-	}
-
-	// Convert map to firestore updates
-	var firestoreUpdates []firestore.Update
-	for key, value := range updates {
-		firestoreUpdates = append(firestoreUpdates, firestore.Update{
-			Path:  key,
-			Value: value,
-		})
-	}
-
-	// Update the case
-	_, err = database.FirestoreClient.Collection(database.CasesCollection).
-		Doc(caseID).Update(ctx, firestoreUpdates)
-	if err != nil {
+	case errors.Is(err, errInvalidCase):
+		respondInvalid(c, "Invalid update data", errs)
+		return
+	case errors.Is(err, errNoCaseUpdates):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No valid fields to update"})
+		return
+	case errors.Is(err, errCaseRevealed):
+		c.JSON(http.StatusConflict, gin.H{"error": "Revealed cases cannot be reopened"})
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update case"})
 		return
 	}
@@ -134,15 +400,21 @@ func DeleteCase(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Check if case exists
-	doc, err := database.FirestoreClient.Collection(database.CasesCollection).Doc(caseID).Get(ctx)
-	if err != nil || !doc.Exists() {
+	// Delete the case with its audit entry
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		before, err := tx.Cases().Get(ctx, caseID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Cases().Delete(ctx, caseID); err != nil {
+			return err
+		}
+		return audit.Write(ctx, tx, c, "case.delete", audit.Cases, caseID, before, nil)
+	})
+	if errors.Is(err, database.ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Case not found"})
 		return
 	}
-
-	// Delete the case
-	_, err = database.FirestoreClient.Collection(database.CasesCollection).Doc(caseID).Delete(ctx)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete case"})
 		return
@@ -175,31 +447,10 @@ func GetAllCases(c *gin.Context) {
 	defer cancel()
 
 	// Query all cases ordered by creation date (newest first)
-	query := database.FirestoreClient.Collection(database.CasesCollection).
-		OrderBy("created_at", firestore.Desc).
-		Limit(limit).
-		Offset(offset)
-
-	iter := query.Documents(ctx)
-
-	var cases []models.CoffeeCase
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cases", "details": err.Error()})
-			return
-		}
-
-		var coffeeCase models.CoffeeCase
-		if err := doc.DataTo(&coffeeCase); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse case data"})
-			return
-		}
-
-		cases = append(cases, coffeeCase)
+	cases, err := database.DB.Cases().List(ctx, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cases", "details": err.Error()})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -215,28 +466,10 @@ func GetCases(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	iter := database.FirestoreClient.Collection(database.CasesCollection).
-		Where("is_active", "==", true).
-		Documents(ctx)
-
-	var cases []models.CoffeeCase
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cases"})
-			return
-		}
-
-		var coffeeCase models.CoffeeCase
-		if err := doc.DataTo(&coffeeCase); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse case data"})
-			return
-		}
-
-		cases = append(cases, coffeeCase)
+	cases, err := database.DB.Cases().ListActive(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cases"})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"cases": cases})
@@ -249,18 +482,12 @@ func GetCaseByID(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	doc, err := database.FirestoreClient.Collection(database.CasesCollection).Doc(caseID).Get(ctx)
+	coffeeCase, err := database.DB.Cases().Get(ctx, caseID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Case not found"})
 		return
 	}
 
-	var coffeeCase models.CoffeeCase
-	if err := doc.DataTo(&coffeeCase); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse case data"})
-		return
-	}
-
 	c.JSON(http.StatusOK, gin.H{"case": coffeeCase})
 }
 
@@ -269,13 +496,8 @@ func GetActiveCase(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	iter := database.FirestoreClient.Collection(database.CasesCollection).
-		Where("is_active", "==", true).
-		Limit(1).
-		Documents(ctx)
-
-	doc, err := iter.Next()
-	if err == iterator.Done {
+	coffeeCase, err := database.DB.Cases().GetActive(ctx)
+	if errors.Is(err, database.ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "No active case found"})
 		return
 	}
@@ -284,48 +506,17 @@ func GetActiveCase(c *gin.Context) {
 		return
 	}
 
-	var coffeeCase models.CoffeeCase
-	if err := doc.DataTo(&coffeeCase); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse case data"})
-		return
-	}
-
 	c.JSON(http.StatusOK, gin.H{"case": coffeeCase})
 }
 
-// GetActiveCasePublic returns the current active coffee case without answers (public endpoint)
-func GetActiveCasePublic(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	iter := database.FirestoreClient.Collection(database.CasesCollection).
-		Where("is_active", "==", true).
-		Limit(1).
-		Documents(ctx)
-
-	doc, err := iter.Next()
-	if err == iterator.Done {
-		c.JSON(http.StatusNotFound, gin.H{"error": "No active case found"})
-		return
-	}
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch active case"})
-		return
-	}
-
-	var coffeeCase models.CoffeeCase
-	if err := doc.DataTo(&coffeeCase); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse case data"})
-		return
-	}
-
-	// Create sanitized public version without coffee answers
+// toPublicCase creates the sanitized public version of a case, without coffee answers
+func toPublicCase(coffeeCase *models.CoffeeCase) models.PublicCoffeeCase {
 	coffeeIDs := make([]string, len(coffeeCase.Coffees))
 	for i, coffee := range coffeeCase.Coffees {
 		coffeeIDs[i] = coffee.ID
 	}
 
-	publicCase := models.PublicCoffeeCase{
+	return models.PublicCoffeeCase{
 		ID:               coffeeCase.ID,
 		Name:             coffeeCase.Name,
 		Description:      coffeeCase.Description,
@@ -333,9 +524,28 @@ func GetActiveCasePublic(c *gin.Context) {
 		CoffeeIDs:        coffeeIDs,
 		CoffeeCount:      len(coffeeCase.Coffees),
 		IsActive:         coffeeCase.IsActive,
+		Phase:            schedule.Phase(coffeeCase),
+		OpensAt:          coffeeCase.OpensAt,
+		ClosesAt:         coffeeCase.ClosesAt,
+	}
+}
+
+// GetActiveCasePublic returns the current active coffee case without answers (public endpoint)
+func GetActiveCasePublic(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	coffeeCase, err := database.DB.Cases().GetActive(ctx)
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No active case found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch active case"})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"case": publicCase})
+	c.JSON(http.StatusOK, gin.H{"case": toPublicCase(coffeeCase)})
 }
 
 // GetCasesPublic returns all active coffee cases without answers (public endpoint)
@@ -343,44 +553,15 @@ func GetCasesPublic(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	iter := database.FirestoreClient.Collection(database.CasesCollection).
-		Where("is_active", "==", true).
-		Documents(ctx)
+	cases, err := database.DB.Cases().ListActive(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cases"})
+		return
+	}
 
 	var publicCases []models.PublicCoffeeCase
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cases"})
-			return
-		}
-
-		var coffeeCase models.CoffeeCase
-		if err := doc.DataTo(&coffeeCase); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse case data"})
-			return
-		}
-
-		// Create sanitized public version without coffee answers
-		coffeeIDs := make([]string, len(coffeeCase.Coffees))
-		for i, coffee := range coffeeCase.Coffees {
-			coffeeIDs[i] = coffee.ID
-		}
-
-		publicCase := models.PublicCoffeeCase{
-			ID:               coffeeCase.ID,
-			Name:             coffeeCase.Name,
-			Description:      coffeeCase.Description,
-			EnabledQuestions: coffeeCase.EnabledQuestions,
-			CoffeeIDs:        coffeeIDs,
-			CoffeeCount:      len(coffeeCase.Coffees),
-			IsActive:         coffeeCase.IsActive,
-		}
-
-		publicCases = append(publicCases, publicCase)
+	for i := range cases {
+		publicCases = append(publicCases, toPublicCase(&cases[i]))
 	}
 
 	c.JSON(http.StatusOK, gin.H{"cases": publicCases})
@@ -393,33 +574,16 @@ func GetCaseByIDPublic(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	doc, err := database.FirestoreClient.Collection(database.CasesCollection).Doc(caseID).Get(ctx)
+	coffeeCase, err := database.DB.Cases().Get(ctx, caseID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Case not found"})
 		return
 	}
 
-	var coffeeCase models.CoffeeCase
-	if err := doc.DataTo(&coffeeCase); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse case data"})
-		return
-	}
-
-	// Create sanitized public version without coffee answers
-	coffeeIDs := make([]string, len(coffeeCase.Coffees))
-	for i, coffee := range coffeeCase.Coffees {
-		coffeeIDs[i] = coffee.ID
-	}
-
-	publicCase := models.PublicCoffeeCase{
-		ID:               coffeeCase.ID,
-		Name:             coffeeCase.Name,
-		Description:      coffeeCase.Description,
-		EnabledQuestions: coffeeCase.EnabledQuestions,
-		CoffeeIDs:        coffeeIDs,
-		CoffeeCount:      len(coffeeCase.Coffees),
-		IsActive:         coffeeCase.IsActive,
-	}
+	c.JSON(http.StatusOK, gin.H{"case": toPublicCase(coffeeCase)})
+}
 
-	c.JSON(http.StatusOK, gin.H{"case": publicCase})
+// GetDefaultScoringRules returns the scoring rules applied to cases without their own (admin only)
+func GetDefaultScoringRules(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"scoring_rules": scoring.DefaultRules()})
 }
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+)
+
+func updateCase(t *testing.T, id string, fields map[string]interface{}) int {
+	return serve(t, "admin", http.MethodPut, "/admin/cases/:id", "/admin/cases/"+id, fields, UpdateCase).Code
+}
+
+// caseAudit returns the audit entries of a case, or of every case for an empty ID
+func caseAudit(t *testing.T, caseID string) []models.AuditEntry {
+	t.Helper()
+	entries, err := database.DB.Audit().Query(context.Background(), database.AuditFilter{TargetType: "case", TargetID: caseID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestUpdateCaseOpensASingleCase(t *testing.T) {
+	useMemoryStore(t)
+	seedCase(t, "case1")
+	next := seedCase(t, "case2")
+	next.IsActive = false
+	if err := database.DB.Cases().Save(context.Background(), next); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := updateCase(t, "case2", map[string]interface{}{"is_active": true}); code != http.StatusOK {
+		t.Fatalf("opening got %d", code)
+	}
+	active, err := database.DB.Cases().ListActive(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active) != 1 || active[0].ID != "case2" {
+		t.Fatalf("%d active cases, want only case2", len(active))
+	}
+	closed, err := database.DB.Cases().Get(context.Background(), "case1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if closed.ClosedAt == nil {
+		t.Error("the case that was open has no closed_at")
+	}
+}
+
+func TestUpdateCaseRejectsInvalidUpdates(t *testing.T) {
+	useMemoryStore(t)
+	seedCase(t, "case1")
+	revealed := seedCase(t, "case2")
+	now := time.Now()
+	revealed.IsActive = false
+	revealed.ClosedAt = &now
+	revealed.ResultsRevealedAt = &now
+	if err := database.DB.Cases().Save(context.Background(), revealed); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		id     string
+		fields map[string]interface{}
+		status int
+	}{
+		{"unknown case", "case9", map[string]interface{}{"name": "Caso"}, http.StatusNotFound},
+		{"invalid field", "case1", map[string]interface{}{"name": "", "price": 5}, http.StatusBadRequest},
+		{"nothing to update", "case1", map[string]interface{}{}, http.StatusBadRequest},
+		{"reopening a revealed case", "case2", map[string]interface{}{"is_active": true}, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := updateCase(t, tt.id, tt.fields); code != tt.status {
+				t.Errorf("got %d, want %d", code, tt.status)
+			}
+		})
+	}
+
+	// Rejected updates write nothing
+	coffeeCase, err := database.DB.Cases().Get(context.Background(), "case1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if coffeeCase.Name != "Caso case1" || coffeeCase.Price != 0 || !coffeeCase.IsActive {
+		t.Errorf("case changed by rejected updates: %+v", coffeeCase)
+	}
+	if entries := caseAudit(t, ""); len(entries) != 0 {
+		t.Errorf("rejected updates wrote %d audit entries", len(entries))
+	}
+}
+
+func TestUpdateCaseWritesItsAuditEntry(t *testing.T) {
+	useMemoryStore(t)
+	seedCase(t, "case1")
+
+	if code := updateCase(t, "case1", map[string]interface{}{"name": "Caso nuevo", "price": 0}); code != http.StatusOK {
+		t.Fatalf("update got %d", code)
+	}
+
+	entries := caseAudit(t, "case1")
+	if len(entries) != 1 {
+		t.Fatalf("%d audit entries, want 1", len(entries))
+	}
+	entry := entries[0]
+	if entry.Action != "case.update" || entry.Actor != "admin" {
+		t.Errorf("entry = %+v", entry)
+	}
+	name := entry.Changes["name"]
+	if name.Before != "Caso case1" || name.After != "Caso nuevo" {
+		t.Errorf("name change = %+v", name)
+	}
+	if _, ok := entry.Changes["price"]; ok {
+		t.Error("unchanged price recorded as a change")
+	}
+}
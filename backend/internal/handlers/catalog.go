@@ -2,19 +2,22 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"brew-detective-backend/internal/audit"
+	"brew-detective-backend/internal/catalog"
 	"brew-detective-backend/internal/database"
 	"brew-detective-backend/internal/models"
 
-	"cloud.google.com/go/firestore"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"google.golang.org/api/iterator"
 )
 
 // GetCatalogByCategory returns catalog items for a specific category
@@ -22,45 +25,19 @@ func GetCatalogByCategory(c *gin.Context) {
 	category := c.Param("category")
 
 	// Validate category
-	validCategories := map[string]bool{
-		"region":         true,
-		"variety":        true,
-		"process":        true,
-		"brewing_method": true,
-	}
-
-	if !validCategories[category] {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category. Must be region, variety, process, or brewing_method"})
+	if !catalog.IsCategory(category) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category. Must be one of: " + strings.Join(catalog.Categories(), ", ")})
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	iter := database.FirestoreClient.Collection(database.CatalogCollection).
-		Where("category", "==", category).
-		Where("is_active", "==", true).
-		Documents(ctx)
-
-	var items []models.CatalogItem
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			log.Printf("Error fetching catalog items: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch catalog items", "details": err.Error()})
-			return
-		}
-
-		var item models.CatalogItem
-		if err := doc.DataTo(&item); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse catalog item"})
-			return
-		}
-
-		items = append(items, item)
+	items, err := database.DB.Catalog().ListActive(ctx, category)
+	if err != nil {
+		log.Printf("Error fetching catalog items: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch catalog items", "details": err.Error()})
+		return
 	}
 
 	// Sort by display order
@@ -76,28 +53,15 @@ func GetAllCatalog(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	iter := database.FirestoreClient.Collection(database.CatalogCollection).
-		Where("is_active", "==", true).
-		Documents(ctx)
+	items, err := database.DB.Catalog().ListActive(ctx, "")
+	if err != nil {
+		log.Printf("Error fetching catalog items: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch catalog items", "details": err.Error()})
+		return
+	}
 
 	catalogMap := make(map[string][]models.CatalogItem)
-
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			log.Printf("Error fetching catalog items: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch catalog items", "details": err.Error()})
-		}
-
-		var item models.CatalogItem
-		if err := doc.DataTo(&item); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse catalog item"})
-			return
-		}
-
+	for _, item := range items {
 		catalogMap[item.Category] = append(catalogMap[item.Category], item)
 	}
 
@@ -111,6 +75,102 @@ func GetAllCatalog(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"catalog": catalogMap})
 }
 
+// cleanSynonyms trims synonyms and drops empty and repeated ones
+func cleanSynonyms(synonyms []string) []string {
+	cleaned := []string{}
+	seen := make(map[string]bool)
+	for _, synonym := range synonyms {
+		synonym = strings.TrimSpace(synonym)
+		folded := catalog.Fold(synonym)
+		if folded == "" || seen[folded] {
+			continue
+		}
+		seen[folded] = true
+		cleaned = append(cleaned, synonym)
+	}
+	return cleaned
+}
+
+// stringList converts a decoded JSON array of strings
+func stringList(value interface{}) ([]string, bool) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		text, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		list = append(list, text)
+	}
+	return list, true
+}
+
+// catalogConflict describes a value, label or synonym of the item that already
+// refers to another active item of its category, since answers could not tell
+// them apart. It returns an empty string when there is no conflict.
+func catalogConflict(ctx context.Context, item *models.CatalogItem) (string, error) {
+	others, err := database.DB.Catalog().ListActive(ctx, item.Category)
+	if err != nil {
+		return "", err
+	}
+
+	for _, other := range others {
+		if other.ID == item.ID {
+			continue
+		}
+		taken := make(map[string]bool)
+		for _, term := range catalog.Terms(other) {
+			taken[catalog.Fold(term)] = true
+		}
+		for _, term := range catalog.Terms(*item) {
+			if folded := catalog.Fold(term); folded != "" && taken[folded] {
+				return fmt.Sprintf("%q already refers to %s %q", term, other.Category, other.Value), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// catalogParentError describes what is wrong with the item's parent: only
+// tasting notes have one, and it must be another active tasting note that is
+// not below the item. It returns an empty string when the parent is valid.
+func catalogParentError(ctx context.Context, item *models.CatalogItem) (string, error) {
+	if item.Parent == "" {
+		return "", nil
+	}
+	if item.Category != catalog.TastingNote {
+		return "Only tasting notes can have a parent", nil
+	}
+
+	notes, err := database.DB.Catalog().ListActive(ctx, catalog.TastingNote)
+	if err != nil {
+		return "", err
+	}
+	parents := make(map[string]string)
+	found := false
+	for _, note := range notes {
+		if note.ID == item.ID {
+			continue
+		}
+		parents[note.Value] = note.Parent
+		found = found || note.Value == item.Parent
+	}
+	if !found || item.Parent == item.Value {
+		return fmt.Sprintf("Parent %q is not another active tasting note", item.Parent), nil
+	}
+
+	parent := func(value string) string { return parents[value] }
+	for _, ancestor := range catalog.Ancestors(item.Parent, parent) {
+		if ancestor == item.Value {
+			return fmt.Sprintf("Parent %q is below %q in the taxonomy", item.Parent, item.Value), nil
+		}
+	}
+	return "", nil
+}
+
 // CreateCatalogItem creates a new catalog item (admin only)
 func CreateCatalogItem(c *gin.Context) {
 	var item models.CatalogItem
@@ -127,14 +187,7 @@ func CreateCatalogItem(c *gin.Context) {
 	}
 
 	// Validate category
-	validCategories := map[string]bool{
-		"region":         true,
-		"variety":        true,
-		"process":        true,
-		"brewing_method": true,
-	}
-
-	if !validCategories[item.Category] {
+	if !catalog.IsCategory(item.Category) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category"})
 		return
 	}
@@ -142,6 +195,8 @@ func CreateCatalogItem(c *gin.Context) {
 	// Set defaults
 	item.ID = uuid.New().String()
 	item.CreatedAt = time.Now()
+	item.Synonyms = cleanSynonyms(item.Synonyms)
+	item.Parent = strings.TrimSpace(item.Parent)
 	if !item.IsActive {
 		item.IsActive = true // Default to active
 	}
@@ -149,13 +204,34 @@ func CreateCatalogItem(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Save to Firestore
-	_, err := database.FirestoreClient.Collection(database.CatalogCollection).
-		Doc(item.ID).Set(ctx, item)
+	if message, err := catalogParentError(ctx, &item); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create catalog item"})
+		return
+	} else if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	if message, err := catalogConflict(ctx, &item); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create catalog item"})
+		return
+	} else if message != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": message})
+		return
+	}
+
+	// Save catalog item with its audit entry
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		if err := tx.Catalog().Save(ctx, &item); err != nil {
+			return err
+		}
+		return audit.Write(ctx, tx, c, "catalog_item.create", audit.CatalogItems, item.ID, nil, &item)
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create catalog item"})
 		return
 	}
+	catalog.Invalidate()
 
 	c.JSON(http.StatusCreated, item)
 }
@@ -173,32 +249,90 @@ func UpdateCatalogItem(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Build Firestore updates
-	var firestoreUpdates []firestore.Update
+	// Build field updates
+	allowedUpdates := make(map[string]interface{})
 	for key, value := range updates {
 		// Only allow specific fields to be updated
 		switch key {
 		case "label", "value", "is_active", "display_order":
-			firestoreUpdates = append(firestoreUpdates, firestore.Update{
-				Path:  key,
-				Value: value,
-			})
+			allowedUpdates[key] = value
+		case "synonyms":
+			synonyms, ok := stringList(value)
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Synonyms must be a list of strings"})
+				return
+			}
+			allowedUpdates[key] = cleanSynonyms(synonyms)
+		case "parent":
+			parent, ok := value.(string)
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Parent must be a string"})
+				return
+			}
+			allowedUpdates[key] = strings.TrimSpace(parent)
 		}
 	}
 
-	if len(firestoreUpdates) == 0 {
+	if len(allowedUpdates) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No valid fields to update"})
 		return
 	}
 
-	// Update document
-	_, err := database.FirestoreClient.Collection(database.CatalogCollection).
-		Doc(itemID).Update(ctx, firestoreUpdates)
+	// Check the item's new names against the other items
+	existing, err := database.DB.Catalog().Get(ctx, itemID)
 	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Catalog item not found"})
+		return
+	}
+	updated := *existing
+	if label, ok := allowedUpdates["label"].(string); ok {
+		updated.Label = label
+	}
+	if value, ok := allowedUpdates["value"].(string); ok {
+		updated.Value = value
+	}
+	if synonyms, ok := allowedUpdates["synonyms"].([]string); ok {
+		updated.Synonyms = synonyms
+	}
+	if parent, ok := allowedUpdates["parent"].(string); ok {
+		updated.Parent = parent
+	}
+	if message, err := catalogParentError(ctx, &updated); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update catalog item"})
 		return
+	} else if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+	if message, err := catalogConflict(ctx, &updated); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update catalog item"})
+		return
+	} else if message != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": message})
+		return
 	}
 
+	// Update document with its audit entry
+	err = database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		before, err := tx.Catalog().Get(ctx, itemID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Catalog().Update(ctx, itemID, allowedUpdates); err != nil {
+			return err
+		}
+		return audit.WriteUpdate(ctx, tx, c, "catalog_item.update", audit.CatalogItems, itemID, before, allowedUpdates)
+	})
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Catalog item not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update catalog item"})
+		return
+	}
+	catalog.Invalidate()
+
 	c.JSON(http.StatusOK, gin.H{"message": "Catalog item updated successfully"})
 }
 
@@ -209,13 +343,26 @@ func DeleteCatalogItem(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Delete from Firestore
-	_, err := database.FirestoreClient.Collection(database.CatalogCollection).
-		Doc(itemID).Delete(ctx)
+	// Delete catalog item with its audit entry
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		before, err := tx.Catalog().Get(ctx, itemID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Catalog().Delete(ctx, itemID); err != nil {
+			return err
+		}
+		return audit.Write(ctx, tx, c, "catalog_item.delete", audit.CatalogItems, itemID, before, nil)
+	})
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Catalog item not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete catalog item"})
 		return
 	}
+	catalog.Invalidate()
 
 	c.JSON(http.StatusOK, gin.H{"message": "Catalog item deleted successfully"})
 }
@@ -244,39 +391,12 @@ func GetAllCatalogItems(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Build query
-	query := database.FirestoreClient.Collection(database.CatalogCollection).
-		OrderBy("category", firestore.Asc).
-		OrderBy("display_order", firestore.Asc).
-		Limit(limit).
-		Offset(offset)
-
-	// Add category filter if provided
-	if category != "" {
-		query = query.Where("category", "==", category)
-	}
-
-	iter := query.Documents(ctx)
-
-	var items []models.CatalogItem
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			log.Printf("Error fetching catalog items: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch catalog items", "details": err.Error()})
-			return
-		}
-
-		var item models.CatalogItem
-		if err := doc.DataTo(&item); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse catalog item"})
-			return
-		}
-
-		items = append(items, item)
+	// Query items, filtered by category if provided
+	items, err := database.DB.Catalog().List(ctx, category, limit, offset)
+	if err != nil {
+		log.Printf("Error fetching catalog items: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch catalog items", "details": err.Error()})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
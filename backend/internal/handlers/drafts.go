@@ -0,0 +1,405 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+	"brew-detective-backend/internal/scoring"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// draftFields is the body of a draft save. Omitted fields keep their saved value.
+type draftFields struct {
+	CoffeeAnswers  []coffeeAnswerFields `json:"coffee_answers"`
+	FavoriteCoffee *string              `json:"favorite_coffee"`
+	BrewingMethod  *string              `json:"brewing_method"`
+}
+
+// coffeeAnswerFields updates the draft's answer for one coffee
+type coffeeAnswerFields struct {
+	CoffeeID   string  `json:"coffee_id"`
+	Region     *string `json:"region"`
+	Variety    *string `json:"variety"`
+	Process    *string `json:"process"`
+	TasteNote1 *string `json:"taste_note_1"`
+	TasteNote2 *string `json:"taste_note_2"`
+}
+
+// answers returns the saved values of each coffee answer, empty where omitted
+func (f draftFields) answers() []models.CoffeeAnswer {
+	value := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return strings.TrimSpace(*s)
+	}
+
+	answers := make([]models.CoffeeAnswer, len(f.CoffeeAnswers))
+	for i, answer := range f.CoffeeAnswers {
+		answers[i] = models.CoffeeAnswer{
+			CoffeeID:   answer.CoffeeID,
+			Region:     value(answer.Region),
+			Variety:    value(answer.Variety),
+			Process:    value(answer.Process),
+			TasteNote1: value(answer.TasteNote1),
+			TasteNote2: value(answer.TasteNote2),
+		}
+	}
+	return answers
+}
+
+// draftFieldsError rejects a draft field by field
+type draftFieldsError struct {
+	message string
+	fields  fieldErrors
+}
+
+func (e *draftFieldsError) Error() string {
+	return e.message
+}
+
+var (
+	errDraftNotFound = &submissionError{
+		Status:  http.StatusNotFound,
+		Code:    "draft_not_found",
+		Message: "No hay respuestas guardadas para este código de pedido.",
+	}
+	errDraftFinalized = &submissionError{
+		Status:  http.StatusConflict,
+		Code:    "draft_finalized",
+		Message: "Estas respuestas ya fueron enviadas y no se pueden modificar.",
+	}
+)
+
+// SaveDraft creates the draft of an order or updates it. Coffee answers are
+// merged per coffee and per question, so each coffee can be saved on its own.
+func SaveDraft(c *gin.Context) {
+	orderID := c.Param("order_id")
+	userID := c.GetString("userID")
+
+	var fields draftFields
+	if errs := decodeStrict(c.Request.Body, &fields); errs != nil {
+		respondInvalid(c, "Invalid draft data", errs)
+		return
+	}
+	errs := fieldErrors{}
+	seen := make(map[string]bool)
+	for i, answer := range fields.CoffeeAnswers {
+		path := fmt.Sprintf("coffee_answers[%d].coffee_id", i)
+		switch {
+		case answer.CoffeeID == "":
+			errs.add(path, "is required")
+		case seen[answer.CoffeeID]:
+			errs.add(path, "is repeated")
+		}
+		seen[answer.CoffeeID] = true
+	}
+	if len(errs) > 0 {
+		respondInvalid(c, "Invalid draft data", errs)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	answers := fields.answers()
+	canonicalAnswers(ctx, answers)
+
+	now := time.Now()
+	var draft *models.Draft
+	var coffeeCase *models.CoffeeCase
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		order, team, err := loadOrder(ctx, tx, orderID, userID)
+		if err != nil {
+			return err
+		}
+		id := draftID(order.OrderID, team, userID)
+		draft, err = tx.Drafts().Get(ctx, id)
+		switch {
+		case errors.Is(err, database.ErrNotFound):
+			draft = &models.Draft{ID: id, UserID: userID, OrderID: order.OrderID, CreatedAt: now}
+		case err != nil:
+			return err
+		case !canEditDraft(draft, team, userID):
+			return errDraftInProgress
+		case draft.FinalizedAt != nil:
+			return errDraftFinalized
+		}
+
+		// Drafts can only be saved while the order could be submitted
+		coffeeCase, err = checkOrderOpen(ctx, tx, order, team, userID, now)
+		if err != nil {
+			return err
+		}
+		draft.CaseID = coffeeCase.ID
+
+		if errs := applyDraftFields(draft, coffeeCase, fields, answers); len(errs) > 0 {
+			return &draftFieldsError{message: "Invalid draft data", fields: errs}
+		}
+		draft.UpdatedAt = now
+		return tx.Drafts().Save(ctx, draft)
+	})
+	var fieldsErr *draftFieldsError
+	var subErr *submissionError
+	switch {
+	case errors.As(err, &fieldsErr):
+		respondInvalid(c, fieldsErr.message, fieldsErr.fields)
+		return
+	case errors.As(err, &subErr):
+		c.JSON(subErr.Status, gin.H{"error": subErr.Message, "code": subErr.Code})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save draft", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"draft":   draft,
+		"missing": draftMissing(draft, coffeeCase),
+	})
+}
+
+// applyDraftFields merges a draft save into the draft. answers holds the
+// saved values of fields.CoffeeAnswers.
+func applyDraftFields(draft *models.Draft, coffeeCase *models.CoffeeCase, fields draftFields, answers []models.CoffeeAnswer) fieldErrors {
+	errs := fieldErrors{}
+	coffees := make(map[string]bool, len(coffeeCase.Coffees))
+	for _, coffee := range coffeeCase.Coffees {
+		coffees[coffee.ID] = true
+	}
+
+	for i, patch := range fields.CoffeeAnswers {
+		if !coffees[patch.CoffeeID] {
+			errs.add(fmt.Sprintf("coffee_answers[%d].coffee_id", i), "is not a coffee of the case")
+			continue
+		}
+
+		answer := draftAnswer(draft, patch.CoffeeID)
+		for _, field := range []struct {
+			patch *string
+			dst   *string
+			value string
+		}{
+			{patch.Region, &answer.Region, answers[i].Region},
+			{patch.Variety, &answer.Variety, answers[i].Variety},
+			{patch.Process, &answer.Process, answers[i].Process},
+			{patch.TasteNote1, &answer.TasteNote1, answers[i].TasteNote1},
+			{patch.TasteNote2, &answer.TasteNote2, answers[i].TasteNote2},
+		} {
+			if field.patch != nil {
+				*field.dst = field.value
+			}
+		}
+	}
+
+	if fields.FavoriteCoffee != nil {
+		draft.FavoriteCoffee = strings.TrimSpace(*fields.FavoriteCoffee)
+	}
+	if fields.BrewingMethod != nil {
+		draft.BrewingMethod = strings.TrimSpace(*fields.BrewingMethod)
+	}
+	return errs
+}
+
+// draftID returns the ID of the user's draft of an order. Members of a team
+// playing separately each keep their own draft, while everyone else shares
+// the order's draft.
+func draftID(orderID string, team *models.Team, userID string) string {
+	if team != nil && team.Mode == models.TeamModeSeparate && userID != team.OwnerID {
+		return orderID + "_" + userID
+	}
+	return orderID
+}
+
+// canEditDraft reports whether the user can see and change a draft. Drafts of
+// teams playing jointly are shared by all the members.
+func canEditDraft(draft *models.Draft, team *models.Team, userID string) bool {
+	if team != nil && team.Mode == models.TeamModeJoint {
+		return isTeamMember(team, userID)
+	}
+	return draft.UserID == userID
+}
+
+// draftAnswer returns the draft's answer for a coffee, adding an empty one
+// when the coffee has not been answered yet
+func draftAnswer(draft *models.Draft, coffeeID string) *models.CoffeeAnswer {
+	for i := range draft.CoffeeAnswers {
+		if draft.CoffeeAnswers[i].CoffeeID == coffeeID {
+			return &draft.CoffeeAnswers[i]
+		}
+	}
+	draft.CoffeeAnswers = append(draft.CoffeeAnswers, models.CoffeeAnswer{CoffeeID: coffeeID})
+	return &draft.CoffeeAnswers[len(draft.CoffeeAnswers)-1]
+}
+
+// draftMissing lists what a draft still lacks before it can be finalized: an
+// answer to every enabled question for every coffee of the case
+func draftMissing(draft *models.Draft, coffeeCase *models.CoffeeCase) fieldErrors {
+	errs := fieldErrors{}
+	if coffeeCase == nil {
+		return errs
+	}
+
+	answers := make(map[string]models.CoffeeAnswer, len(draft.CoffeeAnswers))
+	for _, answer := range draft.CoffeeAnswers {
+		answers[answer.CoffeeID] = answer
+	}
+
+	enabled := coffeeCase.EnabledQuestions
+	for _, coffee := range coffeeCase.Coffees {
+		answer := answers[coffee.ID]
+		delete(answers, coffee.ID)
+		for _, question := range []struct {
+			id      string
+			enabled bool
+			answer  string
+		}{
+			{scoring.QuestionRegion, enabled.Region, answer.Region},
+			{scoring.QuestionVariety, enabled.Variety, answer.Variety},
+			{scoring.QuestionProcess, enabled.Process, answer.Process},
+			{scoring.QuestionTasteNote1, enabled.TasteNote1, answer.TasteNote1},
+			{scoring.QuestionTasteNote2, enabled.TasteNote2, answer.TasteNote2},
+		} {
+			if question.enabled && question.answer == "" {
+				errs.add(fmt.Sprintf("coffee_answers[%s].%s", coffee.ID, question.id), "is required")
+			}
+		}
+	}
+	// Coffees removed from the case since they were answered
+	for coffeeID := range answers {
+		errs.add(fmt.Sprintf("coffee_answers[%s]", coffeeID), "is not a coffee of the case")
+	}
+
+	if enabled.FavoriteCoffee && draft.FavoriteCoffee == "" {
+		errs.add("favorite_coffee", "is required")
+	}
+	if enabled.BrewingMethod && draft.BrewingMethod == "" {
+		errs.add("brewing_method", "is required")
+	}
+	return errs
+}
+
+// GetDraft returns one of the user's drafts and what it still lacks
+func GetDraft(c *gin.Context) {
+	orderID := c.Param("order_id")
+	userID := c.GetString("userID")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Drafts the user cannot edit are reported as missing
+	var draft *models.Draft
+	var subErr *submissionError
+	order, team, err := loadOrder(ctx, database.DB, orderID, userID)
+	if err == nil {
+		draft, err = database.DB.Drafts().Get(ctx, draftID(order.OrderID, team, userID))
+	}
+	if errors.As(err, &subErr) || errors.Is(err, database.ErrNotFound) || (err == nil && !canEditDraft(draft, team, userID)) {
+		c.JSON(errDraftNotFound.Status, gin.H{"error": errDraftNotFound.Message, "code": errDraftNotFound.Code})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch draft", "details": err.Error()})
+		return
+	}
+
+	coffeeCase, err := database.DB.Cases().Get(ctx, draft.CaseID)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch case", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"draft":   draft,
+		"missing": draftMissing(draft, coffeeCase),
+	})
+}
+
+// GetUserDrafts returns the user's drafts, most recently saved first
+func GetUserDrafts(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	drafts, err := database.DB.Drafts().ListByUser(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch drafts", "details": err.Error()})
+		return
+	}
+	if drafts == nil {
+		drafts = []models.Draft{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"drafts": drafts,
+		"count":  len(drafts),
+	})
+}
+
+// FinalizeDraft submits a complete draft: it is scored like any submission
+// and can no longer be changed
+func FinalizeDraft(c *gin.Context) {
+	orderID := c.Param("order_id")
+	userID := c.GetString("userID")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	submission := models.Submission{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		OrderID:     orderID,
+		SubmittedAt: time.Now(),
+	}
+	var newBadges []models.UserBadge
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		order, team, err := loadOrder(ctx, tx, orderID, userID)
+		if err != nil {
+			return err
+		}
+		draft, err := tx.Drafts().Get(ctx, draftID(order.OrderID, team, userID))
+		if errors.Is(err, database.ErrNotFound) || (err == nil && !canEditDraft(draft, team, userID)) {
+			return errDraftNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if draft.FinalizedAt != nil {
+			return errDraftFinalized
+		}
+
+		coffeeCase, err := checkOrderOpen(ctx, tx, order, team, userID, submission.SubmittedAt)
+		if err != nil {
+			return err
+		}
+		if errs := draftMissing(draft, coffeeCase); len(errs) > 0 {
+			return &draftFieldsError{message: "Draft is incomplete", fields: errs}
+		}
+
+		// Recording the submission locks the draft
+		submission.CoffeeAnswers = draft.CoffeeAnswers
+		submission.FavoriteCoffee = draft.FavoriteCoffee
+		submission.BrewingMethod = draft.BrewingMethod
+		newBadges, err = recordSubmission(ctx, tx, &submission)
+		return err
+	})
+	var fieldsErr *draftFieldsError
+	if errors.As(err, &fieldsErr) {
+		respondInvalid(c, fieldsErr.message, fieldsErr.fields)
+		return
+	}
+	if err != nil {
+		respondSubmissionError(c, err)
+		return
+	}
+	respondSubmitted(ctx, c, &submission, newBadges)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"brew-detective-backend/internal/models"
+)
+
+func saveDraft(t *testing.T, userID, orderID string, fields map[string]interface{}) *httptest.ResponseRecorder {
+	return serve(t, userID, http.MethodPut, "/drafts/:order_id", "/drafts/"+orderID, fields, SaveDraft)
+}
+
+func finalizeDraft(t *testing.T, userID, orderID string) *httptest.ResponseRecorder {
+	return serve(t, userID, http.MethodPost, "/drafts/:order_id/finalize", "/drafts/"+orderID+"/finalize", nil, FinalizeDraft)
+}
+
+// draftResponse is the body of a draft save or read
+type draftResponse struct {
+	Draft   models.Draft      `json:"draft"`
+	Missing map[string]string `json:"missing"`
+	Fields  map[string]string `json:"fields"`
+}
+
+func coffeeFields(coffeeID string, fields ...string) map[string]interface{} {
+	answer := map[string]interface{}{"coffee_id": coffeeID}
+	for i := 0; i+1 < len(fields); i += 2 {
+		answer[fields[i]] = fields[i+1]
+	}
+	return map[string]interface{}{"coffee_answers": []interface{}{answer}}
+}
+
+func TestDraftSavedPerCoffee(t *testing.T) {
+	useMemoryStore(t)
+	seedCase(t, "case1")
+	seedUser(t, "player")
+	seedOrder(t, "ABC123", "player", "case1", models.OrderStatusDelivered)
+
+	if rec := saveDraft(t, "player", "ABC123", coffeeFields("c1", "region", "huila")); rec.Code != http.StatusOK {
+		t.Fatalf("first save got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec := saveDraft(t, "player", "ABC123", coffeeFields("c1", "process", "washed"))
+	var saved draftResponse
+	decode(t, rec, &saved)
+	if len(saved.Draft.CoffeeAnswers) != 1 || saved.Draft.CoffeeAnswers[0].Region != "huila" || saved.Draft.CoffeeAnswers[0].Process != "washed" {
+		t.Fatalf("saves were not merged: %+v", saved.Draft.CoffeeAnswers)
+	}
+	if len(saved.Missing) != 2 {
+		t.Errorf("missing = %v, want the two answers of c2", saved.Missing)
+	}
+
+	rec = saveDraft(t, "player", "ABC123", coffeeFields("c9", "region", "huila"))
+	var invalid draftResponse
+	decode(t, rec, &invalid)
+	if rec.Code != http.StatusBadRequest || invalid.Fields["coffee_answers[0].coffee_id"] == "" {
+		t.Errorf("unknown coffee got %d %v", rec.Code, invalid.Fields)
+	}
+
+	// Incomplete drafts cannot be finalized
+	rec = finalizeDraft(t, "player", "ABC123")
+	var incomplete draftResponse
+	decode(t, rec, &incomplete)
+	if rec.Code != http.StatusBadRequest || len(incomplete.Fields) != 2 {
+		t.Fatalf("finalizing an incomplete draft got %d %v", rec.Code, incomplete.Fields)
+	}
+
+	saveDraft(t, "player", "ABC123", coffeeFields("c2", "region", "narino", "process", "natural"))
+	if rec := finalizeDraft(t, "player", "ABC123"); rec.Code != http.StatusCreated {
+		t.Fatalf("finalizing got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// The draft is locked and the order is used
+	rec = saveDraft(t, "player", "ABC123", coffeeFields("c1", "region", "cauca"))
+	if code := errorCode(t, rec); code != errDraftFinalized.Code {
+		t.Errorf("saving a finalized draft got %d %q", rec.Code, code)
+	}
+	if rec := finalizeDraft(t, "player", "ABC123"); errorCode(t, rec) != errDraftFinalized.Code {
+		t.Errorf("finalizing twice got %d", rec.Code)
+	}
+	rec = serve(t, "player", http.MethodGet, "/drafts/:order_id", "/drafts/ABC123", nil, GetDraft)
+	var finalized draftResponse
+	decode(t, rec, &finalized)
+	if finalized.Draft.FinalizedAt == nil || finalized.Draft.SubmissionID == "" {
+		t.Errorf("draft was not locked by its submission: %+v", finalized.Draft)
+	}
+}
+
+func TestDraftBelongsToItsAuthor(t *testing.T) {
+	useMemoryStore(t)
+	seedCase(t, "case1")
+	seedUser(t, "player")
+	seedUser(t, "other")
+	seedOrder(t, "ABC123", "player", "case1", models.OrderStatusDelivered)
+
+	saveDraft(t, "player", "ABC123", coffeeFields("c1", "region", "huila"))
+
+	if rec := serve(t, "other", http.MethodGet, "/drafts/:order_id", "/drafts/ABC123", nil, GetDraft); rec.Code != http.StatusNotFound {
+		t.Errorf("reading another user's draft got %d", rec.Code)
+	}
+	if rec := saveDraft(t, "other", "ABC123", coffeeFields("c1", "region", "cauca")); errorCode(t, rec) != errDraftInProgress.Code {
+		t.Errorf("saving over another user's draft got %d", rec.Code)
+	}
+	if status, code := submit(t, "other", "ABC123", answers(true)); code != errDraftInProgress.Code {
+		t.Errorf("submitting an order another user is drafting got %d %q", status, code)
+	}
+	if rec := finalizeDraft(t, "other", "ABC123"); errorCode(t, rec) != errDraftNotFound.Code {
+		t.Errorf("finalizing another user's draft got %d", rec.Code)
+	}
+}
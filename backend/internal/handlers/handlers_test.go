@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"brew-detective-backend/internal/catalog"
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+	"brew-detective-backend/internal/roles"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// useMemoryStore points the handlers at an empty in-memory store
+func useMemoryStore(t *testing.T) {
+	t.Helper()
+	database.DB = database.NewMemoryStore()
+	catalog.Invalidate()
+}
+
+// serve sends a request to a router with a single route, signed in as userID
+// unless it is empty. body is encoded as JSON unless it is nil.
+func serve(t *testing.T, userID, method, route, path string, body interface{}, chain ...gin.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+
+	signIn := func(c *gin.Context) {
+		if userID != "" {
+			c.Set("userID", userID)
+		}
+	}
+	router := gin.New()
+	router.Handle(method, route, append([]gin.HandlerFunc{signIn}, chain...)...)
+
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatalf("encoding request body: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// decode reads a JSON response body into dst
+func decode(t *testing.T, rec *httptest.ResponseRecorder, dst interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), dst); err != nil {
+		t.Fatalf("decoding response %q: %v", rec.Body.String(), err)
+	}
+}
+
+// errorCode returns the code of a player-facing error response
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body struct {
+		Code string `json:"code"`
+	}
+	decode(t, rec, &body)
+	return body.Code
+}
+
+func seedUser(t *testing.T, id string, userRoles ...string) *models.User {
+	t.Helper()
+	user := &models.User{ID: id, Name: "Detective " + id, Type: "regular", Roles: userRoles, CreatedAt: time.Now()}
+	if err := database.DB.Users().Save(context.Background(), user); err != nil {
+		t.Fatalf("saving user: %v", err)
+	}
+	// Roles cached by an earlier test must not leak into this one
+	roles.Invalidate(id)
+	return user
+}
+
+// seedCase saves an open case of two coffees asking for region and process
+func seedCase(t *testing.T, id string) *models.CoffeeCase {
+	t.Helper()
+	coffeeCase := &models.CoffeeCase{
+		ID:          id,
+		Name:        "Caso " + id,
+		Description: "Dos cafés de Colombia",
+		Coffees: []models.CoffeeItem{
+			{ID: "c1", Region: "huila", Process: "washed"},
+			{ID: "c2", Region: "narino", Process: "natural"},
+		},
+		EnabledQuestions: models.EnabledQuestions{Region: true, Process: true},
+		IsActive:         true,
+		CreatedAt:        time.Now(),
+	}
+	if err := database.DB.Cases().Save(context.Background(), coffeeCase); err != nil {
+		t.Fatalf("saving case: %v", err)
+	}
+	return coffeeCase
+}
+
+func seedOrder(t *testing.T, code, userID, caseID, status string) *models.Order {
+	t.Helper()
+	order := &models.Order{
+		ID:        "order-" + code,
+		OrderID:   code,
+		UserID:    userID,
+		CaseID:    caseID,
+		Status:    status,
+		CreatedAt: time.Now(),
+	}
+	if err := database.DB.Orders().Save(context.Background(), order); err != nil {
+		t.Fatalf("saving order: %v", err)
+	}
+	return order
+}
+
+// answers answers both coffees of a seeded case, all correctly when right is set
+func answers(right bool) []models.CoffeeAnswer {
+	if !right {
+		return []models.CoffeeAnswer{
+			{CoffeeID: "c1", Region: "cauca", Process: "honey"},
+			{CoffeeID: "c2", Region: "cauca", Process: "honey"},
+		}
+	}
+	return []models.CoffeeAnswer{
+		{CoffeeID: "c1", Region: "huila", Process: "washed"},
+		{CoffeeID: "c2", Region: "narino", Process: "natural"},
+	}
+}
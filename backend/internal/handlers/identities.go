@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"brew-detective-backend/internal/auth"
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetIdentities returns the identities the current user can log in with
+func GetIdentities(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	identities, err := database.DB.Identities().ListByUser(ctx, c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch identities"})
+		return
+	}
+	if identities == nil {
+		identities = []models.Identity{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"identities": identities})
+}
+
+// LinkIdentity starts linking another identity to the current user. For an
+// OAuth provider it returns the path the browser must open; for email it
+// sends a link to the address in the body.
+func LinkIdentity(c *gin.Context) {
+	providerName := c.Param("provider")
+	userID := c.GetString("userID")
+
+	if providerName == auth.EmailProvider {
+		var req struct {
+			Email string `json:"email" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Email is required"})
+			return
+		}
+		sendLoginLink(c, req.Email, userID)
+		return
+	}
+
+	if _, err := auth.GetProvider(providerName); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	token, err := auth.CreateLinkToken(ctx, providerName, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start linking"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"auth_path": "/auth/" + providerName + "?redirect=true&link=" + url.QueryEscape(token),
+	})
+}
+
+// UnlinkIdentity removes one of the current user's identities
+func UnlinkIdentity(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := auth.UnlinkIdentity(ctx, c.GetString("userID"), c.Param("id"))
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Identity not found"})
+		return
+	}
+	if errors.Is(err, auth.ErrLastIdentity) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot unlink your only login method"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink identity"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Identity unlinked successfully"})
+}
@@ -3,85 +3,88 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"os"
-	"sort"
+	"strconv"
 	"time"
 
+	"brew-detective-backend/internal/auth"
 	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/leaderboard"
 	"brew-detective-backend/internal/models"
+	"brew-detective-backend/internal/roles"
 
-	"cloud.google.com/go/firestore"
 	"github.com/gin-gonic/gin"
 	"google.golang.org/api/iterator"
 )
 
 // GetLeaderboard returns the global/historical leaderboard ranked by total points across all cases
 func GetLeaderboard(c *gin.Context) {
+	respondWithLeaderboard(c, leaderboard.GlobalBoard, gin.H{})
+}
+
+// respondWithLeaderboard serves a page of a board. Pages are selected with
+// ?limit= and ?cursor=, and authenticated users also get their own rank.
+func respondWithLeaderboard(c *gin.Context, board string, response gin.H) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// First, try to get all users (remove the where clause since fields might not exist yet)
-	iter := database.FirestoreClient.Collection(database.UsersCollection).Documents(ctx)
+	page, err := leaderboard.GetPage(ctx, database.DB, board, c.Query("cursor"), leaderboardLimit(c))
+	if errors.Is(err, leaderboard.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch leaderboard",
+			"details": err.Error(),
+		})
+		return
+	}
 
-	var entries []models.LeaderboardEntryWithUser
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to fetch leaderboard", 
-				"details": err.Error(),
-			})
-			return
-		}
+	response["leaderboard"] = page.Entries
+	response["total_users"] = page.Total
+	response["next_cursor"] = page.NextCursor
 
-		var user models.User
-		if err := doc.DataTo(&user); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to parse user data", 
-				"details": err.Error(),
-				"document_id": doc.Ref.ID,
-			})
+	if userID, exists := c.Get("userID"); exists {
+		myRank, err := leaderboard.RankOf(ctx, database.DB, board, userID.(string))
+		if err != nil && !errors.Is(err, database.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leaderboard", "details": err.Error()})
 			return
 		}
-
-		// Only include users who have attempted at least one case
-		if user.CasesAttempted > 0 || user.CasesSolved > 0 || user.Points > 0 {
-			entry := models.LeaderboardEntryWithUser{
-				UserID:        user.ID,
-				DetectiveName: user.Name,
-				Points:        user.Points, // Use Points field for total accumulated points
-				Accuracy:      user.Accuracy,
-				CasesCount:    user.CasesCount, // Use CasesCount for total cases solved
-				Badges:        user.Badges,
-			}
-			entries = append(entries, entry)
-		}
+		// Unranked users get a null rank
+		response["my_rank"] = myRank
 	}
 
-	// Sort by points (descending)
-	sort.Slice(entries, func(i, j int) bool {
-		return entries[i].Points > entries[j].Points
-	})
+	c.JSON(http.StatusOK, response)
+}
 
-	// Assign ranks
-	for i := range entries {
-		entries[i].Rank = i + 1
+// leaderboardLimit returns the page size requested with ?limit=
+func leaderboardLimit(c *gin.Context) int {
+	limit := 50 // Default limit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
 	}
+	return limit
+}
+
+// RebuildLeaderboard recomputes every standing from user stats and submissions (admin only)
+func RebuildLeaderboard(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
 
-	// Limit to top 50
-	if len(entries) > 50 {
-		entries = entries[:50]
+	rebuilt, err := leaderboard.Rebuild(ctx, database.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rebuild leaderboard", "details": err.Error()})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"leaderboard": entries,
-		"total_users": len(entries),
-	})
+	c.JSON(http.StatusOK, gin.H{"message": "Leaderboard rebuilt", "users_ranked": rebuilt})
 }
 
 // TestFirestore tests if Firestore connection is working
@@ -131,6 +134,14 @@ func TestFirestore(c *gin.Context) {
 		}
 	}
 
+	// The in-memory backend has no Firestore connection to test
+	if database.FirestoreClient == nil {
+		response["status"] = "not_configured"
+		response["storage_backend"] = database.BackendMemory
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
 	// Try to list collections
 	collections := database.FirestoreClient.Collections(ctx)
 	var collectionNames []string
@@ -164,37 +175,75 @@ func TestFirestore(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// GetUserProfile returns user profile information
+// GetUserProfile returns a user's full profile to the user themselves or staff who can read users
 func GetUserProfile(c *gin.Context) {
 	userID := c.Param("id")
-	
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	doc, err := database.FirestoreClient.Collection(database.UsersCollection).Doc(userID).Get(ctx)
+	if userID != c.GetString("userID") && !auth.HasPermission(c, roles.UsersRead) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only view your own profile"})
+		return
+	}
+
+	user, err := database.DB.Users().Get(ctx, userID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
-	var user models.User
-	if err := doc.DataTo(&user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse user data"})
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+// GetPublicUserProfile returns the public view of a user's profile
+func GetPublicUserProfile(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := database.DB.Users().Get(ctx, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"user": user})
+	c.JSON(http.StatusOK, gin.H{"profile": publicProfile(user)})
 }
 
-// UpdateUserProfile updates user profile information
+// publicProfile builds the profile other users see, honoring the user's privacy settings
+func publicProfile(user *models.User) models.PublicProfile {
+	profile := models.PublicProfile{
+		ID:      user.ID,
+		Name:    user.Name,
+		Picture: user.Picture,
+		Badges:  user.Badges,
+	}
+	if profile.Badges == nil {
+		profile.Badges = []models.UserBadge{}
+	}
+	if !user.Privacy.HideStats {
+		profile.Stats = &models.PublicStats{
+			Points:     user.Points,
+			CasesCount: user.CasesCount,
+			Accuracy:   user.Accuracy,
+		}
+	}
+	return profile
+}
+
+// UpdateUserProfile updates user profile information (the user themselves or staff who can manage users).
+// The email is not editable: it comes from the identities the user logs in with.
 func UpdateUserProfile(c *gin.Context) {
 	userID := c.Param("id")
-	
+
 	var updates struct {
-		Name  string `json:"name"`
-		Email string `json:"email"`
+		Name    string `json:"name"`
+		Privacy *struct {
+			HideFromLeaderboards *bool `json:"hide_from_leaderboards"`
+			HideStats            *bool `json:"hide_stats"`
+		} `json:"privacy"`
 	}
-	
+
 	if err := c.ShouldBindJSON(&updates); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid update data"})
 		return
@@ -203,18 +252,17 @@ func UpdateUserProfile(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	userRef := database.FirestoreClient.Collection(database.UsersCollection).Doc(userID)
-	
-	// Check if user exists
-	doc, err := userRef.Get(ctx)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	if userID != c.GetString("userID") && !auth.HasPermission(c, roles.UsersManage) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only update your own profile"})
 		return
 	}
 
-	var user models.User
-	if err := doc.DataTo(&user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse user data"})
+	users := database.DB.Users()
+
+	// Check if user exists
+	user, err := users.Get(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
@@ -222,17 +270,35 @@ func UpdateUserProfile(c *gin.Context) {
 	if updates.Name != "" {
 		user.Name = updates.Name
 	}
-	if updates.Email != "" {
-		user.Email = updates.Email
+	visibilityChanged := false
+	if updates.Privacy != nil {
+		if updates.Privacy.HideFromLeaderboards != nil {
+			visibilityChanged = *updates.Privacy.HideFromLeaderboards != user.Privacy.HideFromLeaderboards
+			user.Privacy.HideFromLeaderboards = *updates.Privacy.HideFromLeaderboards
+		}
+		if updates.Privacy.HideStats != nil {
+			user.Privacy.HideStats = *updates.Privacy.HideStats
+		}
 	}
 	user.UpdatedAt = time.Now()
 
 	// Save updated user
-	if _, err := userRef.Set(ctx, user); err != nil {
+	if err := users.Save(ctx, user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
 		return
 	}
 
+	// Keep the name shown on leaderboards current, adding or removing the
+	// user's standings when they change their leaderboard visibility
+	if visibilityChanged {
+		err = leaderboard.RebuildUser(ctx, database.DB, user)
+	} else {
+		err = leaderboard.SyncUser(ctx, database.DB, user)
+	}
+	if err != nil {
+		log.Printf("Failed to sync standings for user %s: %v", user.ID, err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Profile updated successfully", "user": user})
 }
 
@@ -242,33 +308,21 @@ func GetAllUsers(c *gin.Context) {
 	defer cancel()
 
 	// Query all users ordered by name
-	query := database.FirestoreClient.Collection(database.UsersCollection).
-		OrderBy("name", firestore.Asc)
-
-	iter := query.Documents(ctx)
+	allUsers, err := database.DB.Users().List(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users", "details": err.Error()})
+		return
+	}
 
 	var users []map[string]interface{}
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users", "details": err.Error()})
-			return
-		}
-
-		var user models.User
-		if err := doc.DataTo(&user); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse user data"})
-			return
-		}
+	for _, user := range allUsers {
 
 		// Create response object with basic user info
 		userResponse := map[string]interface{}{
 			"id":    user.ID,
 			"name":  user.Name,
 			"email": user.Email,
+			"roles": roles.Of(&user),
 		}
 
 		users = append(users, userResponse)
@@ -280,133 +334,44 @@ func GetAllUsers(c *gin.Context) {
 	})
 }
 
-// getCurrentActiveCase gets the current active case (helper function)
-func getCurrentActiveCase() (*models.CoffeeCase, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	iter := database.FirestoreClient.Collection(database.CasesCollection).
-		Where("is_active", "==", true).
-		Limit(1).
-		Documents(ctx)
-
-	doc, err := iter.Next()
-	if err == iterator.Done {
-		return nil, err
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	var coffeeCase models.CoffeeCase
-	if err := doc.DataTo(&coffeeCase); err != nil {
-		return nil, err
-	}
-
-	return &coffeeCase, nil
-}
-
 // GetCurrentCaseLeaderboard returns the leaderboard for the current active case only
 func GetCurrentCaseLeaderboard(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
 	// Get the current active case
-	activeCase, err := getCurrentActiveCase()
+	activeCase, err := getActiveCase()
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "No active case found"})
 		return
 	}
 
-	// Get submissions for the current active case only
-	iter := database.FirestoreClient.Collection(database.SubmissionsCollection).
-		Where("case_id", "==", activeCase.ID).
-		Documents(ctx)
-
-	// Map to store user scores for current case
-	userScores := make(map[string]models.LeaderboardEntryWithUser)
-	
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to fetch submissions", 
-				"details": err.Error(),
-			})
-			return
-		}
-
-		var submission models.Submission
-		if err := doc.DataTo(&submission); err != nil {
-			continue // Skip invalid submissions
-		}
-
-		// Get or initialize user entry
-		entry, exists := userScores[submission.UserID]
-		if !exists {
-			// Get user details
-			userRef := database.FirestoreClient.Collection(database.UsersCollection).Doc(submission.UserID)
-			userDoc, err := userRef.Get(ctx)
-			if err != nil {
-				continue // Skip if user not found
-			}
-			
-			var user models.User
-			if err := userDoc.DataTo(&user); err != nil {
-				continue
-			}
-
-			entry = models.LeaderboardEntryWithUser{
-				UserID:        submission.UserID,
-				DetectiveName: user.Name,
-				Points:        0,
-				Accuracy:      0,
-				CasesCount:    0,
-				Badges:        user.Badges,
-			}
-		}
-
-		// Update with best score for this case (keep highest score)
-		if submission.Score > entry.Points {
-			entry.Points = submission.Score
-			entry.Accuracy = submission.Accuracy
-		}
-		entry.CasesCount = 1 // For current case, this is always 1
-		
-		userScores[submission.UserID] = entry
-	}
+	respondWithLeaderboard(c, leaderboard.CaseBoard(activeCase.ID), gin.H{
+		"case_id":   activeCase.ID,
+		"case_name": activeCase.Name,
+	})
+}
 
-	// Convert map to slice
-	var entries []models.LeaderboardEntryWithUser
-	for _, entry := range userScores {
-		entries = append(entries, entry)
-	}
+// GetCaseLeaderboard returns the leaderboard of any case. Once a closed case is
+// revealed its standings are frozen into a final snapshot.
+func GetCaseLeaderboard(c *gin.Context) {
+	caseID := c.Param("id")
 
-	// Sort by points (descending), then by accuracy for ties
-	sort.Slice(entries, func(i, j int) bool {
-		if entries[i].Points == entries[j].Points {
-			return entries[i].Accuracy > entries[j].Accuracy
-		}
-		return entries[i].Points > entries[j].Points
-	})
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
-	// Assign ranks
-	for i := range entries {
-		entries[i].Rank = i + 1
+	coffeeCase, err := database.DB.Cases().Get(ctx, caseID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Case not found"})
+		return
 	}
 
-	// Limit to top 50
-	if len(entries) > 50 {
-		entries = entries[:50]
+	board := leaderboard.CaseBoard(coffeeCase.ID)
+	if coffeeCase.FinalizedAt != nil {
+		board = leaderboard.FinalCaseBoard(coffeeCase.ID)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"leaderboard": entries,
-		"total_users": len(entries),
-		"case_id": activeCase.ID,
-		"case_name": activeCase.Name,
+	respondWithLeaderboard(c, board, gin.H{
+		"case_id":      coffeeCase.ID,
+		"case_name":    coffeeCase.Name,
+		"final":        coffeeCase.FinalizedAt != nil,
+		"finalized_at": coffeeCase.FinalizedAt,
 	})
-}
\ No newline at end of file
+}
@@ -2,115 +2,224 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
+	"brew-detective-backend/internal/audit"
+	"brew-detective-backend/internal/auth"
 	"brew-detective-backend/internal/database"
 	"brew-detective-backend/internal/models"
+	"brew-detective-backend/internal/orders"
+	"brew-detective-backend/internal/roles"
 	"brew-detective-backend/internal/utils"
 
-	"cloud.google.com/go/firestore"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"google.golang.org/api/iterator"
 )
 
-// CreateOrder creates a new coffee case order
+// CreateOrder creates a new coffee case order for the authenticated user. Staff
+// who manage orders can order on behalf of another user. The total is always the case price.
 func CreateOrder(c *gin.Context) {
-	var order models.Order
-	
-	if err := c.ShouldBindJSON(&order); err != nil {
+	var request struct {
+		CaseID      string `json:"case_id"`
+		ContactInfo string `json:"contact_info"`
+		UserID      string `json:"user_id"` // Staff who manage orders only
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order data"})
 		return
 	}
-
-	// Generate order ID and set timestamps
-	order.ID = uuid.New().String()
-	order.OrderID = utils.GenerateOrderID() // Generate 6-character order ID
-	order.Status = "pending"
-	order.IsSubmissionUsed = false
-	order.CreatedAt = time.Now()
-	order.UpdatedAt = time.Now()
+	if request.CaseID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Case ID is required"})
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Save order to Firestore
-	_, err := database.FirestoreClient.Collection(database.OrdersCollection).
-		Doc(order.ID).Set(ctx, order)
+	staff := auth.HasPermission(c, roles.OrdersManage)
+	userID := c.GetString("userID")
+	if request.UserID != "" && request.UserID != userID {
+		if !staff {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only create orders for yourself"})
+			return
+		}
+		if _, err := database.DB.Users().Get(ctx, request.UserID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+			return
+		}
+		userID = request.UserID
+	}
+
+	coffeeCase, err := database.DB.Cases().Get(ctx, request.CaseID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Case not found"})
+		return
+	}
+	if !coffeeCase.IsActive && !staff {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This case is not available for ordering"})
+		return
+	}
+
+	// Generate order ID and set timestamps
+	now := time.Now()
+	order := models.Order{
+		ID:          uuid.New().String(),
+		OrderID:     utils.GenerateOrderID(), // Generate 6-character order ID
+		UserID:      userID,
+		CaseID:      coffeeCase.ID,
+		ContactInfo: request.ContactInfo,
+		TotalAmount: coffeeCase.Price,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	orders.Create(&order, c.GetString("userID"), now)
+
+	// Save order with its audit entry
+	err = database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		if err := tx.Orders().Save(ctx, &order); err != nil {
+			return err
+		}
+		return audit.Write(ctx, tx, c, "order.create", audit.Orders, order.ID, nil, &order)
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order"})
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "Order created successfully",
-		"order_id": order.ID,
+		"message":           "Order created successfully",
+		"order_id":          order.ID,
 		"customer_order_id": order.OrderID, // This is the 6-character ID for customers
-		"status": order.Status,
+		"status":            order.Status,
+		"total_amount":      order.TotalAmount,
 	})
 }
 
-// GetOrder returns a specific order
+// GetOrder returns a specific order to its owner or staff who can read orders
 func GetOrder(c *gin.Context) {
 	orderID := c.Param("id")
-	
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	doc, err := database.FirestoreClient.Collection(database.OrdersCollection).Doc(orderID).Get(ctx)
-	if err != nil {
+	order, err := database.DB.Orders().Get(ctx, orderID)
+	// Other users' orders are reported as missing so their IDs cannot be probed
+	if err != nil || (order.UserID != c.GetString("userID") && !auth.HasPermission(c, roles.OrdersRead)) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
 		return
 	}
 
-	var order models.Order
-	if err := doc.DataTo(&order); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse order data"})
+	c.JSON(http.StatusOK, gin.H{"order": order})
+}
+
+// CancelOrder lets a user cancel their own order while it is still pending
+func CancelOrder(c *gin.Context) {
+	orderID := c.Param("id")
+	userID := c.GetString("userID")
+
+	var request struct {
+		Note string `json:"note"`
+	}
+	// The note is optional, so an empty body is fine
+	_ = c.ShouldBindJSON(&request)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var order *models.Order
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		var err error
+		order, err = tx.Orders().Get(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		if order.UserID != userID {
+			return database.ErrNotFound
+		}
+		if order.Status != models.OrderStatusPending {
+			return orders.ErrInvalidTransition
+		}
+		before := *order
+		if err := orders.Transition(order, models.OrderStatusCancelled, userID, request.Note, time.Now()); err != nil {
+			return err
+		}
+		if err := tx.Orders().Save(ctx, order); err != nil {
+			return err
+		}
+		return audit.Write(ctx, tx, c, "order.cancel", audit.Orders, order.ID, &before, order)
+	})
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+	if errors.Is(err, orders.ErrInvalidTransition) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only pending orders can be cancelled"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel order"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"order": order})
+	c.JSON(http.StatusOK, gin.H{"message": "Order cancelled successfully", "order": order})
 }
 
-// UpdateOrderStatus updates the status of an order
+// UpdateOrderStatus moves an order to a new status, recording the change in its history (admin only)
 func UpdateOrderStatus(c *gin.Context) {
 	orderID := c.Param("id")
-	
+
 	var updates struct {
 		Status string `json:"status"`
+		Note   string `json:"note"`
 	}
-	
+
 	if err := c.ShouldBindJSON(&updates); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status data"})
 		return
 	}
+	if !orders.IsValidStatus(updates.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	orderRef := database.FirestoreClient.Collection(database.OrdersCollection).Doc(orderID)
-	
-	// Check if order exists
-	doc, err := orderRef.Get(ctx)
-	if err != nil {
+	// Read and write in one transaction so concurrent changes cannot skip a
+	// transition, and so that the change is never saved without its audit entry
+	var order *models.Order
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		var err error
+		order, err = tx.Orders().Get(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		before := *order
+		if err := orders.Transition(order, updates.Status, c.GetString("userID"), updates.Note, time.Now()); err != nil {
+			return err
+		}
+		if err := tx.Orders().Save(ctx, order); err != nil {
+			return err
+		}
+		return audit.Write(ctx, tx, c, "order.status_change", audit.Orders, order.ID, &before, order)
+	})
+	if errors.Is(err, database.ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
 		return
 	}
-
-	var order models.Order
-	if err := doc.DataTo(&order); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse order data"})
+	if errors.Is(err, orders.ErrInvalidTransition) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Invalid status transition",
+			"details": err.Error(),
+			"allowed": orders.NextStatuses(order.Status),
+		})
 		return
 	}
-
-	// Update status
-	order.Status = updates.Status
-	order.UpdatedAt = time.Now()
-
-	// Save updated order
-	if _, err := orderRef.Set(ctx, order); err != nil {
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order"})
 		return
 	}
@@ -118,6 +227,31 @@ func UpdateOrderStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Order status updated successfully", "order": order})
 }
 
+// GetOrderHistory returns the status history of an order (admin only)
+func GetOrderHistory(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	order, err := database.DB.Orders().Get(ctx, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	history := order.StatusHistory
+	if history == nil {
+		history = []models.OrderStatusChange{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":       order.ID,
+		"order_id": order.OrderID,
+		"status":   order.Status,
+		"allowed":  orders.NextStatuses(order.Status),
+		"history":  history,
+	})
+}
+
 // GetAllOrders returns all orders with pagination (admin only)
 func GetAllOrders(c *gin.Context) {
 	// Get query parameters for pagination
@@ -142,51 +276,27 @@ func GetAllOrders(c *gin.Context) {
 	defer cancel()
 
 	// Query all orders ordered by creation date (newest first)
-	query := database.FirestoreClient.Collection(database.OrdersCollection).
-		OrderBy("created_at", firestore.Desc).
-		Limit(limit).
-		Offset(offset)
-
-	iter := query.Documents(ctx)
+	allOrders, err := database.DB.Orders().List(ctx, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch orders", "details": err.Error()})
+		return
+	}
 
 	var orders []map[string]interface{}
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch orders", "details": err.Error()})
-			return
-		}
-
-		var order models.Order
-		if err := doc.DataTo(&order); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse order data"})
-			return
-		}
-
+	for _, order := range allOrders {
 		// Get user name for the order
 		var userName string
 		if order.UserID != "" {
-			userDoc, err := database.FirestoreClient.Collection(database.UsersCollection).Doc(order.UserID).Get(ctx)
-			if err == nil && userDoc.Exists() {
-				var user models.User
-				if err := userDoc.DataTo(&user); err == nil {
-					userName = user.Name
-				}
+			if user, err := database.DB.Users().Get(ctx, order.UserID); err == nil {
+				userName = user.Name
 			}
 		}
 
 		// Get case name for the order
 		var caseName string
 		if order.CaseID != "" {
-			caseDoc, err := database.FirestoreClient.Collection(database.CasesCollection).Doc(order.CaseID).Get(ctx)
-			if err == nil && caseDoc.Exists() {
-				var coffeeCase models.CoffeeCase
-				if err := caseDoc.DataTo(&coffeeCase); err == nil {
-					caseName = coffeeCase.Name
-				}
+			if coffeeCase, err := database.DB.Cases().Get(ctx, order.CaseID); err == nil {
+				caseName = coffeeCase.Name
 			}
 		}
 
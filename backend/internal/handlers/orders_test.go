@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"brew-detective-backend/internal/auth"
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+	"brew-detective-backend/internal/orders"
+	"brew-detective-backend/internal/roles"
+)
+
+func setStatus(t *testing.T, userID, id, status string) int {
+	return serve(t, userID, http.MethodPut, "/admin/orders/:id/status", "/admin/orders/"+id+"/status",
+		map[string]string{"status": status, "note": "test"},
+		auth.RequirePermission(roles.OrdersManage), UpdateOrderStatus).Code
+}
+
+func orderAudit(t *testing.T, orderID string) []models.AuditEntry {
+	t.Helper()
+	entries, err := database.DB.Audit().Query(context.Background(), database.AuditFilter{TargetType: "order", TargetID: orderID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestUpdateOrderStatusFollowsLifecycle(t *testing.T) {
+	useMemoryStore(t)
+	seedUser(t, "staff", roles.Fulfillment)
+	order := seedOrder(t, "ABC123", "buyer", "case1", models.OrderStatusPending)
+
+	for _, status := range []string{models.OrderStatusConfirmed, models.OrderStatusShipped, models.OrderStatusDelivered} {
+		if code := setStatus(t, "staff", order.ID, status); code != http.StatusOK {
+			t.Fatalf("moving to %s got %d", status, code)
+		}
+	}
+
+	rec := serve(t, "staff", http.MethodPut, "/admin/orders/:id/status", "/admin/orders/"+order.ID+"/status",
+		map[string]string{"status": models.OrderStatusPending}, UpdateOrderStatus)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("moving a delivered order back to pending got %d", rec.Code)
+	}
+	var conflict struct {
+		Allowed []string `json:"allowed"`
+	}
+	decode(t, rec, &conflict)
+	if len(conflict.Allowed) != 1 || conflict.Allowed[0] != models.OrderStatusRefunded {
+		t.Errorf("allowed = %v, want [%s]", conflict.Allowed, models.OrderStatusRefunded)
+	}
+
+	saved, err := database.DB.Orders().Get(context.Background(), order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Status != models.OrderStatusDelivered || len(saved.StatusHistory) != 3 {
+		t.Fatalf("order is %s with %d changes, want delivered with 3", saved.Status, len(saved.StatusHistory))
+	}
+	for _, change := range saved.StatusHistory {
+		if change.Actor != "staff" || change.Note != "test" {
+			t.Errorf("change %+v was not recorded with its actor and note", change)
+		}
+	}
+
+	// Each change, and only each successful one, has its audit entry
+	entries := orderAudit(t, order.ID)
+	if len(entries) != 3 {
+		t.Fatalf("got %d audit entries, want 3", len(entries))
+	}
+	latest := entries[0]
+	if latest.Action != "order.status_change" || latest.Actor != "staff" {
+		t.Errorf("latest entry is %s by %s", latest.Action, latest.Actor)
+	}
+	change, ok := latest.Changes["status"]
+	if !ok || change.Before != models.OrderStatusShipped || change.After != models.OrderStatusDelivered {
+		t.Errorf("status change recorded as %+v", change)
+	}
+}
+
+func TestUpdateOrderStatusRequiresPermission(t *testing.T) {
+	useMemoryStore(t)
+	seedUser(t, "curator", roles.CaseCurator)
+	order := seedOrder(t, "ABC123", "buyer", "case1", models.OrderStatusPending)
+
+	if code := setStatus(t, "curator", order.ID, models.OrderStatusConfirmed); code != http.StatusForbidden {
+		t.Errorf("case curator got %d, want %d", code, http.StatusForbidden)
+	}
+	if entries := orderAudit(t, order.ID); len(entries) != 0 {
+		t.Errorf("a refused change was audited: %+v", entries)
+	}
+}
+
+func TestCancelOrder(t *testing.T) {
+	useMemoryStore(t)
+	pending := seedOrder(t, "ABC123", "buyer", "case1", models.OrderStatusPending)
+	shipped := seedOrder(t, "DEF456", "buyer", "case1", models.OrderStatusShipped)
+
+	cancelOrder := func(userID, id string) int {
+		return serve(t, userID, http.MethodPost, "/orders/:id/cancel", "/orders/"+id+"/cancel", nil, CancelOrder).Code
+	}
+
+	if code := cancelOrder("someone", pending.ID); code != http.StatusNotFound {
+		t.Errorf("cancelling another user's order got %d, want %d", code, http.StatusNotFound)
+	}
+	if code := cancelOrder("buyer", shipped.ID); code != http.StatusConflict {
+		t.Errorf("cancelling a shipped order got %d, want %d", code, http.StatusConflict)
+	}
+	if code := cancelOrder("buyer", pending.ID); code != http.StatusOK {
+		t.Fatalf("cancelling a pending order got %d", code)
+	}
+
+	saved, err := database.DB.Orders().Get(context.Background(), pending.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Status != models.OrderStatusCancelled || !orders.CanTransition(saved.Status, models.OrderStatusRefunded) {
+		t.Errorf("cancelled order is %s", saved.Status)
+	}
+	entries := orderAudit(t, pending.ID)
+	if len(entries) != 1 || entries[0].Action != "order.cancel" || entries[0].Actor != "buyer" {
+		t.Errorf("cancellation audited as %+v", entries)
+	}
+}
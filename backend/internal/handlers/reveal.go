@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+	"brew-detective-backend/internal/schedule"
+	"brew-detective-backend/internal/scoring"
+
+	"github.com/gin-gonic/gin"
+)
+
+// buildFeedback annotates a submission for its player. Until the case's
+// results are revealed it only echoes the player's answers, since the grading
+// would give the correct answers away.
+func buildFeedback(submission *models.Submission, coffeeCase *models.CoffeeCase) models.SubmissionFeedback {
+	feedback := models.SubmissionFeedback{
+		ID:             submission.ID,
+		CaseID:         submission.CaseID,
+		OrderID:        submission.OrderID,
+		Score:          submission.Score,
+		Accuracy:       submission.Accuracy,
+		Revealed:       coffeeCase != nil && coffeeCase.ResultsRevealedAt != nil,
+		Coffees:        make([]models.CoffeeFeedback, 0, len(submission.CoffeeAnswers)),
+		FavoriteCoffee: submission.FavoriteCoffee,
+		BrewingMethod:  submission.BrewingMethod,
+		SubmittedAt:    submission.SubmittedAt,
+	}
+	for _, answer := range submission.CoffeeAnswers {
+		if !feedback.Revealed {
+			answer.Points = 0
+		}
+		feedback.Coffees = append(feedback.Coffees, models.CoffeeFeedback{CoffeeID: answer.CoffeeID, Answer: answer})
+	}
+	if !feedback.Revealed {
+		return feedback
+	}
+
+	coffees := make(map[string]models.CoffeeItem, len(coffeeCase.Coffees))
+	for _, coffee := range coffeeCase.Coffees {
+		coffees[coffee.ID] = coffee
+	}
+	breakdown := submissionBreakdown(submission, coffeeCase)
+	byCoffee := make(map[string][]models.QuestionScore)
+	for _, score := range breakdown {
+		if score.CoffeeID == "" {
+			feedback.Bonuses = append(feedback.Bonuses, score)
+			continue
+		}
+		byCoffee[score.CoffeeID] = append(byCoffee[score.CoffeeID], score)
+	}
+
+	points := scoring.CoffeePoints(breakdown)
+	for i := range feedback.Coffees {
+		coffeeFeedback := &feedback.Coffees[i]
+		if coffee, ok := coffees[coffeeFeedback.CoffeeID]; ok {
+			coffeeFeedback.Coffee = &coffee
+		}
+		coffeePoints := points[coffeeFeedback.CoffeeID]
+		coffeeFeedback.Points = &coffeePoints
+		coffeeFeedback.Answer.Points = int(math.Round(coffeePoints))
+		coffeeFeedback.Questions = byCoffee[coffeeFeedback.CoffeeID]
+	}
+	return feedback
+}
+
+// submissionBreakdown returns the per-question scores of a submission.
+// Submissions scored before breakdowns were stored are graded again.
+func submissionBreakdown(submission *models.Submission, coffeeCase *models.CoffeeCase) []models.QuestionScore {
+	if len(submission.Breakdown) > 0 {
+		return submission.Breakdown
+	}
+	result, err := scoring.Score(coffeeCase, submission)
+	if err != nil {
+		log.Printf("Failed to grade submission %s: %v", submission.ID, err)
+		return nil
+	}
+	return result.Breakdown
+}
+
+// GetCaseReveal returns a case's coffees with their correct answers once the
+// case's results are revealed. A signed-in player also gets their own
+// submissions to the case, graded question by question.
+func GetCaseReveal(c *gin.Context) {
+	caseID := c.Param("id")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	coffeeCase, err := database.DB.Cases().Get(ctx, caseID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Case not found"})
+		return
+	}
+
+	if coffeeCase.ResultsRevealedAt == nil {
+		var revealsAt *time.Time
+		if coffeeCase.ClosedAt != nil && !coffeeCase.IsActive {
+			at := coffeeCase.ClosedAt.Add(schedule.GracePeriod)
+			revealsAt = &at
+		}
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":      "Answers are revealed once the case's submissions close",
+			"phase":      schedule.Phase(coffeeCase),
+			"reveals_at": revealsAt,
+		})
+		return
+	}
+
+	response := gin.H{
+		"case":                toPublicCase(coffeeCase),
+		"coffees":             coffeeCase.Coffees,
+		"results_revealed_at": coffeeCase.ResultsRevealedAt,
+	}
+
+	if userID := c.GetString("userID"); userID != "" {
+		userSubmissions, err := database.DB.Submissions().ListByUser(ctx, userID, 0, 0)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch submissions", "details": err.Error()})
+			return
+		}
+		submissions := []models.SubmissionFeedback{}
+		for i := range userSubmissions {
+			if userSubmissions[i].CaseID == coffeeCase.ID {
+				submissions = append(submissions, buildFeedback(&userSubmissions[i], coffeeCase))
+			}
+		}
+		response["submissions"] = submissions
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"brew-detective-backend/internal/audit"
+	"brew-detective-backend/internal/auth"
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+	"brew-detective-backend/internal/roles"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetRoles returns every role and the permissions it grants
+func GetRoles(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"roles": roles.Definitions()})
+}
+
+// GrantRole gives a user a role
+func GrantRole(c *gin.Context) {
+	userID := c.Param("id")
+
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || !roles.IsValid(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Read and write in one transaction so that concurrent grants and
+	// revocations cannot undo each other
+	var user *models.User
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		var err error
+		if user, err = tx.Users().Get(ctx, userID); err != nil {
+			return err
+		}
+		before := *user
+		before.Roles = append([]string(nil), user.Roles...)
+		if !roles.Grant(user, req.Role) {
+			return nil
+		}
+		user.UpdatedAt = time.Now()
+		if err := tx.Users().Save(ctx, user); err != nil {
+			return err
+		}
+		return audit.Write(ctx, tx, c, "user.role_grant", audit.Users, userID, &before, user)
+	})
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to grant role"})
+		return
+	}
+	roles.Invalidate(userID)
+
+	c.JSON(http.StatusOK, gin.H{"id": user.ID, "roles": roles.Of(user)})
+}
+
+// errRoleNotHeld is returned from a revocation's transaction when the user
+// does not have the role
+var errRoleNotHeld = errors.New("user does not have this role")
+
+// RevokeRole takes a role away from a user. The user's sessions are revoked
+// too, so the change applies at once on every server instance, not only
+// when their cached roles expire.
+func RevokeRole(c *gin.Context) {
+	userID := c.Param("id")
+	role := c.Param("role")
+
+	if userID == c.GetString("userID") && role == roles.Admin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Admins cannot revoke their own admin role"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user *models.User
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		var err error
+		if user, err = tx.Users().Get(ctx, userID); err != nil {
+			return err
+		}
+		before := *user
+		before.Roles = append([]string(nil), user.Roles...)
+		if !roles.Revoke(user, role) {
+			return errRoleNotHeld
+		}
+		user.UpdatedAt = time.Now()
+		if err := tx.Users().Save(ctx, user); err != nil {
+			return err
+		}
+		return audit.Write(ctx, tx, c, "user.role_revoke", audit.Users, userID, &before, user)
+	})
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if errors.Is(err, errRoleNotHeld) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User does not have this role"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke role"})
+		return
+	}
+	roles.Invalidate(userID)
+
+	if _, err := auth.RevokeAllSessions(ctx, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Role revoked but failed to end the user's sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": user.ID, "roles": roles.Of(user)})
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"brew-detective-backend/internal/auth"
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+	"brew-detective-backend/internal/roles"
+)
+
+func grantRole(t *testing.T, actor, userID, role string) int {
+	return serve(t, actor, http.MethodPost, "/admin/users/:id/roles", "/admin/users/"+userID+"/roles",
+		map[string]string{"role": role}, auth.RequirePermission(roles.RolesManage), GrantRole).Code
+}
+
+func revokeRole(t *testing.T, actor, userID, role string) int {
+	return serve(t, actor, http.MethodDelete, "/admin/users/:id/roles/:role", "/admin/users/"+userID+"/roles/"+role,
+		nil, auth.RequirePermission(roles.RolesManage), RevokeRole).Code
+}
+
+func TestRolesGrantAndRevokePermissions(t *testing.T) {
+	useMemoryStore(t)
+	seedUser(t, "admin", roles.Admin)
+	seedUser(t, "staff")
+	order := seedOrder(t, "ABC123", "buyer", "case1", models.OrderStatusPending)
+
+	if code := setStatus(t, "staff", order.ID, models.OrderStatusConfirmed); code != http.StatusForbidden {
+		t.Fatalf("user without roles got %d, want %d", code, http.StatusForbidden)
+	}
+
+	if code := grantRole(t, "admin", "staff", roles.Fulfillment); code != http.StatusOK {
+		t.Fatalf("granting fulfillment got %d", code)
+	}
+	if code := setStatus(t, "staff", order.ID, models.OrderStatusConfirmed); code != http.StatusOK {
+		t.Fatalf("fulfillment got %d, want %d", code, http.StatusOK)
+	}
+
+	// The revocation applies at once, even though the staff's roles were cached
+	if code := revokeRole(t, "admin", "staff", roles.Fulfillment); code != http.StatusOK {
+		t.Fatalf("revoking fulfillment got %d", code)
+	}
+	if code := setStatus(t, "staff", order.ID, models.OrderStatusShipped); code != http.StatusForbidden {
+		t.Errorf("revoked staff got %d, want %d", code, http.StatusForbidden)
+	}
+}
+
+func TestRolesOnlyGrantTheirPermissions(t *testing.T) {
+	useMemoryStore(t)
+	seedUser(t, "support", roles.Support)
+	seedUser(t, "player")
+
+	if code := grantRole(t, "support", "player", roles.Admin); code != http.StatusForbidden {
+		t.Errorf("support granting admin got %d, want %d", code, http.StatusForbidden)
+	}
+	if code := grantRole(t, "nobody", "player", roles.Admin); code != http.StatusUnauthorized {
+		t.Errorf("unknown user got %d, want %d", code, http.StatusUnauthorized)
+	}
+
+	user, err := database.DB.Users().Get(context.Background(), "player")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roles.Of(user)) != 0 {
+		t.Errorf("player has roles %v", roles.Of(user))
+	}
+}
+
+func TestRolesLegacyAdmin(t *testing.T) {
+	useMemoryStore(t)
+	legacy := &models.User{ID: "legacy", Type: "admin"}
+	if err := database.DB.Users().Save(context.Background(), legacy); err != nil {
+		t.Fatal(err)
+	}
+	roles.Invalidate(legacy.ID)
+	seedUser(t, "admin", roles.Admin)
+
+	if code := grantRole(t, "legacy", "admin", roles.Support); code != http.StatusOK {
+		t.Errorf("legacy admin got %d, want %d", code, http.StatusOK)
+	}
+	if code := revokeRole(t, "admin", "admin", roles.Admin); code != http.StatusBadRequest {
+		t.Errorf("revoking one's own admin role got %d, want %d", code, http.StatusBadRequest)
+	}
+
+	// Revoking the admin role also clears the legacy type
+	if code := revokeRole(t, "admin", "legacy", roles.Admin); code != http.StatusOK {
+		t.Fatalf("revoking the legacy admin got %d", code)
+	}
+	if code := grantRole(t, "legacy", "admin", roles.Fulfillment); code != http.StatusForbidden {
+		t.Errorf("former legacy admin got %d, want %d", code, http.StatusForbidden)
+	}
+}
+
+func TestRolesConcurrentGrantsAreAllKept(t *testing.T) {
+	useMemoryStore(t)
+	seedUser(t, "admin", roles.Admin)
+	seedUser(t, "staff", roles.Support)
+
+	granted := []string{roles.CaseCurator, roles.Fulfillment}
+	var wg sync.WaitGroup
+	for _, role := range granted {
+		wg.Add(1)
+		go func(role string) {
+			defer wg.Done()
+			grantRole(t, "admin", "staff", role)
+		}(role)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		revokeRole(t, "admin", "staff", roles.Support)
+	}()
+	wg.Wait()
+
+	user, err := database.DB.Users().Get(context.Background(), "staff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Roles are sorted, like granted
+	if got := roles.Of(user); fmt.Sprint(got) != fmt.Sprint(granted) {
+		t.Errorf("roles = %v, want %v", got, granted)
+	}
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"brew-detective-backend/internal/audit"
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/leaderboard"
+	"brew-detective-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetSeasons returns every season ordered by start date
+func GetSeasons(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	seasons, err := database.DB.Seasons().List(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch seasons", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"seasons": seasons})
+}
+
+// GetSeasonLeaderboard returns the standings of a season
+func GetSeasonLeaderboard(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	season, err := database.DB.Seasons().Get(ctx, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Season not found"})
+		return
+	}
+
+	respondWithLeaderboard(c, leaderboard.SeasonBoard(season.ID), gin.H{"season": season})
+}
+
+// seasonError is a reason a season is rejected, returned from the
+// transaction that saves it
+type seasonError struct {
+	Status  int
+	Message string
+}
+
+func (e *seasonError) Error() string {
+	return e.Message
+}
+
+// validateSeason checks a season's dates and that it does not overlap another season,
+// so every case belongs to at most one season
+func validateSeason(ctx context.Context, store database.Store, season *models.Season) error {
+	if season.Name == "" || season.StartsAt.IsZero() || season.EndsAt.IsZero() {
+		return &seasonError{http.StatusBadRequest, "Name, starts_at and ends_at are required"}
+	}
+	if !season.EndsAt.After(season.StartsAt) {
+		return &seasonError{http.StatusBadRequest, "ends_at must be after starts_at"}
+	}
+
+	seasons, err := store.Seasons().List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, other := range seasons {
+		if other.ID != season.ID && season.StartsAt.Before(other.EndsAt) && other.StartsAt.Before(season.EndsAt) {
+			return &seasonError{http.StatusConflict, "Season overlaps with " + other.Name}
+		}
+	}
+	return nil
+}
+
+// respondSeasonError reports the error of a season's transaction
+func respondSeasonError(c *gin.Context, err error, message string) {
+	var invalid *seasonError
+	switch {
+	case errors.As(err, &invalid):
+		c.JSON(invalid.Status, gin.H{"error": invalid.Message})
+	case errors.Is(err, database.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Season not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// CreateSeason creates a season and ranks the cases it covers (admin only)
+func CreateSeason(c *gin.Context) {
+	var season models.Season
+	if err := c.ShouldBindJSON(&season); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid season data"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	season.ID = uuid.New().String()
+	season.CreatedAt = time.Now()
+	season.UpdatedAt = season.CreatedAt
+
+	// Check for overlaps in the transaction that saves the season, so that
+	// two seasons created at once cannot overlap
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		if err := validateSeason(ctx, tx, &season); err != nil {
+			return err
+		}
+		if err := tx.Seasons().Save(ctx, &season); err != nil {
+			return err
+		}
+		return audit.Write(ctx, tx, c, "season.create", audit.Seasons, season.ID, nil, &season)
+	})
+	if err != nil {
+		respondSeasonError(c, err, "Failed to create season")
+		return
+	}
+
+	ranked, err := leaderboard.RebuildSeason(ctx, database.DB, &season)
+	if err != nil {
+		log.Printf("Failed to build standings for season %s: %v", season.ID, err)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"season": season, "users_ranked": ranked})
+}
+
+// UpdateSeason changes a season's name or dates and re-ranks it (admin only)
+func UpdateSeason(c *gin.Context) {
+	seasonID := c.Param("id")
+
+	var updates struct {
+		Name     *string    `json:"name"`
+		StartsAt *time.Time `json:"starts_at"`
+		EndsAt   *time.Time `json:"ends_at"`
+	}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid update data"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var season *models.Season
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		before, err := tx.Seasons().Get(ctx, seasonID)
+		if err != nil {
+			return err
+		}
+
+		updated := *before
+		if updates.Name != nil {
+			updated.Name = *updates.Name
+		}
+		if updates.StartsAt != nil {
+			updated.StartsAt = *updates.StartsAt
+		}
+		if updates.EndsAt != nil {
+			updated.EndsAt = *updates.EndsAt
+		}
+		if err := validateSeason(ctx, tx, &updated); err != nil {
+			return err
+		}
+
+		updated.UpdatedAt = time.Now()
+		if err := tx.Seasons().Save(ctx, &updated); err != nil {
+			return err
+		}
+		season = &updated
+		return audit.Write(ctx, tx, c, "season.update", audit.Seasons, seasonID, before, season)
+	})
+	if err != nil {
+		respondSeasonError(c, err, "Failed to update season")
+		return
+	}
+
+	// The dates decide which cases count, so rank the season again
+	ranked, err := leaderboard.RebuildSeason(ctx, database.DB, season)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rebuild season standings", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"season": season, "users_ranked": ranked})
+}
+
+// DeleteSeason deletes a season and its standings (admin only)
+func DeleteSeason(c *gin.Context) {
+	seasonID := c.Param("id")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		before, err := tx.Seasons().Get(ctx, seasonID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Seasons().Delete(ctx, seasonID); err != nil {
+			return err
+		}
+		return audit.Write(ctx, tx, c, "season.delete", audit.Seasons, seasonID, before, nil)
+	})
+	if err != nil {
+		respondSeasonError(c, err, "Failed to delete season")
+		return
+	}
+	if err := leaderboard.DeleteSeasonStandings(ctx, database.DB, seasonID); err != nil {
+		log.Printf("Failed to delete standings of season %s: %v", seasonID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Season deleted successfully"})
+}
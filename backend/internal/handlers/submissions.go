@@ -2,19 +2,23 @@ package handlers
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"log"
+	"math"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
+	"brew-detective-backend/internal/badges"
+	"brew-detective-backend/internal/catalog"
 	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/leaderboard"
 	"brew-detective-backend/internal/models"
+	"brew-detective-backend/internal/schedule"
+	"brew-detective-backend/internal/scoring"
 
-	"cloud.google.com/go/firestore"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"google.golang.org/api/iterator"
 )
 
 // SubmitCase handles case submission
@@ -32,21 +36,6 @@ func SubmitCase(c *gin.Context) {
 		return
 	}
 
-	// Get the current active case
-	activeCase, err := getActiveCase()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No active case available", "details": err.Error()})
-		return
-	}
-	submission.CaseID = activeCase.ID
-
-	// Validate order ID with detailed error messages
-	orderValidation := validateOrderIDDetailed(submission.OrderID)
-	if !orderValidation.IsValid {
-		c.JSON(http.StatusBadRequest, gin.H{"error": orderValidation.ErrorMessage})
-		return
-	}
-
 	// Get user ID from auth context
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -59,459 +48,386 @@ func SubmitCase(c *gin.Context) {
 	submission.ID = uuid.New().String()
 	submission.SubmittedAt = time.Now()
 
-	// Calculate score and accuracy
-	score, accuracy := calculateScore(&submission)
-	submission.Score = score
-	submission.Accuracy = accuracy
-
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Save submission to Firestore
-	_, err = database.FirestoreClient.Collection(database.SubmissionsCollection).
-		Doc(submission.ID).Set(ctx, submission)
+	canonicalAnswers(ctx, submission.CoffeeAnswers)
+
+	// Claim the order, score against the order's case, save the submission
+	// and update user stats and badges atomically
+	var newBadges []models.UserBadge
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		var err error
+		newBadges, err = recordSubmission(ctx, tx, &submission)
+		return err
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save submission"})
+		respondSubmissionError(c, err)
 		return
 	}
+	respondSubmitted(ctx, c, &submission, newBadges)
+}
 
-	// Mark order ID as used
-	markOrderIDAsUsed(submission.OrderID, submission.UserID)
+// respondSubmissionError reports why a submission could not be recorded
+func respondSubmissionError(c *gin.Context, err error) {
+	var subErr *submissionError
+	if errors.As(err, &subErr) {
+		c.JSON(subErr.Status, gin.H{"error": subErr.Message, "code": subErr.Code})
+		return
+	}
+	if errors.Is(err, scoring.ErrUnknownCoffee) || errors.Is(err, scoring.ErrDuplicateCoffee) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid submission data", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save submission"})
+}
 
-	// Update user stats if user exists
-	go updateUserStats(submission.UserID, score, accuracy)
+// respondSubmitted reports a recorded submission along with the badges it earned
+func respondSubmitted(ctx context.Context, c *gin.Context, submission *models.Submission, newBadges []models.UserBadge) {
+	// Show new badges on the user's other case standings too
+	if len(newBadges) > 0 {
+		if user, err := database.DB.Users().Get(ctx, submission.UserID); err == nil {
+			if err := leaderboard.SyncUser(ctx, database.DB, user); err != nil {
+				log.Printf("Failed to sync standings for user %s: %v", user.ID, err)
+			}
+		}
+	}
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message":       "Submission successful",
 		"submission_id": submission.ID,
-		"score":         score,
-		"accuracy":      accuracy,
+		"score":         submission.Score,
+		"accuracy":      submission.Accuracy,
+		"new_badges":    newBadges,
 	})
 }
 
-// calculateScore calculates the score and accuracy for a submission
-func calculateScore(submission *models.Submission) (int, float64) {
-	fmt.Printf("🔍 [SCORING DEBUG] Starting score calculation for submission ID: %s\n", submission.ID)
-	
-	// Get the active case to determine enabled questions
-	activeCase, err := getActiveCase()
+// canonicalAnswers replaces region, variety, process and tasting note answers
+// that refer to a catalog item, by label or synonym, with the item's value.
+// Other answers are kept as typed and graded as free text.
+func canonicalAnswers(ctx context.Context, answers []models.CoffeeAnswer) {
+	index, err := catalog.Load(ctx)
 	if err != nil {
-		fmt.Printf("❌ [SCORING DEBUG] Failed to get active case: %v\n", err)
-		// Fallback to default scoring if case not found
-		return calculateScoreDefault(submission)
-	}
-	
-	fmt.Printf("✅ [SCORING DEBUG] Active case found: %s (ID: %s)\n", activeCase.Name, activeCase.ID)
-	fmt.Printf("📋 [SCORING DEBUG] Enabled questions: Region=%t, Variety=%t, Process=%t, TasteNote1=%t, TasteNote2=%t, FavoriteCoffee=%t, BrewingMethod=%t\n", 
-		activeCase.EnabledQuestions.Region, activeCase.EnabledQuestions.Variety, activeCase.EnabledQuestions.Process,
-		activeCase.EnabledQuestions.TasteNote1, activeCase.EnabledQuestions.TasteNote2, 
-		activeCase.EnabledQuestions.FavoriteCoffee, activeCase.EnabledQuestions.BrewingMethod)
-	fmt.Printf("☕ [SCORING DEBUG] Case has %d coffees\n", len(activeCase.Coffees))
-
-	// Count enabled questions per coffee
-	enabledQuestionsPerCoffee := 0
-	if activeCase.EnabledQuestions.Region {
-		enabledQuestionsPerCoffee++
-	}
-	if activeCase.EnabledQuestions.Variety {
-		enabledQuestionsPerCoffee++
-	}
-	if activeCase.EnabledQuestions.Process {
-		enabledQuestionsPerCoffee++
-	}
-	if activeCase.EnabledQuestions.TasteNote1 {
-		enabledQuestionsPerCoffee++
-	}
-	if activeCase.EnabledQuestions.TasteNote2 {
-		enabledQuestionsPerCoffee++
-	}
-
-	totalQuestions := len(submission.CoffeeAnswers) * enabledQuestionsPerCoffee
-	fmt.Printf("📊 [SCORING DEBUG] Enabled questions per coffee: %d\n", enabledQuestionsPerCoffee)
-	fmt.Printf("📊 [SCORING DEBUG] Total questions: %d coffees × %d questions = %d\n", len(submission.CoffeeAnswers), enabledQuestionsPerCoffee, totalQuestions)
-	
-	if totalQuestions == 0 {
-		fmt.Printf("⚠️ [SCORING DEBUG] No questions enabled, returning 0 score\n")
-		return 0, 0.0
-	}
-
-	correctAnswers := 0
-	basePoints := 100
-	
-	fmt.Printf("👤 [SCORING DEBUG] User submitted %d coffee answers\n", len(submission.CoffeeAnswers))
-
-	for i, answer := range submission.CoffeeAnswers {
-		fmt.Printf("\n☕ [SCORING DEBUG] === Processing Coffee #%d (ID: %s) ===\n", i+1, answer.CoffeeID)
-		
-		// Find the correct coffee data for this answer
-		var correctCoffee *models.CoffeeItem
-		for _, coffee := range activeCase.Coffees {
-			if coffee.ID == answer.CoffeeID {
-				correctCoffee = &coffee
-				break
-			}
-		}
-		
-		if correctCoffee == nil {
-			fmt.Printf("❌ [SCORING DEBUG] Coffee ID %s not found in case, skipping\n", answer.CoffeeID)
-			continue // Skip if coffee not found
-		}
-		
-		fmt.Printf("✅ [SCORING DEBUG] Found correct coffee: %s\n", correctCoffee.Name)
-		fmt.Printf("📋 [SCORING DEBUG] Correct data - Region: '%s', Variety: '%s', Process: '%s', TastingNotes: '%s'\n", 
-			correctCoffee.Region, correctCoffee.Variety, correctCoffee.Process, correctCoffee.TastingNotes)
-		fmt.Printf("👤 [SCORING DEBUG] User answers - Region: '%s', Variety: '%s', Process: '%s', Note1: '%s', Note2: '%s'\n", 
-			answer.Region, answer.Variety, answer.Process, answer.TasteNote1, answer.TasteNote2)
-		
-		// Compare user answers against correct coffee data
-		coffeeCorrectAnswers := 0
-		
-		if activeCase.EnabledQuestions.Region && answer.Region != "" {
-			userRegion := strings.TrimSpace(answer.Region)
-			correctRegion := strings.TrimSpace(correctCoffee.Region)
-			isCorrect := strings.EqualFold(userRegion, correctRegion)
-			fmt.Printf("🌍 [SCORING DEBUG] Region check: '%s' vs '%s' = %t\n", userRegion, correctRegion, isCorrect)
-			if isCorrect {
-				correctAnswers++
-				coffeeCorrectAnswers++
-			}
-		} else if activeCase.EnabledQuestions.Region {
-			fmt.Printf("🌍 [SCORING DEBUG] Region question enabled but user answer is empty\n")
-		}
-		
-		if activeCase.EnabledQuestions.Variety && answer.Variety != "" {
-			userVariety := strings.TrimSpace(answer.Variety)
-			correctVariety := strings.TrimSpace(correctCoffee.Variety)
-			isCorrect := strings.EqualFold(userVariety, correctVariety)
-			fmt.Printf("🌱 [SCORING DEBUG] Variety check: '%s' vs '%s' = %t\n", userVariety, correctVariety, isCorrect)
-			if isCorrect {
-				correctAnswers++
-				coffeeCorrectAnswers++
-			}
-		} else if activeCase.EnabledQuestions.Variety {
-			fmt.Printf("🌱 [SCORING DEBUG] Variety question enabled but user answer is empty\n")
-		}
-		
-		if activeCase.EnabledQuestions.Process && answer.Process != "" {
-			userProcess := strings.TrimSpace(answer.Process)
-			correctProcess := strings.TrimSpace(correctCoffee.Process)
-			isCorrect := strings.EqualFold(userProcess, correctProcess)
-			fmt.Printf("⚙️ [SCORING DEBUG] Process check: '%s' vs '%s' = %t\n", userProcess, correctProcess, isCorrect)
-			if isCorrect {
-				correctAnswers++
-				coffeeCorrectAnswers++
-			}
-		} else if activeCase.EnabledQuestions.Process {
-			fmt.Printf("⚙️ [SCORING DEBUG] Process question enabled but user answer is empty\n")
-		}
-		
-		// Handle comma-separated tasting notes (avoid double points for same note)
-		var awardedTastingNotes []string
-		fmt.Printf("🍫 [SCORING DEBUG] Starting tasting notes evaluation...\n")
-		
-		if activeCase.EnabledQuestions.TasteNote1 && answer.TasteNote1 != "" {
-			fmt.Printf("🍫 [SCORING DEBUG] TasteNote1 check: user='%s' vs correct='%s'\n", answer.TasteNote1, correctCoffee.TastingNotes)
-			if matchedNote := getMatchedTastingNote(answer.TasteNote1, correctCoffee.TastingNotes); matchedNote != "" {
-				fmt.Printf("✅ [SCORING DEBUG] TasteNote1 MATCHED: '%s'\n", matchedNote)
-				awardedTastingNotes = append(awardedTastingNotes, matchedNote)
-				correctAnswers++
-				coffeeCorrectAnswers++
-			} else {
-				fmt.Printf("❌ [SCORING DEBUG] TasteNote1 NO MATCH\n")
+		log.Printf("Failed to load catalog for answers: %v", err)
+		return
+	}
+
+	for i := range answers {
+		answer := &answers[i]
+		for category, value := range map[string]*string{
+			catalog.Region:  &answer.Region,
+			catalog.Variety: &answer.Variety,
+			catalog.Process: &answer.Process,
+		} {
+			if resolved, ok := index.Resolve(category, *value); ok {
+				*value = resolved
 			}
-		} else if activeCase.EnabledQuestions.TasteNote1 {
-			fmt.Printf("🍫 [SCORING DEBUG] TasteNote1 question enabled but user answer is empty\n")
 		}
-		
-		if activeCase.EnabledQuestions.TasteNote2 && answer.TasteNote2 != "" {
-			fmt.Printf("🍫 [SCORING DEBUG] TasteNote2 check: user='%s' vs correct='%s'\n", answer.TasteNote2, correctCoffee.TastingNotes)
-			if matchedNote := getMatchedTastingNote(answer.TasteNote2, correctCoffee.TastingNotes); matchedNote != "" {
-				// Check if we already awarded points for this exact note
-				alreadyAwarded := false
-				for _, awarded := range awardedTastingNotes {
-					if strings.EqualFold(awarded, matchedNote) {
-						alreadyAwarded = true
-						break
-					}
-				}
-				if !alreadyAwarded {
-					fmt.Printf("✅ [SCORING DEBUG] TasteNote2 MATCHED (new): '%s'\n", matchedNote)
-					correctAnswers++
-					coffeeCorrectAnswers++
-				} else {
-					fmt.Printf("⚠️ [SCORING DEBUG] TasteNote2 matched '%s' but already awarded for this note\n", matchedNote)
-				}
-			} else {
-				fmt.Printf("❌ [SCORING DEBUG] TasteNote2 NO MATCH\n")
+		for _, note := range []*string{&answer.TasteNote1, &answer.TasteNote2} {
+			if resolved, ok := index.Resolve(catalog.TastingNote, *note); ok {
+				*note = resolved
 			}
-		} else if activeCase.EnabledQuestions.TasteNote2 {
-			fmt.Printf("🍫 [SCORING DEBUG] TasteNote2 question enabled but user answer is empty\n")
 		}
-		
-		fmt.Printf("📊 [SCORING DEBUG] Coffee #%d results: %d/%d correct answers\n", i+1, coffeeCorrectAnswers, enabledQuestionsPerCoffee)
-	}
-
-	// Add bonus points for non-coffee questions
-	bonusPoints := 0
-	fmt.Printf("\n🎁 [SCORING DEBUG] === Bonus Questions Evaluation ===\n")
-	
-	if activeCase.EnabledQuestions.FavoriteCoffee && submission.FavoriteCoffee != "" {
-		bonusPoints += 50
-		fmt.Printf("✅ [SCORING DEBUG] FavoriteCoffee bonus: +50 points (answer: '%s')\n", submission.FavoriteCoffee)
-	} else if activeCase.EnabledQuestions.FavoriteCoffee {
-		fmt.Printf("❌ [SCORING DEBUG] FavoriteCoffee enabled but no answer provided\n")
-	} else {
-		fmt.Printf("⚪ [SCORING DEBUG] FavoriteCoffee question disabled\n")
-	}
-	
-	if activeCase.EnabledQuestions.BrewingMethod && submission.BrewingMethod != "" {
-		bonusPoints += 50
-		fmt.Printf("✅ [SCORING DEBUG] BrewingMethod bonus: +50 points (answer: '%s')\n", submission.BrewingMethod)
-	} else if activeCase.EnabledQuestions.BrewingMethod {
-		fmt.Printf("❌ [SCORING DEBUG] BrewingMethod enabled but no answer provided\n")
-	} else {
-		fmt.Printf("⚪ [SCORING DEBUG] BrewingMethod question disabled\n")
 	}
-
-	accuracy := float64(correctAnswers) / float64(totalQuestions)
-	score := int(float64(basePoints) * accuracy * float64(len(submission.CoffeeAnswers))) + bonusPoints
-
-	fmt.Printf("\n🏆 [SCORING DEBUG] === FINAL CALCULATION ===\n")
-	fmt.Printf("📊 [SCORING DEBUG] Correct answers: %d/%d\n", correctAnswers, totalQuestions)
-	fmt.Printf("📊 [SCORING DEBUG] Accuracy: %.4f (%.1f%%)\n", accuracy, accuracy*100)
-	fmt.Printf("📊 [SCORING DEBUG] Base calculation: %d points × %.4f accuracy × %d coffees = %.2f\n", 
-		basePoints, accuracy, len(submission.CoffeeAnswers), float64(basePoints) * accuracy * float64(len(submission.CoffeeAnswers)))
-	fmt.Printf("📊 [SCORING DEBUG] Bonus points: %d\n", bonusPoints)
-	fmt.Printf("🏆 [SCORING DEBUG] FINAL SCORE: %d points\n", score)
-
-	return score, accuracy
 }
 
-// calculateScoreDefault provides fallback scoring when case info is not available
-func calculateScoreDefault(submission *models.Submission) (int, float64) {
-	totalQuestions := len(submission.CoffeeAnswers) * 3 // region, variety, process
-	if totalQuestions == 0 {
-		return 0, 0.0
-	}
+// applySubmissionStats folds a scored submission into the user's running stats
+func applySubmissionStats(user *models.User, score int, accuracy float64) {
+	user.Points += score
+	user.CasesCount++
+	user.Accuracy = (user.Accuracy*float64(user.CasesCount-1) + accuracy) / float64(user.CasesCount)
+	user.UpdatedAt = time.Now()
+}
 
-	correctAnswers := 0
-	basePoints := 100
+// rebuildUserStats recomputes the user's stats from their full submission history
+func rebuildUserStats(user *models.User, submissions []models.Submission) {
+	user.Points = 0
+	user.CasesCount = len(submissions)
+	user.Accuracy = 0
 
-	for _, answer := range submission.CoffeeAnswers {
-		if answer.Region != "" {
-			correctAnswers++
-		}
-		if answer.Variety != "" {
-			correctAnswers++
-		}
-		if answer.Process != "" {
-			correctAnswers++
-		}
+	totalAccuracy := 0.0
+	for _, submission := range submissions {
+		user.Points += submission.Score
+		totalAccuracy += submission.Accuracy
+	}
+	if user.CasesCount > 0 {
+		user.Accuracy = totalAccuracy / float64(user.CasesCount)
 	}
+	user.UpdatedAt = time.Now()
+}
 
-	accuracy := float64(correctAnswers) / float64(totalQuestions)
-	score := int(float64(basePoints) * accuracy * float64(len(submission.CoffeeAnswers)))
+// submissionError is a submission failure that is reported to the player as-is
+type submissionError struct {
+	Status  int
+	Code    string
+	Message string
+}
 
-	return score, accuracy
+func (e *submissionError) Error() string {
+	return e.Message
 }
 
-// updateUserStats updates user statistics
-func updateUserStats(userID string, score int, accuracy float64) {
-	if userID == "" {
-		return
+var (
+	errOrderNotFound = &submissionError{
+		Status:  http.StatusBadRequest,
+		Code:    "order_not_found",
+		Message: "Código de pedido no válido. Verifica que hayas ingresado el código correctamente.",
+	}
+	errOrderAlreadyClaimed = &submissionError{
+		Status:  http.StatusConflict,
+		Code:    "order_already_claimed",
+		Message: "Este código de pedido ya fue utilizado para enviar respuestas. Cada código solo puede usarse una vez.",
+	}
+	errOrderNotDelivered = &submissionError{
+		Status:  http.StatusBadRequest,
+		Code:    "order_not_delivered",
+		Message: "Tu pedido aún no ha sido entregado. Solo puedes enviar respuestas después de recibir tu café.",
+	}
+	errCaseNotFound = &submissionError{
+		Status:  http.StatusNotFound,
+		Code:    "case_not_found",
+		Message: "El caso de este pedido no existe o no hay un caso activo.",
+	}
+	errCaseClosed = &submissionError{
+		Status:  http.StatusConflict,
+		Code:    "case_closed",
+		Message: "El caso de este pedido ya cerró y no acepta más respuestas.",
+	}
+	errDraftInProgress = &submissionError{
+		Status:  http.StatusConflict,
+		Code:    "draft_in_progress",
+		Message: "Otro detective ya está guardando respuestas con este código de pedido.",
+	}
+	errNotTeamMember = &submissionError{
+		Status:  http.StatusForbidden,
+		Code:    "not_team_member",
+		Message: "Este pedido es de un equipo. Únete con el código de invitación del equipo para enviar respuestas.",
+	}
+	errTeamMemberSubmitted = &submissionError{
+		Status:  http.StatusConflict,
+		Code:    "team_member_submitted",
+		Message: "Ya enviaste tus respuestas para este pedido.",
+	}
+	errSubmissionUserNotFound = &submissionError{
+		Status:  http.StatusNotFound,
+		Code:    "user_not_found",
+		Message: "User not found",
 	}
+)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	userRef := database.FirestoreClient.Collection(database.UsersCollection).Doc(userID)
-
-	// Get current user data
-	doc, err := userRef.Get(ctx)
+// openOrder loads an order the user can still submit, along with its team, if
+// any, and its case
+func openOrder(ctx context.Context, tx database.Store, orderID, userID string, at time.Time) (*models.Order, *models.Team, *models.CoffeeCase, error) {
+	order, team, err := loadOrder(ctx, tx, orderID, userID)
 	if err != nil {
-		// User doesn't exist - submissions should only work for existing users
-		return
+		return nil, nil, nil, err
 	}
-
-	if !doc.Exists() {
-		// User doesn't exist - submissions should only work for existing users
-		return
-	}
-
-	var user models.User
-	if err := doc.DataTo(&user); err != nil {
-		return
+	coffeeCase, err := checkOrderOpen(ctx, tx, order, team, userID, at)
+	if err != nil {
+		return nil, nil, nil, err
 	}
-
-	// Update user stats
-	user.Points += score
-	user.CasesCount++
-	user.Accuracy = (user.Accuracy*float64(user.CasesCount-1) + accuracy) / float64(user.CasesCount)
-	user.UpdatedAt = time.Now()
-
-	// Badges temporarily disabled
-	// updateBadges(&user)
-
-	userRef.Set(ctx, user)
+	return order, team, coffeeCase, nil
 }
 
-// updateBadges updates user badges based on achievements
-func updateBadges(user *models.User) {
-	badges := make(map[string]bool)
-
-	// Convert existing badges to map for easy lookup
-	for _, badge := range user.Badges {
-		badges[badge] = true
+// loadOrder loads an order by its code along with its team. Orders played by
+// a team can only be used by the team's members.
+func loadOrder(ctx context.Context, tx database.Store, orderID, userID string) (*models.Order, *models.Team, error) {
+	order, err := tx.Orders().GetByOrderID(ctx, orderID)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, nil, errOrderNotFound
 	}
-
-	// First case badge
-	if user.CasesCount >= 1 && !badges["🔍 Primer Caso"] {
-		badges["🔍 Primer Caso"] = true
+	if err != nil {
+		return nil, nil, err
+	}
+	if order.TeamID == "" {
+		return order, nil, nil
 	}
 
-	// Accuracy badges
-	if user.Accuracy >= 0.7 && !badges["🎯 Precisión 70%"] {
-		badges["🎯 Precisión 70%"] = true
+	team, err := tx.Teams().Get(ctx, order.TeamID)
+	if err != nil {
+		return nil, nil, err
 	}
-	if user.Accuracy >= 0.8 && !badges["💎 Catador Nivel 2"] {
-		badges["💎 Catador Nivel 2"] = true
+	if !isTeamMember(team, userID) {
+		return nil, nil, errNotTeamMember
 	}
+	return order, team, nil
+}
 
-	// Points badges
-	if user.Points >= 2000 && !badges["🏆 Detective Maestro"] {
-		badges["🏆 Detective Maestro"] = true
+// checkOrderOpen checks that the user can still submit an order and returns
+// its case
+func checkOrderOpen(ctx context.Context, tx database.Store, order *models.Order, team *models.Team, userID string, at time.Time) (*models.CoffeeCase, error) {
+	// Check if already used for submission
+	if order.IsSubmissionUsed {
+		return nil, errOrderAlreadyClaimed
 	}
 
-	// Cases count badges
-	if user.CasesCount >= 5 && !badges["🔥 Experto en Tuestes"] {
-		badges["🔥 Experto en Tuestes"] = true
+	// Order must be in delivered status to allow submission
+	if order.Status != models.OrderStatusDelivered {
+		return nil, errOrderNotDelivered
 	}
 
-	// Convert back to slice
-	user.Badges = make([]string, 0, len(badges))
-	for badge := range badges {
-		user.Badges = append(user.Badges, badge)
+	// Members of a team playing separately submit once each
+	if team != nil && team.Mode == models.TeamModeSeparate {
+		submitted, err := tx.Submissions().ListByTeam(ctx, team.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, submission := range submitted {
+			if submission.UserID == userID {
+				return nil, errTeamMemberSubmitted
+			}
+		}
+	}
+
+	coffeeCase, err := orderCase(ctx, tx, order)
+	if err != nil {
+		return nil, err
+	}
+	if !schedule.AcceptsSubmissions(coffeeCase, at) {
+		return nil, errCaseClosed
 	}
+	return coffeeCase, nil
 }
 
-// OrderValidationResult holds the result of order ID validation
-type OrderValidationResult struct {
-	IsValid      bool
-	ErrorMessage string
+// orderCase loads the case an order was placed for. Orders created before
+// orders carried a case are bound to the active case.
+func orderCase(ctx context.Context, tx database.Store, order *models.Order) (*models.CoffeeCase, error) {
+	var coffeeCase *models.CoffeeCase
+	var err error
+	if order.CaseID != "" {
+		coffeeCase, err = tx.Cases().Get(ctx, order.CaseID)
+	} else {
+		coffeeCase, err = tx.Cases().GetActive(ctx)
+	}
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, errCaseNotFound
+	}
+	return coffeeCase, err
 }
 
-// validateOrderIDDetailed validates an order ID and returns detailed error information
-func validateOrderIDDetailed(orderID string) OrderValidationResult {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// applyScore stores a scoring result on its submission, including the points
+// each coffee answer earned
+func applyScore(submission *models.Submission, result *scoring.Result) {
+	submission.Score = result.Score
+	submission.Accuracy = result.Accuracy
+	submission.Breakdown = result.Breakdown
 
-	// Query orders collection to find order with this order ID
-	query := database.FirestoreClient.Collection(database.OrdersCollection).
-		Where("order_id", "==", orderID).
-		Limit(1)
+	points := scoring.CoffeePoints(result.Breakdown)
+	for i := range submission.CoffeeAnswers {
+		submission.CoffeeAnswers[i].Points = int(math.Round(points[submission.CoffeeAnswers[i].CoffeeID]))
+	}
+}
 
-	docs, err := query.Documents(ctx).GetAll()
+// recordSubmission claims the submission's order, scores the submission against
+// the order's case, saves it and updates the submitting user's stats, badges and
+// leaderboard standings, as well as the standing of the order's team.
+// It must run inside a transaction so that concurrent submissions with the same
+// order code cannot both succeed. It returns the badges awarded by the submission.
+func recordSubmission(ctx context.Context, tx database.Store, submission *models.Submission) ([]models.UserBadge, error) {
+	// Reads first: Firestore transactions do not allow reads after writes
+	order, team, coffeeCase, err := openOrder(ctx, tx, submission.OrderID, submission.UserID, submission.SubmittedAt)
 	if err != nil {
-		return OrderValidationResult{
-			IsValid:      false,
-			ErrorMessage: "Error al validar el código de pedido. Intenta nuevamente.",
-		}
-	}
-	
-	if len(docs) == 0 {
-		return OrderValidationResult{
-			IsValid:      false,
-			ErrorMessage: "Código de pedido no válido. Verifica que hayas ingresado el código correctamente.",
-		}
+		return nil, err
 	}
 
-	var order models.Order
-	if err := docs[0].DataTo(&order); err != nil {
-		return OrderValidationResult{
-			IsValid:      false,
-			ErrorMessage: "Error al procesar el código de pedido. Contacta al soporte.",
-		}
+	// The user's draft of the order, if any, is locked by the submission
+	draft, err := tx.Drafts().Get(ctx, draftID(order.OrderID, team, submission.UserID))
+	if errors.Is(err, database.ErrNotFound) {
+		draft = nil
+	} else if err != nil {
+		return nil, err
+	} else if !canEditDraft(draft, team, submission.UserID) {
+		return nil, errDraftInProgress
 	}
 
-	// Check if already used for submission
-	if order.IsSubmissionUsed {
-		return OrderValidationResult{
-			IsValid:      false,
-			ErrorMessage: "Este código de pedido ya fue utilizado para enviar respuestas. Cada código solo puede usarse una vez.",
+	var teamSubmissions []models.Submission
+	if team != nil {
+		submission.TeamID = team.ID
+		teamSubmissions, err = tx.Submissions().ListByTeam(ctx, team.ID)
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	// Order must be in delivered status to allow submission
-	if order.Status != "delivered" {
-		return OrderValidationResult{
-			IsValid:      false,
-			ErrorMessage: "Tu pedido aún no ha sido entregado. Solo puedes enviar respuestas después de recibir tu café.",
-		}
+	user, err := tx.Users().Get(ctx, submission.UserID)
+	if errors.Is(err, database.ErrNotFound) {
+		// Submissions only work for existing users
+		return nil, errSubmissionUserNotFound
 	}
-
-	return OrderValidationResult{
-		IsValid:      true,
-		ErrorMessage: "",
+	if err != nil {
+		return nil, err
 	}
-}
 
-// validateOrderID validates if an order ID is valid and unused (legacy function)
-func validateOrderID(orderID string) bool {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	// Query orders collection to find order with this order ID
-	query := database.FirestoreClient.Collection(database.OrdersCollection).
-		Where("order_id", "==", orderID).
-		Limit(1)
-
-	docs, err := query.Documents(ctx).GetAll()
-	if err != nil || len(docs) == 0 {
-		return false // Order ID doesn't exist
+	// Badge rules look at the whole history, not just the running totals
+	history, err := tx.Submissions().ListByUser(ctx, submission.UserID, 0, 0)
+	if err != nil {
+		return nil, err
 	}
-
-	var order models.Order
-	if err := docs[0].DataTo(&order); err != nil {
-		return false
+	definitions, err := tx.Badges().List(ctx)
+	if err != nil {
+		return nil, err
 	}
-
-	// Check if already used for submission
-	if order.IsSubmissionUsed {
-		return false
+	season, seasonCases, err := leaderboard.SeasonFor(ctx, tx, coffeeCase)
+	if err != nil {
+		return nil, err
 	}
 
-	// Order must be in delivered status to allow submission
-	if order.Status != "delivered" {
-		return false
+	// Calculate score, accuracy and per-question breakdown
+	submission.CaseID = coffeeCase.ID
+	result, err := scoring.Score(coffeeCase, submission)
+	if err != nil {
+		return nil, err
+	}
+	applyScore(submission, result)
+	submission.ProcessedAt = &submission.SubmittedAt
+	log.Printf("Scored submission %s for case %s: %d points, %.2f accuracy", submission.ID, coffeeCase.ID, result.Score, result.Accuracy)
+
+	// Mark order ID as used, unless the other members of its team still
+	// submit their own answers
+	if team == nil || team.Mode == models.TeamModeJoint {
+		order.IsSubmissionUsed = true
+		order.SubmissionUsedBy = submission.UserID
+		order.SubmissionUsedAt = &submission.SubmittedAt
+		order.UpdatedAt = submission.SubmittedAt
+		if err := tx.Orders().Save(ctx, order); err != nil {
+			return nil, err
+		}
 	}
 
-	return true
-}
-
-// markOrderIDAsUsed marks an order ID as used for submission
-func markOrderIDAsUsed(orderID string, userID string) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	// Query orders collection to find order with this order ID
-	query := database.FirestoreClient.Collection(database.OrdersCollection).
-		Where("order_id", "==", orderID).
-		Limit(1)
+	if err := tx.Submissions().Save(ctx, submission); err != nil {
+		return nil, err
+	}
 
-	docs, err := query.Documents(ctx).GetAll()
-	if err != nil || len(docs) == 0 {
-		return // Order not found
+	if draft != nil {
+		draft.SubmissionID = submission.ID
+		draft.FinalizedAt = &submission.SubmittedAt
+		draft.UpdatedAt = submission.SubmittedAt
+		if err := tx.Drafts().Save(ctx, draft); err != nil {
+			return nil, err
+		}
 	}
 
-	now := time.Now()
-	updates := []firestore.Update{
-		{Path: "is_submission_used", Value: true},
-		{Path: "submission_used_by", Value: userID},
-		{Path: "submission_used_at", Value: now},
-		{Path: "updated_at", Value: now},
+	applySubmissionStats(user, submission.Score, submission.Accuracy)
+	history = append(history, *submission)
+	awarded := badges.Evaluate(definitions, user, badges.StatsFor(user, history), submission.SubmittedAt)
+	if err := tx.Users().Save(ctx, user); err != nil {
+		return nil, err
 	}
 
-	docs[0].Ref.Update(ctx, updates)
+	if err := leaderboard.SaveStandings(ctx, tx, user, history, coffeeCase.ID); err != nil {
+		return nil, err
+	}
+	if team != nil {
+		// Writing the team makes concurrent submissions of its members conflict
+		team.UpdatedAt = submission.SubmittedAt
+		if err := tx.Teams().Save(ctx, team); err != nil {
+			return nil, err
+		}
+		if err := leaderboard.SaveTeamStanding(ctx, tx, team, append(teamSubmissions, *submission)); err != nil {
+			return nil, err
+		}
+	}
+	return awarded, leaderboard.SaveSeasonStanding(ctx, tx, user, history, season, seasonCases)
 }
 
 // getActiveCase gets the current active case
@@ -519,25 +435,7 @@ func getActiveCase() (*models.CoffeeCase, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	iter := database.FirestoreClient.Collection(database.CasesCollection).
-		Where("is_active", "==", true).
-		Limit(1).
-		Documents(ctx)
-
-	doc, err := iter.Next()
-	if err == iterator.Done {
-		return nil, err
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	var coffeeCase models.CoffeeCase
-	if err := doc.DataTo(&coffeeCase); err != nil {
-		return nil, err
-	}
-
-	return &coffeeCase, nil
+	return database.DB.Cases().GetActive(ctx)
 }
 
 // GetUserSubmissions returns submissions for a specific user
@@ -570,40 +468,20 @@ func GetUserSubmissions(c *gin.Context) {
 	defer cancel()
 
 	// Query submissions for this user, ordered by most recent first
-	query := database.FirestoreClient.Collection(database.SubmissionsCollection).
-		Where("user_id", "==", userID.(string)).
-		OrderBy("submitted_at", firestore.Desc).
-		Limit(limit).
-		Offset(offset)
-
-	iter := query.Documents(ctx)
+	userSubmissions, err := database.DB.Submissions().ListByUser(ctx, userID.(string), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch submissions", "details": err.Error()})
+		return
+	}
 
 	var submissions []map[string]interface{}
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch submissions", "details": err.Error()})
-			return
-		}
-
-		var submission models.Submission
-		if err := doc.DataTo(&submission); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse submission"})
-			return
-		}
-
+	for _, submission := range userSubmissions {
 		// Get case information for this submission
-		caseRef := database.FirestoreClient.Collection(database.CasesCollection).Doc(submission.CaseID)
-		caseDoc, err := caseRef.Get(ctx)
 		var caseName string
-		if err == nil && caseDoc.Exists() {
-			var coffeeCase models.CoffeeCase
-			if err := caseDoc.DataTo(&coffeeCase); err == nil {
-				caseName = coffeeCase.Name
-			}
+		revealed := false
+		if coffeeCase, err := database.DB.Cases().Get(ctx, submission.CaseID); err == nil {
+			caseName = coffeeCase.Name
+			revealed = coffeeCase.ResultsRevealedAt != nil
 		}
 		if caseName == "" {
 			caseName = "Caso Desconocido"
@@ -618,6 +496,7 @@ func GetUserSubmissions(c *gin.Context) {
 			"accuracy":     submission.Accuracy,
 			"submitted_at": submission.SubmittedAt,
 			"status":       "completed",
+			"revealed":     revealed, // Whether GET /submissions/:id includes the grading
 		}
 
 		submissions = append(submissions, submissionResponse)
@@ -631,57 +510,241 @@ func GetUserSubmissions(c *gin.Context) {
 	})
 }
 
-// getMatchedTastingNote returns the matched note from correct notes, or empty string if no match
-func getMatchedTastingNote(userNote, correctNotes string) string {
-	fmt.Printf("🔍 [TASTING DEBUG] Checking note: user='%s' vs correct='%s'\n", userNote, correctNotes)
-	
-	if userNote == "" || correctNotes == "" {
-		fmt.Printf("⚠️ [TASTING DEBUG] Empty input - userNote='%s', correctNotes='%s'\n", userNote, correctNotes)
-		return ""
-	}
-	
-	// Clean and normalize user input
-	userNote = strings.TrimSpace(strings.ToLower(userNote))
-	fmt.Printf("🧹 [TASTING DEBUG] Normalized user note: '%s'\n", userNote)
-	
-	// Split correct notes by comma and check each one
-	correctNotesSlice := strings.Split(correctNotes, ",")
-	fmt.Printf("📝 [TASTING DEBUG] Split correct notes into %d parts: %v\n", len(correctNotesSlice), correctNotesSlice)
-	
-	for i, note := range correctNotesSlice {
-		originalNote := note
-		note = strings.TrimSpace(strings.ToLower(note))
-		fmt.Printf("🔍 [TASTING DEBUG] Checking note #%d: original='%s', normalized='%s'\n", i+1, originalNote, note)
-		
-		if note == "" {
-			fmt.Printf("⚠️ [TASTING DEBUG] Note #%d is empty after normalization, skipping\n", i+1)
+// GetUserSubmission returns one of the user's submissions. Once the case's
+// results are revealed each coffee answer comes with the correct answers and
+// the points earned per question.
+func GetUserSubmission(c *gin.Context) {
+	submissionID := c.Param("id")
+	userID := c.GetString("userID")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Other users' submissions are reported as missing
+	submission, err := database.DB.Submissions().Get(ctx, submissionID)
+	if err != nil || submission.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return
+	}
+
+	coffeeCase, err := database.DB.Cases().Get(ctx, submission.CaseID)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch case", "details": err.Error()})
+		return
+	}
+
+	response := gin.H{}
+	if coffeeCase != nil {
+		response["case"] = toPublicCase(coffeeCase)
+	}
+	response["submission"] = buildFeedback(submission, coffeeCase)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetSubmissionByID returns a submission with its per-question score breakdown (admin only)
+func GetSubmissionByID(c *gin.Context) {
+	submissionID := c.Param("id")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	submission, err := database.DB.Submissions().Get(ctx, submissionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"submission": submission})
+}
+
+// QuestionDiff describes how re-scoring changed a single question
+type QuestionDiff struct {
+	CoffeeID  string  `json:"coffee_id,omitempty"`
+	Question  string  `json:"question"`
+	Answer    string  `json:"answer"`
+	OldResult string  `json:"old_result"`
+	NewResult string  `json:"new_result"`
+	OldPoints float64 `json:"old_points"`
+	NewPoints float64 `json:"new_points"`
+}
+
+// SubmissionDiff describes how re-scoring changed a submission
+type SubmissionDiff struct {
+	SubmissionID string         `json:"submission_id"`
+	UserID       string         `json:"user_id"`
+	OldScore     int            `json:"old_score"`
+	NewScore     int            `json:"new_score"`
+	OldAccuracy  float64        `json:"old_accuracy"`
+	NewAccuracy  float64        `json:"new_accuracy"`
+	Questions    []QuestionDiff `json:"questions"`
+}
+
+// diffBreakdown lists the questions whose result or points differ between two breakdowns
+func diffBreakdown(oldBreakdown, newBreakdown []models.QuestionScore) []QuestionDiff {
+	previous := make(map[string]models.QuestionScore, len(oldBreakdown))
+	for _, score := range oldBreakdown {
+		previous[score.CoffeeID+"|"+score.Question] = score
+	}
+
+	diffs := []QuestionDiff{}
+	for _, score := range newBreakdown {
+		old, ok := previous[score.CoffeeID+"|"+score.Question]
+		if ok && old.Result == score.Result && old.Points == score.Points {
 			continue
 		}
-		
-		// Check for exact match
-		if userNote == note {
-			fmt.Printf("✅ [TASTING DEBUG] EXACT MATCH found: '%s' == '%s'\n", userNote, note)
-			return note
+		diffs = append(diffs, QuestionDiff{
+			CoffeeID:  score.CoffeeID,
+			Question:  score.Question,
+			Answer:    score.Answer,
+			OldResult: old.Result,
+			NewResult: score.Result,
+			OldPoints: old.Points,
+			NewPoints: score.Points,
+		})
+	}
+	return diffs
+}
+
+// RescoreCase re-runs scoring for every submission of a case and rebuilds the
+// stats and standings of affected users from their full history (admin only).
+// With ?dry_run=true nothing is written and the diffs are returned.
+func RescoreCase(c *gin.Context) {
+	caseID := c.Param("id")
+	dryRun := c.Query("dry_run") == "true"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	coffeeCase, err := database.DB.Cases().Get(ctx, caseID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Case not found"})
+		return
+	}
+
+	submissions, err := database.DB.Submissions().ListByCase(ctx, caseID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch submissions", "details": err.Error()})
+		return
+	}
+
+	diffs := []SubmissionDiff{}
+	scoringErrors := []gin.H{}
+	var rescored []models.Submission
+
+	for _, submission := range submissions {
+		result, err := scoring.Score(coffeeCase, &submission)
+		if err != nil {
+			scoringErrors = append(scoringErrors, gin.H{"submission_id": submission.ID, "error": err.Error()})
+			continue
+		}
+
+		questions := diffBreakdown(submission.Breakdown, result.Breakdown)
+		if result.Score == submission.Score && result.Accuracy == submission.Accuracy && len(questions) == 0 {
+			continue
+		}
+
+		diffs = append(diffs, SubmissionDiff{
+			SubmissionID: submission.ID,
+			UserID:       submission.UserID,
+			OldScore:     submission.Score,
+			NewScore:     result.Score,
+			OldAccuracy:  submission.Accuracy,
+			NewAccuracy:  result.Accuracy,
+			Questions:    questions,
+		})
+
+		applyScore(&submission, result)
+		rescored = append(rescored, submission)
+	}
+
+	response := gin.H{
+		"case_id":             caseID,
+		"dry_run":             dryRun,
+		"submissions_scanned": len(submissions),
+		"submissions_changed": len(diffs),
+		"diffs":               diffs,
+		"errors":              scoringErrors,
+	}
+
+	if dryRun {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	// Refuse to apply a partial re-score
+	if len(scoringErrors) > 0 {
+		response["error"] = "Some submissions could not be scored, nothing was changed"
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	now := time.Now()
+	affectedUsers := make(map[string]bool)
+	for i := range rescored {
+		rescored[i].ProcessedAt = &now
+		if err := database.DB.Submissions().Save(ctx, &rescored[i]); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save submission", "details": err.Error()})
+			return
+		}
+		affectedUsers[rescored[i].UserID] = true
+	}
+
+	season, seasonCases, err := leaderboard.SeasonFor(ctx, database.DB, coffeeCase)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch season", "details": err.Error()})
+		return
+	}
+
+	// Rebuild stats from the full history rather than adjusting them incrementally
+	for userID := range affectedUsers {
+		err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+			user, err := tx.Users().Get(ctx, userID)
+			if err != nil {
+				return err
+			}
+			history, err := tx.Submissions().ListByUser(ctx, userID, 0, 0)
+			if err != nil {
+				return err
+			}
+			definitions, err := tx.Badges().List(ctx)
+			if err != nil {
+				return err
+			}
+			rebuildUserStats(user, history)
+			// Corrected answers can earn badges but never take them away
+			badges.Evaluate(definitions, user, badges.StatsFor(user, history), now)
+			if err := tx.Users().Save(ctx, user); err != nil {
+				return err
+			}
+			if err := leaderboard.SaveStandings(ctx, tx, user, history, caseID); err != nil {
+				return err
+			}
+			return leaderboard.SaveSeasonStanding(ctx, tx, user, history, season, seasonCases)
+		})
+		if errors.Is(err, database.ErrNotFound) {
+			continue // Submissions of deleted users have no stats to rebuild
 		}
-		
-		// Check for partial match (user note contains correct note or vice versa)
-		if strings.Contains(userNote, note) {
-			fmt.Printf("✅ [TASTING DEBUG] PARTIAL MATCH found: user '%s' contains correct '%s'\n", userNote, note)
-			return note
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to recompute user stats", "details": err.Error()})
+			return
 		}
-		if strings.Contains(note, userNote) {
-			fmt.Printf("✅ [TASTING DEBUG] PARTIAL MATCH found: correct '%s' contains user '%s'\n", note, userNote)
-			return note
+	}
+
+	// Team standings average their members' rescored submissions
+	teams, err := database.DB.Teams().ListByCase(ctx, caseID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch teams", "details": err.Error()})
+		return
+	}
+	for i := range teams {
+		if err := leaderboard.RebuildTeam(ctx, database.DB, &teams[i]); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to recompute team standings", "details": err.Error()})
+			return
 		}
-		
-		fmt.Printf("❌ [TASTING DEBUG] No match for note #%d\n", i+1)
 	}
-	
-	fmt.Printf("❌ [TASTING DEBUG] No matches found for user note '%s'\n", userNote)
-	return ""
-}
 
-// matchesTastingNotes checks if a user's tasting note matches any of the comma-separated correct notes (legacy function)
-func matchesTastingNotes(userNote, correctNotes string) bool {
-	return getMatchedTastingNote(userNote, correctNotes) != ""
+	response["users_recomputed"] = len(affectedUsers)
+	response["teams_recomputed"] = len(teams)
+	c.JSON(http.StatusOK, response)
 }
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/leaderboard"
+	"brew-detective-backend/internal/models"
+)
+
+func submit(t *testing.T, userID, orderID string, coffeeAnswers []models.CoffeeAnswer) (int, string) {
+	rec := serve(t, userID, http.MethodPost, "/submissions", "/submissions",
+		models.Submission{OrderID: orderID, CoffeeAnswers: coffeeAnswers}, SubmitCase)
+	if rec.Code == http.StatusCreated {
+		return rec.Code, ""
+	}
+	return rec.Code, errorCode(t, rec)
+}
+
+func TestSubmitCaseClaimsOrderOnce(t *testing.T) {
+	useMemoryStore(t)
+	seedCase(t, "case1")
+	seedOrder(t, "ABC123", "owner", "case1", models.OrderStatusDelivered)
+
+	const players = 10
+	for i := 0; i < players; i++ {
+		seedUser(t, fmt.Sprintf("player%d", i))
+	}
+
+	var wg sync.WaitGroup
+	statuses := make([]int, players)
+	codes := make([]string, players)
+	for i := 0; i < players; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			statuses[i], codes[i] = submit(t, fmt.Sprintf("player%d", i), "ABC123", answers(true))
+		}(i)
+	}
+	wg.Wait()
+
+	winner := ""
+	for i, status := range statuses {
+		switch {
+		case status == http.StatusCreated:
+			if winner != "" {
+				t.Fatalf("both %s and player%d claimed the order", winner, i)
+			}
+			winner = fmt.Sprintf("player%d", i)
+		case status != http.StatusConflict || codes[i] != errOrderAlreadyClaimed.Code:
+			t.Errorf("player%d got %d %q, want %d %q", i, status, codes[i], http.StatusConflict, errOrderAlreadyClaimed.Code)
+		}
+	}
+	if winner == "" {
+		t.Fatal("nobody claimed the order")
+	}
+
+	ctx := context.Background()
+	submissions, err := database.DB.Submissions().ListByCase(ctx, "case1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(submissions) != 1 || submissions[0].UserID != winner {
+		t.Fatalf("got %d submissions, want one by %s", len(submissions), winner)
+	}
+
+	order, err := database.DB.Orders().GetByOrderID(ctx, "ABC123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !order.IsSubmissionUsed || order.SubmissionUsedBy != winner {
+		t.Errorf("order used by %q (used %v), want %s", order.SubmissionUsedBy, order.IsSubmissionUsed, winner)
+	}
+
+	for i := 0; i < players; i++ {
+		id := fmt.Sprintf("player%d", i)
+		user, err := database.DB.Users().Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantCases := 0
+		if id == winner {
+			wantCases = 1
+		}
+		if user.CasesCount != wantCases {
+			t.Errorf("%s has %d cases, want %d", id, user.CasesCount, wantCases)
+		}
+	}
+}
+
+func TestSubmitCaseUpdatesStatsAndStandings(t *testing.T) {
+	useMemoryStore(t)
+	seedCase(t, "case1")
+	seedUser(t, "player")
+	seedOrder(t, "ABC123", "player", "case1", models.OrderStatusDelivered)
+
+	if status, code := submit(t, "player", "ABC123", answers(true)); status != http.StatusCreated {
+		t.Fatalf("submit got %d %q", status, code)
+	}
+
+	ctx := context.Background()
+	user, err := database.DB.Users().Get(ctx, "player")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Points != 200 || user.CasesCount != 1 || user.Accuracy != 1 {
+		t.Errorf("stats = %d points, %d cases, %v accuracy; want 200, 1, 1", user.Points, user.CasesCount, user.Accuracy)
+	}
+
+	standing, err := database.DB.Standings().Get(ctx, leaderboard.CaseBoard("case1"), "player")
+	if err != nil {
+		t.Fatalf("case standing: %v", err)
+	}
+	if standing.Points != 200 {
+		t.Errorf("case standing has %d points, want 200", standing.Points)
+	}
+}
+
+func TestSubmitCaseRejectsUnusableOrders(t *testing.T) {
+	useMemoryStore(t)
+	seedCase(t, "case1")
+	seedUser(t, "player")
+	seedOrder(t, "PEND01", "player", "case1", models.OrderStatusShipped)
+
+	closed := seedCase(t, "case2")
+	if err := database.DB.Cases().Update(context.Background(), closed.ID, map[string]interface{}{"is_active": false}); err != nil {
+		t.Fatal(err)
+	}
+	seedOrder(t, "CLOSED", "player", "case2", models.OrderStatusDelivered)
+
+	tests := []struct {
+		order  string
+		status int
+		code   string
+	}{
+		{"NOPE00", errOrderNotFound.Status, errOrderNotFound.Code},
+		{"PEND01", errOrderNotDelivered.Status, errOrderNotDelivered.Code},
+		{"CLOSED", errCaseClosed.Status, errCaseClosed.Code},
+	}
+	for _, tt := range tests {
+		status, code := submit(t, "player", tt.order, answers(true))
+		if status != tt.status || code != tt.code {
+			t.Errorf("order %s: got %d %q, want %d %q", tt.order, status, code, tt.status, tt.code)
+		}
+	}
+}
+
+func TestSubmitCaseKeepsOrderOnInvalidAnswers(t *testing.T) {
+	useMemoryStore(t)
+	seedCase(t, "case1")
+	seedUser(t, "player")
+	seedOrder(t, "ABC123", "player", "case1", models.OrderStatusDelivered)
+
+	// Scoring rejects a coffee that is not in the case
+	bad := []models.CoffeeAnswer{{CoffeeID: "c9", Region: "huila"}}
+	if status, _ := submit(t, "player", "ABC123", bad); status != http.StatusBadRequest {
+		t.Fatalf("submit got %d, want %d", status, http.StatusBadRequest)
+	}
+
+	order, err := database.DB.Orders().GetByOrderID(context.Background(), "ABC123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.IsSubmissionUsed {
+		t.Error("a failed submission claimed the order")
+	}
+	if status, code := submit(t, "player", "ABC123", answers(true)); status != http.StatusCreated {
+		t.Errorf("retry got %d %q", status, code)
+	}
+}
@@ -0,0 +1,443 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/leaderboard"
+	"brew-detective-backend/internal/models"
+	"brew-detective-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxTeamMembers is how many players, the owner included, can share an order
+var maxTeamMembers = utils.IntFromEnv("TEAM_MAX_MEMBERS", 4)
+
+const maxTeamNameLength = 40
+
+var (
+	errTeamOrderNotOwned = &submissionError{
+		Status:  http.StatusForbidden,
+		Code:    "team_order_not_owned",
+		Message: "Solo quien hizo el pedido puede formar un equipo con él.",
+	}
+	errTeamExists = &submissionError{
+		Status:  http.StatusConflict,
+		Code:    "team_exists",
+		Message: "Este pedido ya tiene un equipo.",
+	}
+	errTeamNotFound = &submissionError{
+		Status:  http.StatusNotFound,
+		Code:    "team_not_found",
+		Message: "El equipo no existe o no perteneces a él.",
+	}
+	errInviteNotFound = &submissionError{
+		Status:  http.StatusNotFound,
+		Code:    "invite_not_found",
+		Message: "Código de invitación no válido.",
+	}
+	errTeamFull = &submissionError{
+		Status:  http.StatusConflict,
+		Code:    "team_full",
+		Message: "El equipo ya está completo.",
+	}
+	errTeamOwnerCannotLeave = &submissionError{
+		Status:  http.StatusConflict,
+		Code:    "team_owner",
+		Message: "Quien hizo el pedido no puede salir del equipo.",
+	}
+	errTeamMemberLocked = &submissionError{
+		Status:  http.StatusConflict,
+		Code:    "team_member_submitted",
+		Message: "No se puede quitar a un miembro que ya envió sus respuestas.",
+	}
+)
+
+// teamFields is the body of a team creation
+type teamFields struct {
+	OrderID string `json:"order_id"`
+	Name    string `json:"name"`
+	Mode    string `json:"mode"`
+}
+
+// isTeamMember reports whether the user belongs to the team
+func isTeamMember(team *models.Team, userID string) bool {
+	for _, memberID := range team.MemberIDs {
+		if memberID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// teamView hides the invite code from everyone but the team's owner
+func teamView(team models.Team, userID string) models.Team {
+	if team.OwnerID != userID {
+		team.InviteCode = ""
+	}
+	return team
+}
+
+// respondTeamError reports a failed team change
+func respondTeamError(c *gin.Context, err error, failure string) {
+	var subErr *submissionError
+	if errors.As(err, &subErr) {
+		c.JSON(subErr.Status, gin.H{"error": subErr.Message, "code": subErr.Code})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": failure, "details": err.Error()})
+}
+
+// CreateTeam lets an order's owner share the order with a team. The other
+// members join with the team's invite code.
+func CreateTeam(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var fields teamFields
+	if errs := decodeStrict(c.Request.Body, &fields); errs != nil {
+		respondInvalid(c, "Invalid team data", errs)
+		return
+	}
+	fields.Name = strings.TrimSpace(fields.Name)
+	if fields.Mode == "" {
+		fields.Mode = models.TeamModeSeparate
+	}
+
+	errs := fieldErrors{}
+	if fields.OrderID == "" {
+		errs.add("order_id", "is required")
+	}
+	if fields.Name == "" {
+		errs.add("name", "is required")
+	} else if len([]rune(fields.Name)) > maxTeamNameLength {
+		errs.add("name", "must be at most %d characters", maxTeamNameLength)
+	}
+	if fields.Mode != models.TeamModeSeparate && fields.Mode != models.TeamModeJoint {
+		errs.add("mode", "must be %q or %q", models.TeamModeSeparate, models.TeamModeJoint)
+	}
+	if len(errs) > 0 {
+		respondInvalid(c, "Invalid team data", errs)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	team := &models.Team{
+		ID:         uuid.New().String(),
+		Name:       fields.Name,
+		OwnerID:    userID,
+		MemberIDs:  []string{userID},
+		InviteCode: utils.GenerateInviteCode(),
+		Mode:       fields.Mode,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		order, err := tx.Orders().GetByOrderID(ctx, fields.OrderID)
+		if errors.Is(err, database.ErrNotFound) {
+			return errOrderNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if order.UserID != userID {
+			return errTeamOrderNotOwned
+		}
+		if order.TeamID != "" {
+			return errTeamExists
+		}
+		if order.IsSubmissionUsed {
+			return errOrderAlreadyClaimed
+		}
+
+		coffeeCase, err := orderCase(ctx, tx, order)
+		if err != nil {
+			return err
+		}
+		if coffeeCase.ResultsRevealedAt != nil {
+			return errCaseClosed
+		}
+
+		// Answers someone else started saving with the code stay theirs
+		draft, err := tx.Drafts().Get(ctx, order.OrderID)
+		if err == nil && draft.UserID != userID {
+			return errDraftInProgress
+		}
+		if err != nil && !errors.Is(err, database.ErrNotFound) {
+			return err
+		}
+
+		team.OrderID = order.OrderID
+		team.CaseID = coffeeCase.ID
+		order.TeamID = team.ID
+		order.UpdatedAt = now
+		if err := tx.Orders().Save(ctx, order); err != nil {
+			return err
+		}
+		return tx.Teams().Save(ctx, team)
+	})
+	if err != nil {
+		respondTeamError(c, err, "Failed to create team")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"team": team})
+}
+
+// JoinTeam adds the user to the team with the given invite code
+func JoinTeam(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	var request struct {
+		InviteCode string `json:"invite_code"`
+	}
+	if errs := decodeStrict(c.Request.Body, &request); errs != nil {
+		respondInvalid(c, "Invalid invite data", errs)
+		return
+	}
+	code := strings.ToUpper(strings.TrimSpace(request.InviteCode))
+	if code == "" {
+		respondInvalid(c, "Invalid invite data", fieldErrors{"invite_code": "is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var team *models.Team
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		var err error
+		team, err = tx.Teams().GetByInviteCode(ctx, code)
+		if errors.Is(err, database.ErrNotFound) {
+			return errInviteNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if isTeamMember(team, userID) {
+			return nil
+		}
+		if len(team.MemberIDs) >= maxTeamMembers {
+			return errTeamFull
+		}
+
+		// Joining is pointless once the order can no longer be submitted
+		order, err := tx.Orders().GetByOrderID(ctx, team.OrderID)
+		if err != nil {
+			return err
+		}
+		if order.IsSubmissionUsed {
+			return errOrderAlreadyClaimed
+		}
+		coffeeCase, err := tx.Cases().Get(ctx, team.CaseID)
+		if err != nil {
+			return err
+		}
+		if coffeeCase.ResultsRevealedAt != nil {
+			return errCaseClosed
+		}
+
+		team.MemberIDs = append(team.MemberIDs, userID)
+		team.UpdatedAt = time.Now()
+		return tx.Teams().Save(ctx, team)
+	})
+	if err != nil {
+		respondTeamError(c, err, "Failed to join team")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"team": teamView(*team, userID)})
+}
+
+// GetUserTeams returns the teams the user belongs to
+func GetUserTeams(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	teams, err := database.DB.Teams().ListByMember(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch teams", "details": err.Error()})
+		return
+	}
+
+	views := make([]models.Team, 0, len(teams))
+	for _, team := range teams {
+		views = append(views, teamView(team, userID))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"teams": views,
+		"count": len(views),
+	})
+}
+
+// GetTeam returns one of the user's teams with its members and whether each
+// has submitted
+func GetTeam(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Other teams are reported as missing
+	team, err := database.DB.Teams().Get(ctx, c.Param("id"))
+	if err != nil || !isTeamMember(team, userID) {
+		c.JSON(errTeamNotFound.Status, gin.H{"error": errTeamNotFound.Message, "code": errTeamNotFound.Code})
+		return
+	}
+
+	submissions, err := database.DB.Submissions().ListByTeam(ctx, team.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch submissions", "details": err.Error()})
+		return
+	}
+	submitted := make(map[string]bool)
+	for _, submission := range submissions {
+		submitted[submission.UserID] = true
+	}
+
+	members := make([]gin.H, 0, len(team.MemberIDs))
+	for _, memberID := range team.MemberIDs {
+		name := "Detective"
+		if member, err := database.DB.Users().Get(ctx, memberID); err == nil {
+			name = member.Name
+		}
+		members = append(members, gin.H{
+			"user_id":   memberID,
+			"name":      name,
+			"owner":     memberID == team.OwnerID,
+			"submitted": submitted[memberID],
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"team":    teamView(*team, userID),
+		"members": members,
+	})
+}
+
+// RemoveTeamMember lets the team's owner remove a member, or a member leave.
+// Members who have submitted stay, since their answers count for the team.
+func RemoveTeamMember(c *gin.Context) {
+	userID := c.GetString("userID")
+	memberID := c.Param("user_id")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := database.DB.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		team, err := tx.Teams().Get(ctx, c.Param("id"))
+		if errors.Is(err, database.ErrNotFound) {
+			return errTeamNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if !isTeamMember(team, userID) || (userID != team.OwnerID && userID != memberID) || !isTeamMember(team, memberID) {
+			return errTeamNotFound
+		}
+		if memberID == team.OwnerID {
+			return errTeamOwnerCannotLeave
+		}
+
+		submissions, err := tx.Submissions().ListByTeam(ctx, team.ID)
+		if err != nil {
+			return err
+		}
+		for _, submission := range submissions {
+			if submission.UserID == memberID {
+				return errTeamMemberLocked
+			}
+		}
+
+		members := make([]string, 0, len(team.MemberIDs)-1)
+		for _, id := range team.MemberIDs {
+			if id != memberID {
+				members = append(members, id)
+			}
+		}
+		team.MemberIDs = members
+		team.UpdatedAt = time.Now()
+		return tx.Teams().Save(ctx, team)
+	})
+	if err != nil {
+		respondTeamError(c, err, "Failed to remove team member")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team member removed"})
+}
+
+// GetCaseTeamLeaderboard returns the team leaderboard of a case. Teams are
+// ranked by the average score of their submissions, and authenticated users
+// also get the rank of their team.
+func GetCaseTeamLeaderboard(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	coffeeCase, err := database.DB.Cases().Get(ctx, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Case not found"})
+		return
+	}
+
+	board := leaderboard.TeamBoard(coffeeCase.ID)
+	page, err := leaderboard.GetPage(ctx, database.DB, board, c.Query("cursor"), leaderboardLimit(c))
+	if errors.Is(err, leaderboard.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leaderboard", "details": err.Error()})
+		return
+	}
+
+	entries := make([]models.TeamLeaderboardEntry, 0, len(page.Entries))
+	for _, entry := range page.Entries {
+		entries = append(entries, leaderboard.TeamEntry(entry))
+	}
+	response := gin.H{
+		"case_id":     coffeeCase.ID,
+		"case_name":   coffeeCase.Name,
+		"leaderboard": entries,
+		"total_teams": page.Total,
+		"next_cursor": page.NextCursor,
+	}
+
+	if userID := c.GetString("userID"); userID != "" {
+		teams, err := database.DB.Teams().ListByMember(ctx, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch teams", "details": err.Error()})
+			return
+		}
+		// Users without a ranked team for the case get a null rank
+		var myTeam *models.TeamLeaderboardEntry
+		for _, team := range teams {
+			if team.CaseID != coffeeCase.ID {
+				continue
+			}
+			entry, err := leaderboard.RankOf(ctx, database.DB, board, team.ID)
+			if err != nil && !errors.Is(err, database.ErrNotFound) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leaderboard", "details": err.Error()})
+				return
+			}
+			if entry != nil {
+				teamEntry := leaderboard.TeamEntry(*entry)
+				myTeam = &teamEntry
+				break
+			}
+		}
+		response["my_team"] = myTeam
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/leaderboard"
+	"brew-detective-backend/internal/models"
+)
+
+// createTeam shares an order as a team and returns the team
+func createTeam(t *testing.T, ownerID, orderID, mode string) models.Team {
+	t.Helper()
+	rec := serve(t, ownerID, http.MethodPost, "/teams", "/teams",
+		teamFields{OrderID: orderID, Name: "Los Catadores", Mode: mode}, CreateTeam)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("creating team got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Team models.Team `json:"team"`
+	}
+	decode(t, rec, &body)
+	return body.Team
+}
+
+func joinTeam(t *testing.T, userID, inviteCode string) int {
+	return serve(t, userID, http.MethodPost, "/teams/join", "/teams/join",
+		map[string]string{"invite_code": inviteCode}, JoinTeam).Code
+}
+
+// seedTeamOrder seeds an open case, a delivered order of its owner and the
+// given players
+func seedTeamOrder(t *testing.T, players ...string) {
+	t.Helper()
+	useMemoryStore(t)
+	seedCase(t, "case1")
+	seedOrder(t, "ABC123", "owner", "case1", models.OrderStatusDelivered)
+	seedUser(t, "owner")
+	for _, player := range players {
+		seedUser(t, player)
+	}
+}
+
+func TestCreateTeamOnlyByOrderOwner(t *testing.T) {
+	seedTeamOrder(t, "friend")
+
+	rec := serve(t, "friend", http.MethodPost, "/teams", "/teams",
+		teamFields{OrderID: "ABC123", Name: "Intrusos"}, CreateTeam)
+	if code := errorCode(t, rec); rec.Code != http.StatusForbidden || code != errTeamOrderNotOwned.Code {
+		t.Errorf("non-owner got %d %q", rec.Code, code)
+	}
+
+	team := createTeam(t, "owner", "ABC123", "")
+	if team.Mode != models.TeamModeSeparate || team.InviteCode == "" {
+		t.Errorf("team created as %q with invite %q", team.Mode, team.InviteCode)
+	}
+
+	rec = serve(t, "owner", http.MethodPost, "/teams", "/teams",
+		teamFields{OrderID: "ABC123", Name: "Otra vez"}, CreateTeam)
+	if code := errorCode(t, rec); rec.Code != http.StatusConflict || code != errTeamExists.Code {
+		t.Errorf("second team got %d %q", rec.Code, code)
+	}
+}
+
+func TestTeamPlayingSeparately(t *testing.T) {
+	seedTeamOrder(t, "friend", "stranger")
+	team := createTeam(t, "owner", "ABC123", models.TeamModeSeparate)
+
+	if code := joinTeam(t, "friend", team.InviteCode); code != http.StatusOK {
+		t.Fatalf("joining got %d", code)
+	}
+	if status, code := submit(t, "stranger", "ABC123", answers(true)); code != errNotTeamMember.Code {
+		t.Errorf("non-member submit got %d %q", status, code)
+	}
+
+	// Each member submits once and the order stays open for the others
+	if status, code := submit(t, "owner", "ABC123", answers(true)); status != http.StatusCreated {
+		t.Fatalf("owner submit got %d %q", status, code)
+	}
+	if status, code := submit(t, "owner", "ABC123", answers(true)); code != errTeamMemberSubmitted.Code {
+		t.Errorf("second owner submit got %d %q", status, code)
+	}
+	if status, code := submit(t, "friend", "ABC123", answers(false)); status != http.StatusCreated {
+		t.Fatalf("member submit got %d %q", status, code)
+	}
+
+	// The team ranks by the average of its members
+	standing, err := database.DB.Standings().Get(context.Background(), leaderboard.TeamBoard("case1"), team.ID)
+	if err != nil {
+		t.Fatalf("team standing: %v", err)
+	}
+	if standing.Points != 100 || standing.Accuracy != 0.5 {
+		t.Errorf("team standing = %d points, %v accuracy; want 100, 0.5", standing.Points, standing.Accuracy)
+	}
+
+	// Members who have submitted cannot be removed
+	rec := serve(t, "owner", http.MethodDelete, "/teams/:id/members/:user_id", "/teams/"+team.ID+"/members/friend",
+		nil, RemoveTeamMember)
+	if code := errorCode(t, rec); code != errTeamMemberLocked.Code {
+		t.Errorf("removing a member who submitted got %d %q", rec.Code, code)
+	}
+}
+
+func TestTeamPlayingJointly(t *testing.T) {
+	seedTeamOrder(t, "friend")
+	team := createTeam(t, "owner", "ABC123", models.TeamModeJoint)
+	if code := joinTeam(t, "friend", team.InviteCode); code != http.StatusOK {
+		t.Fatalf("joining got %d", code)
+	}
+
+	if status, code := submit(t, "friend", "ABC123", answers(true)); status != http.StatusCreated {
+		t.Fatalf("member submit got %d %q", status, code)
+	}
+	if status, code := submit(t, "owner", "ABC123", answers(true)); code != errOrderAlreadyClaimed.Code {
+		t.Errorf("second joint submit got %d %q", status, code)
+	}
+	if code := joinTeam(t, "latecomer", team.InviteCode); code != errOrderAlreadyClaimed.Status {
+		t.Errorf("joining a submitted team got %d", code)
+	}
+}
+
+func TestTeamMembership(t *testing.T) {
+	seedTeamOrder(t, "friend", "third")
+	defer func(max int) { maxTeamMembers = max }(maxTeamMembers)
+	maxTeamMembers = 2
+
+	team := createTeam(t, "owner", "ABC123", "")
+	if code := joinTeam(t, "friend", "nope"); code != errInviteNotFound.Status {
+		t.Errorf("unknown invite got %d", code)
+	}
+	if code := joinTeam(t, "friend", team.InviteCode); code != http.StatusOK {
+		t.Fatalf("joining got %d", code)
+	}
+	if code := joinTeam(t, "third", team.InviteCode); code != errTeamFull.Status {
+		t.Errorf("joining a full team got %d", code)
+	}
+
+	// Only the owner sees the invite code
+	rec := serve(t, "friend", http.MethodGet, "/teams/:id", "/teams/"+team.ID, nil, GetTeam)
+	var body struct {
+		Team models.Team `json:"team"`
+	}
+	decode(t, rec, &body)
+	if rec.Code != http.StatusOK || body.Team.InviteCode != "" {
+		t.Errorf("member got %d with invite %q", rec.Code, body.Team.InviteCode)
+	}
+	if rec := serve(t, "third", http.MethodGet, "/teams/:id", "/teams/"+team.ID, nil, GetTeam); rec.Code != http.StatusNotFound {
+		t.Errorf("non-member got %d", rec.Code)
+	}
+
+	// Members can leave, the owner cannot
+	leave := func(userID, memberID string) *httptest.ResponseRecorder {
+		return serve(t, userID, http.MethodDelete, "/teams/:id/members/:user_id", "/teams/"+team.ID+"/members/"+memberID,
+			nil, RemoveTeamMember)
+	}
+	if rec := leave("owner", "owner"); errorCode(t, rec) != errTeamOwnerCannotLeave.Code {
+		t.Errorf("owner leaving got %d", rec.Code)
+	}
+	if rec := leave("friend", "friend"); rec.Code != http.StatusOK {
+		t.Errorf("member leaving got %d", rec.Code)
+	}
+	if code := joinTeam(t, "third", team.InviteCode); code != http.StatusOK {
+		t.Errorf("joining after a member left got %d", code)
+	}
+}
+
+func TestDraftsOfTeamMembersPlayingSeparately(t *testing.T) {
+	seedTeamOrder(t, "friend")
+	team := createTeam(t, "owner", "ABC123", models.TeamModeSeparate)
+	if code := joinTeam(t, "friend", team.InviteCode); code != http.StatusOK {
+		t.Fatalf("joining got %d", code)
+	}
+
+	saveDraft(t, "owner", "ABC123", coffeeFields("c1", "region", "huila"))
+	rec := saveDraft(t, "friend", "ABC123", coffeeFields("c1", "region", "cauca"))
+	var saved draftResponse
+	decode(t, rec, &saved)
+	if rec.Code != http.StatusOK || saved.Draft.CoffeeAnswers[0].Region != "cauca" {
+		t.Fatalf("member save got %d %+v", rec.Code, saved.Draft)
+	}
+
+	rec = serve(t, "owner", http.MethodGet, "/drafts/:order_id", "/drafts/ABC123", nil, GetDraft)
+	var own draftResponse
+	decode(t, rec, &own)
+	if own.Draft.CoffeeAnswers[0].Region != "huila" {
+		t.Errorf("the member's save changed the owner's draft: %+v", own.Draft.CoffeeAnswers)
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fieldErrors maps a field path, e.g. "coffees[0].region", to what is wrong with it
+type fieldErrors map[string]string
+
+func (e fieldErrors) add(field, format string, args ...interface{}) {
+	if _, exists := e[field]; !exists {
+		e[field] = fmt.Sprintf(format, args...)
+	}
+}
+
+// respondInvalid rejects a request with its errors listed field by field
+func respondInvalid(c *gin.Context, message string, errs fieldErrors) {
+	c.JSON(http.StatusBadRequest, gin.H{"error": message, "fields": errs})
+}
+
+// decodeStrict decodes JSON into dst, rejecting fields dst does not have
+func decodeStrict(r io.Reader, dst interface{}) fieldErrors {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		return decodeErrors(err)
+	}
+	return nil
+}
+
+// decodeErrors turns a JSON decoding error into field errors where the
+// decoder names the field
+func decodeErrors(err error) fieldErrors {
+	errs := fieldErrors{}
+
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		errs.add(typeErr.Field, "must be of type %s", typeErr.Type)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		errs.add(field, "unknown field")
+	default:
+		errs.add("body", "invalid JSON: %v", err)
+	}
+	return errs
+}
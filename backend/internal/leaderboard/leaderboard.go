@@ -0,0 +1,365 @@
+// Package leaderboard keeps precomputed standings up to date as submissions are
+// scored and serves them in ranked, cursor-paginated pages.
+package leaderboard
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+)
+
+// GlobalBoard ranks users by their totals across every case
+const GlobalBoard = "global"
+
+// ErrInvalidCursor is returned for cursors that were not issued by GetPage
+var ErrInvalidCursor = errors.New("invalid leaderboard cursor")
+
+// CaseBoard is the board ranking the submissions of a single case
+func CaseBoard(caseID string) string {
+	return "case_" + caseID
+}
+
+// GlobalStanding builds a user's global standing. It is reached with the
+// user's latest submission.
+func GlobalStanding(user *models.User, history []models.Submission) models.Standing {
+	standing := models.Standing{
+		Board:         GlobalBoard,
+		UserID:        user.ID,
+		DetectiveName: user.Name,
+		Points:        user.Points,
+		Accuracy:      user.Accuracy,
+		CasesCount:    user.CasesCount,
+		Badges:        user.Badges,
+		UpdatedAt:     time.Now(),
+	}
+	for _, submission := range history {
+		if submission.SubmittedAt.After(standing.ReachedAt) {
+			standing.ReachedAt = submission.SubmittedAt
+		}
+	}
+	return standing
+}
+
+// CaseStanding builds a user's standing on a case board from their best
+// submission for the case. It returns false when the user has not played it.
+func CaseStanding(user *models.User, history []models.Submission, caseID string) (models.Standing, bool) {
+	var best *models.Standing
+	for _, submission := range history {
+		if submission.CaseID != caseID {
+			continue
+		}
+		candidate := models.Standing{
+			Points:    submission.Score,
+			Accuracy:  submission.Accuracy,
+			ReachedAt: submission.SubmittedAt,
+			UserID:    user.ID,
+		}
+		if best == nil || database.RanksBefore(&candidate, best) {
+			best = &candidate
+		}
+	}
+	if best == nil {
+		return models.Standing{}, false
+	}
+
+	best.Board = CaseBoard(caseID)
+	best.DetectiveName = user.Name
+	best.CasesCount = 1
+	best.Badges = user.Badges
+	best.UpdatedAt = time.Now()
+	return *best, true
+}
+
+// SaveStandings writes the user's global standing and their standing on each
+// of the given cases, or removes them when the user hides from leaderboards.
+// It only writes, so it can run at the end of a transaction.
+func SaveStandings(ctx context.Context, store database.Store, user *models.User, history []models.Submission, caseIDs ...string) error {
+	if user.Privacy.HideFromLeaderboards {
+		if err := store.Standings().Delete(ctx, GlobalBoard, user.ID); err != nil {
+			return err
+		}
+		for _, caseID := range caseIDs {
+			if err := store.Standings().Delete(ctx, CaseBoard(caseID), user.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	global := GlobalStanding(user, history)
+	if err := store.Standings().Save(ctx, &global); err != nil {
+		return err
+	}
+	for _, caseID := range caseIDs {
+		standing, ok := CaseStanding(user, history, caseID)
+		if !ok {
+			continue
+		}
+		if err := store.Standings().Save(ctx, &standing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SyncUser copies the user's current name and badges onto all their standings
+// except the final snapshots
+func SyncUser(ctx context.Context, store database.Store, user *models.User) error {
+	standings, err := store.Standings().ListByUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	for _, standing := range standings {
+		if IsFinal(standing.Board) {
+			continue
+		}
+		standing.DetectiveName = user.Name
+		standing.Badges = user.Badges
+		standing.UpdatedAt = time.Now()
+		if err := store.Standings().Save(ctx, &standing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rebuild recomputes every live standing from the users, the teams and their
+// submission history. Final snapshots are left untouched.
+func Rebuild(ctx context.Context, store database.Store) (int, error) {
+	users, err := store.Users().List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	seasons, err := store.Seasons().List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for i := range seasons {
+		if _, err := RebuildSeason(ctx, store, &seasons[i]); err != nil {
+			return 0, err
+		}
+	}
+
+	teams, err := store.Teams().List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for i := range teams {
+		if err := RebuildTeam(ctx, store, &teams[i]); err != nil {
+			return 0, err
+		}
+	}
+
+	rebuilt := 0
+	for i := range users {
+		user := &users[i]
+		history, err := store.Submissions().ListByUser(ctx, user.ID, 0, 0)
+		if err != nil {
+			return rebuilt, err
+		}
+		if len(history) == 0 || user.Privacy.HideFromLeaderboards {
+			continue
+		}
+		if err := SaveStandings(ctx, store, user, history, playedCases(history)...); err != nil {
+			return rebuilt, err
+		}
+		rebuilt++
+	}
+	return rebuilt, nil
+}
+
+// RebuildUser recomputes a user's live standings on every board, removing
+// them when the user hides from leaderboards
+func RebuildUser(ctx context.Context, store database.Store, user *models.User) error {
+	history, err := store.Submissions().ListByUser(ctx, user.ID, 0, 0)
+	if err != nil {
+		return err
+	}
+	seasons, err := store.Seasons().List(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(history) > 0 {
+		if err := SaveStandings(ctx, store, user, history, playedCases(history)...); err != nil {
+			return err
+		}
+	}
+	for i := range seasons {
+		caseIDs, err := seasonCaseIDs(ctx, store, &seasons[i])
+		if err != nil {
+			return err
+		}
+		if err := SaveSeasonStanding(ctx, store, user, history, &seasons[i], caseIDs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// playedCases lists the distinct cases of a submission history
+func playedCases(history []models.Submission) []string {
+	caseIDs := make([]string, 0, len(history))
+	seen := make(map[string]bool)
+	for _, submission := range history {
+		if !seen[submission.CaseID] {
+			seen[submission.CaseID] = true
+			caseIDs = append(caseIDs, submission.CaseID)
+		}
+	}
+	return caseIDs
+}
+
+// EnsureBuilt rebuilds the standings when the global board is empty, which
+// happens the first time the server runs with precomputed leaderboards
+func EnsureBuilt(ctx context.Context, store database.Store) error {
+	count, err := store.Standings().Count(ctx, GlobalBoard)
+	if err != nil || count > 0 {
+		return err
+	}
+	_, err = Rebuild(ctx, store)
+	return err
+}
+
+// cursor identifies the last entry of a page
+type cursor struct {
+	Points    int       `json:"p"`
+	Accuracy  float64   `json:"a"`
+	ReachedAt time.Time `json:"t"`
+	UserID    string    `json:"u"`
+	Rank      int       `json:"r"`
+}
+
+func encodeCursor(entry models.LeaderboardEntryWithUser, standing models.Standing) string {
+	data, _ := json.Marshal(cursor{
+		Points:    standing.Points,
+		Accuracy:  standing.Accuracy,
+		ReachedAt: standing.ReachedAt,
+		UserID:    standing.UserID,
+		Rank:      entry.Rank,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(encoded string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.UserID == "" || c.Rank < 1 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// Page is one page of a leaderboard
+type Page struct {
+	Entries    []models.LeaderboardEntryWithUser `json:"leaderboard"`
+	NextCursor string                            `json:"next_cursor,omitempty"`
+	Total      int                               `json:"total_users"`
+}
+
+// GetPage returns up to limit entries of a board following the cursor
+// returned with the previous page, or the first page for an empty cursor
+func GetPage(ctx context.Context, store database.Store, board, encodedCursor string, limit int) (*Page, error) {
+	var after *models.Standing
+	rank := 0
+	if encodedCursor != "" {
+		c, err := decodeCursor(encodedCursor)
+		if err != nil {
+			return nil, err
+		}
+		after = &models.Standing{Points: c.Points, Accuracy: c.Accuracy, ReachedAt: c.ReachedAt, UserID: c.UserID}
+		rank = c.Rank
+	}
+
+	hidden, err := hiddenStandings(ctx, store, board)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch one extra standing to know whether there is a next page
+	var standings []models.Standing
+	for len(standings) <= limit {
+		batch, err := store.Standings().Page(ctx, board, after, limit+1)
+		if err != nil {
+			return nil, err
+		}
+		for _, standing := range batch {
+			if _, ok := hidden[standing.UserID]; !ok {
+				standings = append(standings, standing)
+			}
+		}
+		if len(batch) <= limit {
+			break
+		}
+		after = &batch[len(batch)-1]
+	}
+	total, err := store.Standings().Count(ctx, board)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page{Entries: []models.LeaderboardEntryWithUser{}, Total: total - len(hidden)}
+	for i, standing := range standings {
+		if i == limit {
+			last := len(page.Entries) - 1
+			page.NextCursor = encodeCursor(page.Entries[last], standings[last])
+			break
+		}
+		rank++
+		page.Entries = append(page.Entries, entryFor(standing, rank))
+	}
+	return page, nil
+}
+
+// RankOf returns the user's entry on a board with its absolute rank, or
+// database.ErrNotFound when the user is not ranked on it
+func RankOf(ctx context.Context, store database.Store, board, userID string) (*models.LeaderboardEntryWithUser, error) {
+	hidden, err := hiddenStandings(ctx, store, board)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := hidden[userID]; ok {
+		return nil, database.ErrNotFound
+	}
+
+	standing, err := store.Standings().Get(ctx, board, userID)
+	if err != nil {
+		return nil, err
+	}
+	ahead, err := store.Standings().CountAhead(ctx, standing)
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range hidden {
+		if database.RanksBefore(&other, standing) {
+			ahead--
+		}
+	}
+	entry := entryFor(*standing, ahead+1)
+	return &entry, nil
+}
+
+func entryFor(standing models.Standing, rank int) models.LeaderboardEntryWithUser {
+	badges := standing.Badges
+	if badges == nil {
+		badges = []models.UserBadge{}
+	}
+	return models.LeaderboardEntryWithUser{
+		UserID:        standing.UserID,
+		DetectiveName: standing.DetectiveName,
+		Points:        standing.Points,
+		Accuracy:      standing.Accuracy,
+		CasesCount:    standing.CasesCount,
+		Badges:        badges,
+		Rank:          rank,
+	}
+}
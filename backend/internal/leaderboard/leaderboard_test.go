@@ -0,0 +1,190 @@
+package leaderboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+)
+
+func TestCaseStandingKeepsTheBestSubmission(t *testing.T) {
+	start := time.Now()
+	user := &models.User{ID: "u1", Name: "Detective"}
+	history := []models.Submission{
+		{CaseID: "case1", Score: 200, Accuracy: 0.5, SubmittedAt: start.Add(2 * time.Hour)},
+		{CaseID: "case1", Score: 200, Accuracy: 0.5, SubmittedAt: start.Add(time.Hour)},
+		{CaseID: "case1", Score: 100, Accuracy: 1, SubmittedAt: start},
+		{CaseID: "case2", Score: 900, Accuracy: 1, SubmittedAt: start},
+	}
+
+	standing, ok := CaseStanding(user, history, "case1")
+	if !ok {
+		t.Fatal("no standing for a played case")
+	}
+	// Equal results rank by whoever reached them first
+	if standing.Points != 200 || !standing.ReachedAt.Equal(start.Add(time.Hour)) {
+		t.Errorf("standing = %d points reached at %v", standing.Points, standing.ReachedAt)
+	}
+	if standing.Board != CaseBoard("case1") || standing.DetectiveName != "Detective" || standing.CasesCount != 1 {
+		t.Errorf("standing = %+v", standing)
+	}
+
+	if _, ok := CaseStanding(user, history, "case3"); ok {
+		t.Error("standing for a case the user has not played")
+	}
+}
+
+func TestGetPageWalksTheBoardInRankOrder(t *testing.T) {
+	ctx := context.Background()
+	store := database.NewMemoryStore()
+	start := time.Now()
+	// Ties on points break on accuracy, then on who reached them first
+	for _, standing := range []models.Standing{
+		{UserID: "d", Points: 100, Accuracy: 0.5, ReachedAt: start},
+		{UserID: "b", Points: 300, Accuracy: 0.5, ReachedAt: start.Add(time.Hour)},
+		{UserID: "e", Points: 50, Accuracy: 1, ReachedAt: start},
+		{UserID: "a", Points: 300, Accuracy: 0.5, ReachedAt: start},
+		{UserID: "c", Points: 300, Accuracy: 0.4, ReachedAt: start},
+	} {
+		standing.Board = GlobalBoard
+		if err := store.Standings().Save(ctx, &standing); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var users []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("too many pages")
+		}
+		page, err := GetPage(ctx, store, GlobalBoard, cursor, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != 5 {
+			t.Errorf("total = %d, want 5", page.Total)
+		}
+		for _, entry := range page.Entries {
+			users = append(users, entry.UserID)
+			if entry.Rank != len(users) {
+				t.Errorf("%s ranked %d, want %d", entry.UserID, entry.Rank, len(users))
+			}
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if got := fmt.Sprint(users); got != "[a b c d e]" {
+		t.Fatalf("board = %s, want [a b c d e]", got)
+	}
+
+	entry, err := RankOf(ctx, store, GlobalBoard, "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Rank != 4 || entry.Badges == nil {
+		t.Errorf("d ranked %d with badges %v, want 4 and an empty list", entry.Rank, entry.Badges)
+	}
+	if _, err := RankOf(ctx, store, GlobalBoard, "z"); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("RankOf an unranked user = %v, want ErrNotFound", err)
+	}
+}
+
+func TestGetPageRejectsInvalidCursors(t *testing.T) {
+	store := database.NewMemoryStore()
+	for _, cursor := range []string{"not base64!", "bm90IGpzb24", "e30"} {
+		if _, err := GetPage(context.Background(), store, GlobalBoard, cursor, 10); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("GetPage(%q) = %v, want ErrInvalidCursor", cursor, err)
+		}
+	}
+}
+
+func TestSaveStandingsRemovesHiddenUsers(t *testing.T) {
+	ctx := context.Background()
+	store := database.NewMemoryStore()
+	user := &models.User{ID: "u1", Name: "Detective", Points: 100}
+	history := []models.Submission{{CaseID: "case1", Score: 100, SubmittedAt: time.Now()}}
+
+	if err := SaveStandings(ctx, store, user, history, "case1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, board := range []string{GlobalBoard, CaseBoard("case1")} {
+		if _, err := store.Standings().Get(ctx, board, "u1"); err != nil {
+			t.Fatalf("standing on %s: %v", board, err)
+		}
+	}
+
+	user.Privacy.HideFromLeaderboards = true
+	if err := SaveStandings(ctx, store, user, history, "case1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, board := range []string{GlobalBoard, CaseBoard("case1")} {
+		if _, err := store.Standings().Get(ctx, board, "u1"); !errors.Is(err, database.ErrNotFound) {
+			t.Errorf("hidden user still on %s: %v", board, err)
+		}
+	}
+}
+
+func TestRebuildRanksPlayersFromTheirHistory(t *testing.T) {
+	ctx := context.Background()
+	store := database.NewMemoryStore()
+	for _, user := range []*models.User{
+		{ID: "player", Name: "Player", Points: 300, CasesCount: 2},
+		{ID: "newcomer", Name: "Newcomer"},
+		{ID: "hidden", Name: "Hidden", Points: 500, Privacy: models.PrivacySettings{HideFromLeaderboards: true}},
+	} {
+		if err := store.Users().Save(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, submission := range []*models.Submission{
+		{ID: "s1", UserID: "player", CaseID: "case1", Score: 100, SubmittedAt: time.Now()},
+		{ID: "s2", UserID: "player", CaseID: "case2", Score: 200, SubmittedAt: time.Now()},
+		{ID: "s3", UserID: "hidden", CaseID: "case1", Score: 500, SubmittedAt: time.Now()},
+	} {
+		if err := store.Submissions().Save(ctx, submission); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rebuilt, err := Rebuild(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rebuilt != 1 {
+		t.Errorf("rebuilt %d users, want 1", rebuilt)
+	}
+	for board, want := range map[string]int{GlobalBoard: 1, CaseBoard("case1"): 1, CaseBoard("case2"): 1} {
+		count, err := store.Standings().Count(ctx, board)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != want {
+			t.Errorf("%s has %d standings, want %d", board, count, want)
+		}
+	}
+	global, err := store.Standings().Get(ctx, GlobalBoard, "player")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if global.Points != 300 || global.CasesCount != 2 {
+		t.Errorf("global standing = %+v", global)
+	}
+
+	// EnsureBuilt leaves a built board alone
+	if err := store.Standings().Delete(ctx, CaseBoard("case2"), "player"); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnsureBuilt(ctx, store); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := store.Standings().Count(ctx, CaseBoard("case2")); count != 0 {
+		t.Error("EnsureBuilt rebuilt a board that was already built")
+	}
+}
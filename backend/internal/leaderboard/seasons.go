@@ -0,0 +1,165 @@
+package leaderboard
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+)
+
+// SeasonBoard ranks users by their results in the cases of a season
+func SeasonBoard(seasonID string) string {
+	return "season_" + seasonID
+}
+
+// SeasonFor returns the season a case was created in and the IDs of every
+// case in that season, or a nil season when the case falls outside all seasons
+func SeasonFor(ctx context.Context, store database.Store, coffeeCase *models.CoffeeCase) (*models.Season, map[string]bool, error) {
+	seasons, err := store.Seasons().List(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range seasons {
+		season := &seasons[i]
+		if coffeeCase.CreatedAt.Before(season.StartsAt) || !coffeeCase.CreatedAt.Before(season.EndsAt) {
+			continue
+		}
+		caseIDs, err := seasonCaseIDs(ctx, store, season)
+		if err != nil {
+			return nil, nil, err
+		}
+		return season, caseIDs, nil
+	}
+	return nil, nil, nil
+}
+
+func seasonCaseIDs(ctx context.Context, store database.Store, season *models.Season) (map[string]bool, error) {
+	cases, err := store.Cases().ListCreatedBetween(ctx, season.StartsAt, season.EndsAt)
+	if err != nil {
+		return nil, err
+	}
+	caseIDs := make(map[string]bool, len(cases))
+	for _, coffeeCase := range cases {
+		caseIDs[coffeeCase.ID] = true
+	}
+	return caseIDs, nil
+}
+
+// SeasonStanding adds up a user's best result in each case of the season.
+// It returns false when the user has not played any of them.
+func SeasonStanding(user *models.User, history []models.Submission, season *models.Season, caseIDs map[string]bool) (models.Standing, bool) {
+	standing := models.Standing{
+		Board:         SeasonBoard(season.ID),
+		UserID:        user.ID,
+		DetectiveName: user.Name,
+		Badges:        user.Badges,
+		UpdatedAt:     time.Now(),
+	}
+
+	played := make(map[string]bool)
+	totalAccuracy := 0.0
+	for _, submission := range history {
+		if !caseIDs[submission.CaseID] || played[submission.CaseID] {
+			continue
+		}
+		played[submission.CaseID] = true
+
+		best, _ := CaseStanding(user, history, submission.CaseID)
+		standing.Points += best.Points
+		totalAccuracy += best.Accuracy
+		if best.ReachedAt.After(standing.ReachedAt) {
+			standing.ReachedAt = best.ReachedAt
+		}
+	}
+	if len(played) == 0 {
+		return models.Standing{}, false
+	}
+
+	standing.CasesCount = len(played)
+	standing.Accuracy = totalAccuracy / float64(len(played))
+	return standing, true
+}
+
+// SaveSeasonStanding writes the user's standing on the season board, or removes
+// it when the user hides from leaderboards. It only writes, so it can run at
+// the end of a transaction.
+func SaveSeasonStanding(ctx context.Context, store database.Store, user *models.User, history []models.Submission, season *models.Season, caseIDs map[string]bool) error {
+	if season == nil {
+		return nil
+	}
+	if user.Privacy.HideFromLeaderboards {
+		return store.Standings().Delete(ctx, SeasonBoard(season.ID), user.ID)
+	}
+	standing, ok := SeasonStanding(user, history, season, caseIDs)
+	if !ok {
+		return nil
+	}
+	return store.Standings().Save(ctx, &standing)
+}
+
+// RebuildSeason recomputes a season's board from the submissions to its cases
+func RebuildSeason(ctx context.Context, store database.Store, season *models.Season) (int, error) {
+	if err := clearBoard(ctx, store, SeasonBoard(season.ID)); err != nil {
+		return 0, err
+	}
+
+	caseIDs, err := seasonCaseIDs(ctx, store, season)
+	if err != nil {
+		return 0, err
+	}
+
+	histories := make(map[string][]models.Submission)
+	for caseID := range caseIDs {
+		submissions, err := store.Submissions().ListByCase(ctx, caseID)
+		if err != nil {
+			return 0, err
+		}
+		for _, submission := range submissions {
+			histories[submission.UserID] = append(histories[submission.UserID], submission)
+		}
+	}
+
+	ranked := 0
+	for userID, history := range histories {
+		user, err := store.Users().Get(ctx, userID)
+		if errors.Is(err, database.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return ranked, err
+		}
+		if user.Privacy.HideFromLeaderboards {
+			continue
+		}
+		if err := SaveSeasonStanding(ctx, store, user, history, season, caseIDs); err != nil {
+			return ranked, err
+		}
+		ranked++
+	}
+	return ranked, nil
+}
+
+// DeleteSeasonStandings removes a season's board
+func DeleteSeasonStandings(ctx context.Context, store database.Store, seasonID string) error {
+	return clearBoard(ctx, store, SeasonBoard(seasonID))
+}
+
+// clearBoard deletes every standing of a board
+func clearBoard(ctx context.Context, store database.Store, board string) error {
+	for {
+		standings, err := store.Standings().Page(ctx, board, nil, 500)
+		if err != nil {
+			return err
+		}
+		if len(standings) == 0 {
+			return nil
+		}
+		for _, standing := range standings {
+			if err := store.Standings().Delete(ctx, board, standing.UserID); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package leaderboard
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+)
+
+func TestSeasonStandingAddsUpTheBestOfEachCase(t *testing.T) {
+	start := time.Now()
+	user := &models.User{ID: "u1", Name: "Detective"}
+	season := &models.Season{ID: "s1"}
+	caseIDs := map[string]bool{"case1": true, "case2": true}
+	history := []models.Submission{
+		{CaseID: "case1", Score: 100, Accuracy: 0.5, SubmittedAt: start},
+		{CaseID: "case1", Score: 300, Accuracy: 1, SubmittedAt: start.Add(time.Hour)},
+		{CaseID: "case2", Score: 200, Accuracy: 0.5, SubmittedAt: start.Add(2 * time.Hour)},
+		{CaseID: "case3", Score: 900, Accuracy: 1, SubmittedAt: start.Add(3 * time.Hour)}, // Another season
+	}
+
+	standing, ok := SeasonStanding(user, history, season, caseIDs)
+	if !ok {
+		t.Fatal("no standing for a played season")
+	}
+	if standing.Board != SeasonBoard("s1") || standing.Points != 500 || standing.Accuracy != 0.75 || standing.CasesCount != 2 {
+		t.Errorf("standing = %+v", standing)
+	}
+	if !standing.ReachedAt.Equal(start.Add(2 * time.Hour)) {
+		t.Errorf("reached at %v, want the last best result", standing.ReachedAt)
+	}
+
+	if _, ok := SeasonStanding(user, history, season, map[string]bool{"case9": true}); ok {
+		t.Error("standing for a season the user has not played")
+	}
+}
+
+func TestRebuildSeasonRanksTheCasesOfTheSeason(t *testing.T) {
+	ctx := context.Background()
+	store := database.NewMemoryStore()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	season := &models.Season{ID: "s1", Name: "Invierno", StartsAt: start, EndsAt: start.AddDate(0, 3, 0)}
+
+	for _, coffeeCase := range []*models.CoffeeCase{
+		{ID: "in", CreatedAt: start.AddDate(0, 1, 0)},
+		{ID: "out", CreatedAt: start.AddDate(0, 3, 0)},
+	} {
+		if err := store.Cases().Save(ctx, coffeeCase); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, user := range []*models.User{{ID: "a", Name: "A"}, {ID: "b", Name: "B"}} {
+		if err := store.Users().Save(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, submission := range []*models.Submission{
+		{ID: "s1", UserID: "a", CaseID: "in", Score: 100, SubmittedAt: start},
+		{ID: "s2", UserID: "b", CaseID: "out", Score: 500, SubmittedAt: start},
+	} {
+		if err := store.Submissions().Save(ctx, submission); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	found, caseIDs, err := SeasonFor(ctx, store, &models.CoffeeCase{ID: "in", CreatedAt: start.AddDate(0, 1, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != nil {
+		t.Errorf("found season %v before it was saved", found.ID)
+	}
+	if err := store.Seasons().Save(ctx, season); err != nil {
+		t.Fatal(err)
+	}
+	found, caseIDs, err = SeasonFor(ctx, store, &models.CoffeeCase{ID: "in", CreatedAt: start.AddDate(0, 1, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found == nil || found.ID != "s1" || !caseIDs["in"] || caseIDs["out"] {
+		t.Fatalf("SeasonFor = %v with cases %v, want s1 with only in", found, caseIDs)
+	}
+
+	ranked, err := RebuildSeason(ctx, store, season)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ranked != 1 {
+		t.Errorf("ranked %d users, want 1", ranked)
+	}
+	if users, total := boardUsers(t, store, SeasonBoard("s1")); total != 1 || users[0] != "a" {
+		t.Errorf("season board = %v of %d, want [a]", users, total)
+	}
+
+	if err := DeleteSeasonStandings(ctx, store, "s1"); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := store.Standings().Count(ctx, SeasonBoard("s1")); count != 0 {
+		t.Errorf("%d standings left after deleting the season's", count)
+	}
+}
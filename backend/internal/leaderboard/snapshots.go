@@ -0,0 +1,89 @@
+package leaderboard
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+)
+
+const finalPrefix = "final_"
+
+// FinalCaseBoard holds the frozen standings of a finalized case
+func FinalCaseBoard(caseID string) string {
+	return finalPrefix + CaseBoard(caseID)
+}
+
+// IsFinal reports whether a board is a frozen snapshot
+func IsFinal(board string) bool {
+	return strings.HasPrefix(board, finalPrefix)
+}
+
+// FinalizeCase copies the case's standings into its final board and marks the
+// case as finalized. Final boards are never updated afterwards, not even when
+// users are renamed or the case is re-scored.
+func FinalizeCase(ctx context.Context, store database.Store, coffeeCase *models.CoffeeCase) error {
+	if coffeeCase.FinalizedAt != nil {
+		return nil
+	}
+
+	// Start from scratch in case a previous attempt stopped half way
+	final := FinalCaseBoard(coffeeCase.ID)
+	if err := clearBoard(ctx, store, final); err != nil {
+		return err
+	}
+
+	var after *models.Standing
+	for {
+		standings, err := store.Standings().Page(ctx, CaseBoard(coffeeCase.ID), after, 500)
+		if err != nil {
+			return err
+		}
+		if len(standings) == 0 {
+			break
+		}
+		for _, standing := range standings {
+			standing.Board = final
+			if err := store.Standings().Save(ctx, &standing); err != nil {
+				return err
+			}
+		}
+		after = &standings[len(standings)-1]
+	}
+
+	now := time.Now()
+	if err := store.Cases().Update(ctx, coffeeCase.ID, map[string]interface{}{"finalized_at": now}); err != nil {
+		return err
+	}
+	coffeeCase.FinalizedAt = &now
+	return nil
+}
+
+// hiddenStandings returns the standings on a final board of the users who
+// hide from leaderboards, keyed by user ID. Live boards drop those users when
+// they hide; final boards keep them, so they are filtered out when read.
+func hiddenStandings(ctx context.Context, store database.Store, board string) (map[string]models.Standing, error) {
+	hidden := make(map[string]models.Standing)
+	if !IsFinal(board) {
+		return hidden, nil
+	}
+
+	users, err := store.Users().ListHiddenFromLeaderboards(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		standing, err := store.Standings().Get(ctx, board, user.ID)
+		if errors.Is(err, database.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		hidden[user.ID] = *standing
+	}
+	return hidden, nil
+}
@@ -0,0 +1,136 @@
+package leaderboard
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+)
+
+// finalizedCase plays a case with the given users, scoring 300, 200, 100...
+// in order, and freezes its standings
+func finalizedCase(t *testing.T, store database.Store, userIDs ...string) {
+	t.Helper()
+	ctx := context.Background()
+	coffeeCase := &models.CoffeeCase{ID: "case1"}
+	if err := store.Cases().Save(ctx, coffeeCase); err != nil {
+		t.Fatal(err)
+	}
+	for i, id := range userIDs {
+		user := &models.User{ID: id, Name: "Detective " + id}
+		history := []models.Submission{{CaseID: "case1", Score: 100 * (len(userIDs) - i), SubmittedAt: time.Now()}}
+		if err := store.Users().Save(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		if err := SaveStandings(ctx, store, user, history, "case1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := FinalizeCase(ctx, store, coffeeCase); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func setHidden(t *testing.T, store database.Store, userID string, hidden bool) {
+	t.Helper()
+	ctx := context.Background()
+	user, err := store.Users().Get(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.Privacy.HideFromLeaderboards = hidden
+	if err := store.Users().Save(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := RebuildUser(ctx, store, user); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func boardUsers(t *testing.T, store database.Store, board string) ([]string, int) {
+	t.Helper()
+	var users []string
+	total := 0
+	cursor := ""
+	for {
+		page, err := GetPage(context.Background(), store, board, cursor, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range page.Entries {
+			if entry.Rank != len(users)+1 {
+				t.Errorf("%s ranked %d, want %d", entry.UserID, entry.Rank, len(users)+1)
+			}
+			users = append(users, entry.UserID)
+		}
+		total = page.Total
+		if page.NextCursor == "" {
+			return users, total
+		}
+		cursor = page.NextCursor
+	}
+}
+
+func TestFinalBoardLeavesOutHiddenUsers(t *testing.T) {
+	ctx := context.Background()
+	store := database.NewMemoryStore()
+	finalizedCase(t, store, "a", "b", "c")
+	board := FinalCaseBoard("case1")
+
+	setHidden(t, store, "b", true)
+
+	users, total := boardUsers(t, store, board)
+	if len(users) != 2 || users[0] != "a" || users[1] != "c" || total != 2 {
+		t.Fatalf("final board = %v of %d, want [a c] of 2", users, total)
+	}
+	entry, err := RankOf(ctx, store, board, "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Rank != 2 {
+		t.Errorf("c ranked %d, want 2", entry.Rank)
+	}
+	if _, err := RankOf(ctx, store, board, "b"); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("RankOf a hidden user = %v, want ErrNotFound", err)
+	}
+
+	// The snapshot itself is untouched, so showing again restores the result
+	setHidden(t, store, "b", false)
+	users, total = boardUsers(t, store, board)
+	if len(users) != 3 || users[1] != "b" || total != 3 {
+		t.Errorf("final board = %v of %d, want [a b c] of 3", users, total)
+	}
+}
+
+func TestFinalBoardIsNotUpdated(t *testing.T) {
+	ctx := context.Background()
+	store := database.NewMemoryStore()
+	finalizedCase(t, store, "a")
+
+	user, err := store.Users().Get(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.Name = "Renamed"
+	if err := SyncUser(ctx, store, user); err != nil {
+		t.Fatal(err)
+	}
+
+	final, err := store.Standings().Get(ctx, FinalCaseBoard("case1"), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final.DetectiveName != "Detective a" {
+		t.Errorf("final standing renamed to %q", final.DetectiveName)
+	}
+	live, err := store.Standings().Get(ctx, CaseBoard("case1"), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if live.DetectiveName != "Renamed" {
+		t.Errorf("live standing named %q, want Renamed", live.DetectiveName)
+	}
+}
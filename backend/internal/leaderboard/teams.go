@@ -0,0 +1,73 @@
+package leaderboard
+
+import (
+	"context"
+	"time"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+)
+
+// TeamBoard ranks the teams that played a case. Its standings are keyed by
+// team ID instead of user ID.
+func TeamBoard(caseID string) string {
+	return "teams_" + CaseBoard(caseID)
+}
+
+// TeamStanding builds a team's standing from its submissions: their average
+// score and accuracy, so that larger teams do not outrank smaller ones just
+// by submitting more often. It returns false when the team has not submitted.
+func TeamStanding(team *models.Team, submissions []models.Submission) (models.Standing, bool) {
+	if len(submissions) == 0 {
+		return models.Standing{}, false
+	}
+
+	standing := models.Standing{
+		Board:         TeamBoard(team.CaseID),
+		UserID:        team.ID,
+		DetectiveName: team.Name,
+		CasesCount:    1,
+		UpdatedAt:     time.Now(),
+	}
+	points := 0
+	for _, submission := range submissions {
+		points += submission.Score
+		standing.Accuracy += submission.Accuracy
+		if submission.SubmittedAt.After(standing.ReachedAt) {
+			standing.ReachedAt = submission.SubmittedAt
+		}
+	}
+	standing.Points = points / len(submissions)
+	standing.Accuracy /= float64(len(submissions))
+	return standing, true
+}
+
+// SaveTeamStanding writes a team's standing on its case's team board. It only
+// writes, so it can run at the end of a transaction.
+func SaveTeamStanding(ctx context.Context, store database.Store, team *models.Team, submissions []models.Submission) error {
+	standing, ok := TeamStanding(team, submissions)
+	if !ok {
+		return store.Standings().Delete(ctx, TeamBoard(team.CaseID), team.ID)
+	}
+	return store.Standings().Save(ctx, &standing)
+}
+
+// RebuildTeam recomputes a team's standing from its submissions
+func RebuildTeam(ctx context.Context, store database.Store, team *models.Team) error {
+	submissions, err := store.Submissions().ListByTeam(ctx, team.ID)
+	if err != nil {
+		return err
+	}
+	return SaveTeamStanding(ctx, store, team, submissions)
+}
+
+// TeamEntry converts a standing of a team board into a leaderboard entry
+func TeamEntry(entry models.LeaderboardEntryWithUser) models.TeamLeaderboardEntry {
+	return models.TeamLeaderboardEntry{
+		TeamID:   entry.UserID,
+		TeamName: entry.DetectiveName,
+		Points:   entry.Points,
+		Accuracy: entry.Accuracy,
+		Rank:     entry.Rank,
+	}
+}
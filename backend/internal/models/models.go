@@ -9,19 +9,124 @@ type User struct {
 	ID             string    `firestore:"id" json:"id"`
 	Name           string    `firestore:"name" json:"name"`
 	Email          string    `firestore:"email" json:"email"`
+	EmailVerified  bool      `firestore:"email_verified" json:"email_verified"` // Set only by a provider that verified the email or an email login link
 	Picture        string    `firestore:"picture" json:"picture"`
 	Type           string    `firestore:"type" json:"type"`     // regular, admin
+	Roles          []string  `firestore:"roles" json:"roles"`   // Staff roles, see the roles package
 	Score          int       `firestore:"score" json:"score"`
 	Points         int       `firestore:"points" json:"points"`
 	CasesAttempted int       `firestore:"cases_attempted" json:"cases_attempted"`
 	CasesSolved    int       `firestore:"cases_solved" json:"cases_solved"`
 	CasesCount     int       `firestore:"cases_count" json:"cases_count"`
 	Accuracy       float64   `firestore:"accuracy" json:"accuracy"`
-	Badges         []string  `firestore:"badges" json:"badges"`
+	Badges         []UserBadge `firestore:"badges" json:"badges"`
+	Privacy        PrivacySettings `firestore:"privacy" json:"privacy"`
 	CreatedAt      time.Time `firestore:"created_at" json:"created_at"`
 	UpdatedAt      time.Time `firestore:"updated_at" json:"updated_at"`
 }
 
+// PrivacySettings control what other users can see. The zero value shares everything.
+type PrivacySettings struct {
+	HideFromLeaderboards bool `firestore:"hide_from_leaderboards" json:"hide_from_leaderboards"`
+	HideStats            bool `firestore:"hide_stats" json:"hide_stats"` // Hides points, cases and accuracy on the public profile
+}
+
+// PublicProfile is the view of a user shown to other users
+type PublicProfile struct {
+	ID      string       `json:"id"`
+	Name    string       `json:"name"`
+	Picture string       `json:"picture"`
+	Badges  []UserBadge  `json:"badges"`
+	Stats   *PublicStats `json:"stats"` // Nil when the user hides their stats
+}
+
+// PublicStats are the stats shown on a public profile
+type PublicStats struct {
+	Points     int     `json:"points"`
+	CasesCount int     `json:"cases_count"`
+	Accuracy   float64 `json:"accuracy"`
+}
+
+// Identity links an account at an identity provider to a user. A user can
+// have several identities; their ID never depends on any of them.
+type Identity struct {
+	ID          string    `firestore:"id" json:"id"` // Provider and subject, see IdentityID
+	UserID      string    `firestore:"user_id" json:"user_id"`
+	Provider    string    `firestore:"provider" json:"provider"` // google, github, email or a configured OIDC provider
+	Subject     string    `firestore:"subject" json:"subject"`   // The account's ID at the provider
+	Email       string    `firestore:"email" json:"email"`
+	CreatedAt   time.Time `firestore:"created_at" json:"created_at"`
+	LastLoginAt time.Time `firestore:"last_login_at" json:"last_login_at"`
+}
+
+// IdentityID is the document ID of a provider account
+func IdentityID(provider, subject string) string {
+	return provider + ":" + subject
+}
+
+// LoginLink is a pending passwordless email login
+type LoginLink struct {
+	ID         string    `firestore:"id" json:"-"` // SHA-256 of the token sent by email
+	Email      string    `firestore:"email" json:"email"`
+	LinkUserID string    `firestore:"link_user_id" json:"-"` // Set when an existing user adds this email as an identity
+	CreatedAt  time.Time `firestore:"created_at" json:"created_at"`
+	ExpiresAt  time.Time `firestore:"expires_at" json:"expires_at"`
+}
+
+// SigningKey is a key pair used to sign access tokens. A key signs tokens
+// from NotBefore until RetiresAt and is published for verification until ExpiresAt.
+type SigningKey struct {
+	ID         string    `firestore:"id" json:"kid"`
+	Algorithm  string    `firestore:"algorithm" json:"alg"`
+	PrivateKey []byte    `firestore:"private_key" json:"-"` // PKCS #8, encrypted with JWT_SECRET
+	CreatedAt  time.Time `firestore:"created_at" json:"created_at"`
+	NotBefore  time.Time `firestore:"not_before" json:"not_before"`
+	RetiresAt  time.Time `firestore:"retires_at" json:"retires_at"`
+	ExpiresAt  time.Time `firestore:"expires_at" json:"expires_at"`
+}
+
+// Session is a signed-in device. Its refresh token is rotated on every use.
+type Session struct {
+	ID                  string        `firestore:"id" json:"id"`
+	UserID              string        `firestore:"user_id" json:"user_id"`
+	RefreshTokenHash    string        `firestore:"refresh_token_hash" json:"-"`
+	PreviousRefreshHash string        `firestore:"previous_refresh_hash" json:"-"` // Detects reuse of a rotated token
+	AccessTokens        []IssuedToken `firestore:"access_tokens" json:"-"`         // Unexpired access tokens, revoked with the session
+	UserAgent           string        `firestore:"user_agent" json:"user_agent"`
+	IPAddress           string        `firestore:"ip_address" json:"ip_address"`
+	CreatedAt           time.Time     `firestore:"created_at" json:"created_at"`
+	LastUsedAt          time.Time     `firestore:"last_used_at" json:"last_used_at"`
+	ExpiresAt           time.Time     `firestore:"expires_at" json:"expires_at"`
+	RevokedAt           *time.Time    `firestore:"revoked_at" json:"revoked_at,omitempty"`
+}
+
+// IssuedToken identifies an access token by its JWT ID
+type IssuedToken struct {
+	JTI       string    `firestore:"jti" json:"jti"`
+	ExpiresAt time.Time `firestore:"expires_at" json:"expires_at"`
+}
+
+// BadgeDefinition describes an achievement and the rule that awards it
+type BadgeDefinition struct {
+	ID           string    `firestore:"id" json:"id"` // Stable identifier, e.g. "first_case"
+	Name         string    `firestore:"name" json:"name"`
+	Description  string    `firestore:"description" json:"description"`
+	Icon         string    `firestore:"icon" json:"icon"`
+	Rule         string    `firestore:"rule" json:"rule"` // e.g. "cases_solved >= 5 && accuracy >= 0.8"
+	IsActive     bool      `firestore:"is_active" json:"is_active"`
+	DisplayOrder int       `firestore:"display_order" json:"display_order"`
+	CreatedAt    time.Time `firestore:"created_at" json:"created_at"`
+	UpdatedAt    time.Time `firestore:"updated_at" json:"updated_at"`
+}
+
+// UserBadge is a badge awarded to a user
+type UserBadge struct {
+	ID        string    `firestore:"id" json:"id"` // BadgeDefinition ID
+	Name      string    `firestore:"name" json:"name"`
+	Icon      string    `firestore:"icon" json:"icon"`
+	AwardedAt time.Time `firestore:"awarded_at" json:"awarded_at"`
+}
+
 // CoffeeCase represents a coffee mystery case
 type CoffeeCase struct {
 	ID               string            `firestore:"id" json:"id"`
@@ -30,9 +135,15 @@ type CoffeeCase struct {
 	Price            int               `firestore:"price" json:"price"`
 	Coffees          []CoffeeItem      `firestore:"coffees" json:"coffees"`
 	EnabledQuestions EnabledQuestions  `firestore:"enabled_questions" json:"enabled_questions"`
+	ScoringRules     *ScoringRules     `firestore:"scoring_rules,omitempty" json:"scoring_rules,omitempty"` // Defaults apply when unset
 	CreatedAt        time.Time         `firestore:"created_at" json:"created_at"`
 	UpdatedAt        time.Time         `firestore:"updated_at" json:"updated_at"`
 	IsActive         bool              `firestore:"is_active" json:"is_active"`
+	OpensAt          *time.Time        `firestore:"opens_at" json:"opens_at"`   // When the scheduler opens the case, if it is scheduled
+	ClosesAt         *time.Time        `firestore:"closes_at" json:"closes_at"` // When the scheduler closes the case, if it is scheduled
+	ClosedAt         *time.Time        `firestore:"closed_at" json:"closed_at"` // When the case was last deactivated
+	FinalizedAt      *time.Time        `firestore:"finalized_at" json:"finalized_at"` // When the final leaderboard snapshot was taken
+	ResultsRevealedAt *time.Time       `firestore:"results_revealed_at" json:"results_revealed_at"` // When the submission window ended and results became public
 }
 
 // PublicCoffeeCase represents a coffee case with only public information (no answers)
@@ -44,6 +155,9 @@ type PublicCoffeeCase struct {
 	CoffeeIDs        []string         `json:"coffee_ids"`
 	CoffeeCount      int              `json:"coffee_count"`
 	IsActive         bool             `json:"is_active"`
+	Phase            string           `json:"phase"` // "draft", "scheduled", "open", "closed" or "revealed"
+	OpensAt          *time.Time       `json:"opens_at"`
+	ClosesAt         *time.Time       `json:"closes_at"`
 }
 
 // EnabledQuestions defines which questions are enabled for this case
@@ -57,6 +171,25 @@ type EnabledQuestions struct {
 	BrewingMethod  bool `firestore:"brewing_method" json:"brewing_method"`
 }
 
+// ScoringRules configures how submissions for a case are graded
+type ScoringRules struct {
+	PointsPerCoffee     int          `firestore:"points_per_coffee" json:"points_per_coffee"` // Points for a perfectly answered coffee, split across questions by weight
+	Region              QuestionRule `firestore:"region" json:"region"`
+	Variety             QuestionRule `firestore:"variety" json:"variety"`
+	Process             QuestionRule `firestore:"process" json:"process"`
+	TasteNote1          QuestionRule `firestore:"taste_note_1" json:"taste_note_1"`
+	TasteNote2          QuestionRule `firestore:"taste_note_2" json:"taste_note_2"`
+	FavoriteCoffeeBonus int          `firestore:"favorite_coffee_bonus" json:"favorite_coffee_bonus"` // Awarded for answering the question
+	BrewingMethodBonus  int          `firestore:"brewing_method_bonus" json:"brewing_method_bonus"`   // Awarded for answering the question
+}
+
+// QuestionRule configures the grading of a single per-coffee question
+type QuestionRule struct {
+	Weight        float64 `firestore:"weight" json:"weight"`                 // Relative share of the coffee's points
+	Penalty       int     `firestore:"penalty" json:"penalty"`               // Points deducted for a wrong answer, blank answers are never penalized
+	PartialCredit float64 `firestore:"partial_credit" json:"partial_credit"` // Fraction of the question's points awarded for a partial match, compounded per tasting note level
+}
+
 // CoffeeItem represents a single coffee in a case
 type CoffeeItem struct {
 	ID          string `firestore:"id" json:"id"`
@@ -64,7 +197,8 @@ type CoffeeItem struct {
 	Region      string `firestore:"region" json:"region"`
 	Variety     string `firestore:"variety" json:"variety"`
 	Process     string `firestore:"process" json:"process"`
-	TastingNotes string `firestore:"tasting_notes" json:"tasting_notes"`
+	TastingNotes string `firestore:"tasting_notes" json:"tasting_notes"` // Legacy free-text notes, kept for cases created before the taxonomy
+	TastingNoteIDs []string `firestore:"tasting_note_ids" json:"tasting_note_ids"` // Values of tasting_note catalog items
 	Farm        string `firestore:"farm" json:"farm"`
 	Altitude    int    `firestore:"altitude" json:"altitude"`
 }
@@ -80,6 +214,8 @@ type Submission struct {
 	BrewingMethod   string           `firestore:"brewing_method" json:"brewing_method"`
 	Score           int              `firestore:"score" json:"score"`
 	Accuracy        float64          `firestore:"accuracy" json:"accuracy"`
+	Breakdown       []QuestionScore  `firestore:"breakdown" json:"breakdown"` // Per-question scoring detail
+	TeamID          string           `firestore:"team_id" json:"team_id,omitempty"` // Set when submitted for a team
 	SubmittedAt     time.Time        `firestore:"submitted_at" json:"submitted_at"`
 	ProcessedAt     *time.Time       `firestore:"processed_at" json:"processed_at"`
 }
@@ -95,6 +231,59 @@ type CoffeeAnswer struct {
 	Points       int    `firestore:"points" json:"points"`
 }
 
+// QuestionScore is the graded result of a single question in a submission
+type QuestionScore struct {
+	CoffeeID string  `firestore:"coffee_id" json:"coffee_id,omitempty"` // Empty for case-level questions
+	Question string  `firestore:"question" json:"question"`
+	Answer   string  `firestore:"answer" json:"answer"`
+	Result   string  `firestore:"result" json:"result"` // correct, partial, incorrect, duplicate, unanswered
+	Credit   float64 `firestore:"credit" json:"credit"` // Fraction of the question's points earned
+	Points   float64 `firestore:"points" json:"points"` // Negative when a penalty applies
+}
+
+// SubmissionFeedback is a player's submission as shown back to the player.
+// Grading and the correct answers are only included once the case's results
+// are revealed.
+type SubmissionFeedback struct {
+	ID             string           `json:"id"`
+	CaseID         string           `json:"case_id"`
+	OrderID        string           `json:"order_id"`
+	Score          int              `json:"score"`
+	Accuracy       float64          `json:"accuracy"`
+	Revealed       bool             `json:"revealed"`
+	Coffees        []CoffeeFeedback `json:"coffees"`
+	FavoriteCoffee string           `json:"favorite_coffee"`
+	BrewingMethod  string           `json:"brewing_method"`
+	Bonuses        []QuestionScore  `json:"bonuses,omitempty"` // Opinion questions, once revealed
+	SubmittedAt    time.Time        `json:"submitted_at"`
+}
+
+// CoffeeFeedback is a player's answer to one coffee
+type CoffeeFeedback struct {
+	CoffeeID  string          `json:"coffee_id"`
+	Answer    CoffeeAnswer    `json:"answer"`
+	Coffee    *CoffeeItem     `json:"coffee,omitempty"`    // The correct answers, once revealed
+	Points    *float64        `json:"points,omitempty"`    // Points earned for the coffee, once revealed
+	Questions []QuestionScore `json:"questions,omitempty"` // Each question's result and points, once revealed
+}
+
+// Draft is a submission the player is still working on. It is keyed by its
+// order code, so an order has at most one draft. Finalizing a draft scores it
+// as a submission and locks it.
+type Draft struct {
+	ID             string         `firestore:"id" json:"id"` // The order's 6-character code
+	UserID         string         `firestore:"user_id" json:"user_id"`
+	CaseID         string         `firestore:"case_id" json:"case_id"`
+	OrderID        string         `firestore:"order_id" json:"order_id"`
+	CoffeeAnswers  []CoffeeAnswer `firestore:"coffee_answers" json:"coffee_answers"`
+	FavoriteCoffee string         `firestore:"favorite_coffee" json:"favorite_coffee"`
+	BrewingMethod  string         `firestore:"brewing_method" json:"brewing_method"`
+	SubmissionID   string         `firestore:"submission_id" json:"submission_id,omitempty"` // Set once finalized
+	CreatedAt      time.Time      `firestore:"created_at" json:"created_at"`
+	UpdatedAt      time.Time      `firestore:"updated_at" json:"updated_at"`
+	FinalizedAt    *time.Time     `firestore:"finalized_at" json:"finalized_at"`
+}
+
 // Order represents a coffee case order
 type Order struct {
 	ID              string     `firestore:"id" json:"id"`
@@ -102,25 +291,124 @@ type Order struct {
 	UserID          string     `firestore:"user_id" json:"user_id"`
 	CaseID          string     `firestore:"case_id" json:"case_id"`
 	ContactInfo     string     `firestore:"contact_info" json:"contact_info"`
-	Status          string     `firestore:"status" json:"status"` // pending, confirmed, shipped, delivered
+	Status          string     `firestore:"status" json:"status"` // pending, confirmed, shipped, delivered, cancelled, refunded
+	StatusHistory   []OrderStatusChange `firestore:"status_history" json:"status_history"`
 	TotalAmount     int        `firestore:"total_amount" json:"total_amount"`
 	IsSubmissionUsed bool      `firestore:"is_submission_used" json:"is_submission_used"` // Whether order ID was used for submission
 	SubmissionUsedBy string    `firestore:"submission_used_by" json:"submission_used_by"` // User ID who used the order for submission
 	SubmissionUsedAt *time.Time `firestore:"submission_used_at" json:"submission_used_at"` // When the order ID was used
+	TeamID          string     `firestore:"team_id" json:"team_id,omitempty"` // Team playing the order, if any
 	CreatedAt       time.Time  `firestore:"created_at" json:"created_at"`
 	UpdatedAt       time.Time  `firestore:"updated_at" json:"updated_at"`
 }
 
+// Team is a group of players sharing one order, such as a household or an
+// office. The order's owner invites the other members with the invite code.
+type Team struct {
+	ID         string    `firestore:"id" json:"id"`
+	Name       string    `firestore:"name" json:"name"`
+	OrderID    string    `firestore:"order_id" json:"order_id"` // The shared order's 6-character code
+	CaseID     string    `firestore:"case_id" json:"case_id"`
+	OwnerID    string    `firestore:"owner_id" json:"owner_id"`     // The order's owner
+	MemberIDs  []string  `firestore:"member_ids" json:"member_ids"` // Including the owner
+	InviteCode string    `firestore:"invite_code" json:"invite_code,omitempty"` // Only shown to the owner
+	Mode       string    `firestore:"mode" json:"mode"`
+	CreatedAt  time.Time `firestore:"created_at" json:"created_at"`
+	UpdatedAt  time.Time `firestore:"updated_at" json:"updated_at"`
+}
+
+// Team modes
+const (
+	TeamModeSeparate = "separate" // Every member submits their own answers
+	TeamModeJoint    = "joint"    // One member submits the answers for the whole team
+)
+
+// TeamLeaderboardEntry is a team's entry on a case's team leaderboard
+type TeamLeaderboardEntry struct {
+	TeamID   string  `json:"team_id"`
+	TeamName string  `json:"team_name"`
+	Points   int     `json:"points"`   // Average score of the team's submissions
+	Accuracy float64 `json:"accuracy"` // Average accuracy of the team's submissions
+	Rank     int     `json:"rank"`
+}
+
+// Order statuses
+const (
+	OrderStatusPending   = "pending"
+	OrderStatusConfirmed = "confirmed"
+	OrderStatusShipped   = "shipped"
+	OrderStatusDelivered = "delivered"
+	OrderStatusCancelled = "cancelled"
+	OrderStatusRefunded  = "refunded"
+)
+
+// OrderStatusChange records a single status transition of an order
+type OrderStatusChange struct {
+	From      string    `firestore:"from" json:"from"` // Empty for the order's creation
+	To        string    `firestore:"to" json:"to"`
+	Actor     string    `firestore:"actor" json:"actor"` // User ID that made the change
+	Note      string    `firestore:"note" json:"note"`
+	ChangedAt time.Time `firestore:"changed_at" json:"changed_at"`
+}
+
+// AuditEntry records one change made by staff, or an order status change.
+// Entries are append-only.
+type AuditEntry struct {
+	ID         string                 `firestore:"id" json:"id"`
+	Actor      string                 `firestore:"actor" json:"actor"` // User ID that made the change
+	ActorEmail string                 `firestore:"actor_email" json:"actor_email"`
+	Action     string                 `firestore:"action" json:"action"`           // e.g. "case.update" or "order.status_change"
+	TargetType string                 `firestore:"target_type" json:"target_type"` // e.g. "case" or "order"
+	TargetID   string                 `firestore:"target_id" json:"target_id"`
+	Changes    map[string]FieldChange `firestore:"changes" json:"changes"` // Changed fields of the target, keyed by JSON field name
+	RequestID  string                 `firestore:"request_id" json:"request_id"`
+	Method     string                 `firestore:"method" json:"method"`
+	Path       string                 `firestore:"path" json:"path"`
+	Status     int                    `firestore:"status" json:"status"` // HTTP status of the response
+	CreatedAt  time.Time              `firestore:"created_at" json:"created_at"`
+}
+
+// FieldChange is the value of a field before and after a change. Before is
+// nil for created fields and After is nil for removed ones.
+type FieldChange struct {
+	Before interface{} `firestore:"before" json:"before"`
+	After  interface{} `firestore:"after" json:"after"`
+}
+
 // LeaderboardEntry represents a leaderboard entry
 type LeaderboardEntry struct {
 	UserID        string  `firestore:"user_id" json:"user_id"`
 	Points        int     `firestore:"points" json:"points"`
 	Accuracy      float64 `firestore:"accuracy" json:"accuracy"`
 	CasesCount    int     `firestore:"cases_count" json:"cases_count"`
-	Badges        []string `firestore:"badges" json:"badges"`
+	Badges        []UserBadge `firestore:"badges" json:"badges"`
 	Rank          int     `json:"rank"`
 }
 
+// Season groups the cases created within a date range under one leaderboard
+type Season struct {
+	ID        string    `firestore:"id" json:"id"`
+	Name      string    `firestore:"name" json:"name"`
+	StartsAt  time.Time `firestore:"starts_at" json:"starts_at"` // Inclusive
+	EndsAt    time.Time `firestore:"ends_at" json:"ends_at"`     // Exclusive
+	CreatedAt time.Time `firestore:"created_at" json:"created_at"`
+	UpdatedAt time.Time `firestore:"updated_at" json:"updated_at"`
+}
+
+// Standing is a user's precomputed position on a leaderboard
+type Standing struct {
+	ID            string      `firestore:"id" json:"-"`           // Board and user ID
+	Board         string      `firestore:"board" json:"board"`    // e.g. "global", "case_<case id>" or "season_<season id>"
+	UserID        string      `firestore:"user_id" json:"user_id"` // Team ID on team boards
+	DetectiveName string      `firestore:"detective_name" json:"detective_name"` // Team name on team boards
+	Points        int         `firestore:"points" json:"points"`
+	Accuracy      float64     `firestore:"accuracy" json:"accuracy"`
+	CasesCount    int         `firestore:"cases_count" json:"cases_count"`
+	Badges        []UserBadge `firestore:"badges" json:"badges"`
+	ReachedAt     time.Time   `firestore:"reached_at" json:"reached_at"` // When the standing was reached, earlier wins ties
+	UpdatedAt     time.Time   `firestore:"updated_at" json:"updated_at"`
+}
+
 // LeaderboardEntryWithUser represents a leaderboard entry with user information
 type LeaderboardEntryWithUser struct {
 	UserID        string  `json:"user_id"`
@@ -128,7 +416,7 @@ type LeaderboardEntryWithUser struct {
 	Points        int     `json:"points"`
 	Accuracy      float64 `json:"accuracy"`
 	CasesCount    int     `json:"cases_count"`
-	Badges        []string `json:"badges"`
+	Badges        []UserBadge `json:"badges"`
 	Rank          int     `json:"rank"`
 }
 
@@ -137,8 +425,10 @@ type CatalogItem struct {
 	ID          string `firestore:"id" json:"id"`
 	Value       string `firestore:"value" json:"value"`         // The option value (e.g., "central_valley")
 	Label       string `firestore:"label" json:"label"`         // The display name (e.g., "Valle Central")
-	Category    string `firestore:"category" json:"category"`   // "region", "variety", or "process"
+	Category    string `firestore:"category" json:"category"`   // "region", "variety", "process", "brewing_method" or "tasting_note"
 	IsActive    bool   `firestore:"is_active" json:"is_active"`
 	DisplayOrder int   `firestore:"display_order" json:"display_order"`
+	Synonyms    []string `firestore:"synonyms" json:"synonyms"` // Other names that refer to the item, e.g. "Central Valley"
+	Parent      string `firestore:"parent" json:"parent"` // Value of the broader tasting note, e.g. "berry" for "blueberry"
 	CreatedAt   time.Time `firestore:"created_at" json:"created_at"`
 }
\ No newline at end of file
@@ -0,0 +1,79 @@
+// Package orders implements the order lifecycle.
+package orders
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"brew-detective-backend/internal/models"
+)
+
+// ErrInvalidTransition is returned when an order cannot move to the requested status
+var ErrInvalidTransition = errors.New("invalid order status transition")
+
+// transitions lists the statuses each status can move to. Orders are fulfilled
+// pending → confirmed → shipped → delivered, can be cancelled until they ship,
+// and cancelled or delivered orders can be refunded.
+var transitions = map[string][]string{
+	models.OrderStatusPending:   {models.OrderStatusConfirmed, models.OrderStatusCancelled},
+	models.OrderStatusConfirmed: {models.OrderStatusShipped, models.OrderStatusCancelled},
+	models.OrderStatusShipped:   {models.OrderStatusDelivered},
+	models.OrderStatusDelivered: {models.OrderStatusRefunded},
+	models.OrderStatusCancelled: {models.OrderStatusRefunded},
+	models.OrderStatusRefunded:  {},
+}
+
+// IsValidStatus reports whether status is a known order status
+func IsValidStatus(status string) bool {
+	_, ok := transitions[status]
+	return ok
+}
+
+// NextStatuses returns the statuses an order in the given status can move to
+func NextStatuses(status string) []string {
+	next := transitions[status]
+	if next == nil {
+		return []string{}
+	}
+	return append([]string(nil), next...)
+}
+
+// CanTransition reports whether an order can move from one status to another
+func CanTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Create sets the initial status of a new order and starts its history
+func Create(order *models.Order, actor string, at time.Time) {
+	order.Status = models.OrderStatusPending
+	order.StatusHistory = []models.OrderStatusChange{{
+		To:        models.OrderStatusPending,
+		Actor:     actor,
+		Note:      "Order created",
+		ChangedAt: at,
+	}}
+}
+
+// Transition moves the order to a new status and appends the change to its history
+func Transition(order *models.Order, to, actor, note string, at time.Time) error {
+	if !CanTransition(order.Status, to) {
+		return fmt.Errorf("%w: %s → %s", ErrInvalidTransition, order.Status, to)
+	}
+
+	order.StatusHistory = append(order.StatusHistory, models.OrderStatusChange{
+		From:      order.Status,
+		To:        to,
+		Actor:     actor,
+		Note:      note,
+		ChangedAt: at,
+	})
+	order.Status = to
+	order.UpdatedAt = at
+	return nil
+}
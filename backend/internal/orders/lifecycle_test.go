@@ -0,0 +1,76 @@
+package orders
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"brew-detective-backend/internal/models"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{models.OrderStatusPending, models.OrderStatusConfirmed, true},
+		{models.OrderStatusConfirmed, models.OrderStatusShipped, true},
+		{models.OrderStatusShipped, models.OrderStatusDelivered, true},
+		{models.OrderStatusPending, models.OrderStatusCancelled, true},
+		{models.OrderStatusConfirmed, models.OrderStatusCancelled, true},
+		{models.OrderStatusCancelled, models.OrderStatusRefunded, true},
+		{models.OrderStatusDelivered, models.OrderStatusRefunded, true},
+
+		{models.OrderStatusPending, models.OrderStatusDelivered, false},
+		{models.OrderStatusShipped, models.OrderStatusCancelled, false},
+		{models.OrderStatusDelivered, models.OrderStatusPending, false},
+		{models.OrderStatusRefunded, models.OrderStatusPending, false},
+		{models.OrderStatusPending, models.OrderStatusPending, false},
+		{models.OrderStatusPending, "lost", false},
+	}
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestTransitionRecordsHistory(t *testing.T) {
+	created := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	order := &models.Order{}
+	Create(order, "buyer", created)
+
+	confirmed := created.Add(time.Hour)
+	if err := Transition(order, models.OrderStatusConfirmed, "staff", "Paid", confirmed); err != nil {
+		t.Fatalf("Transition: %v", err)
+	}
+	if order.Status != models.OrderStatusConfirmed || !order.UpdatedAt.Equal(confirmed) {
+		t.Errorf("order is %s updated at %v", order.Status, order.UpdatedAt)
+	}
+
+	want := []models.OrderStatusChange{
+		{To: models.OrderStatusPending, Actor: "buyer", Note: "Order created", ChangedAt: created},
+		{From: models.OrderStatusPending, To: models.OrderStatusConfirmed, Actor: "staff", Note: "Paid", ChangedAt: confirmed},
+	}
+	if len(order.StatusHistory) != len(want) {
+		t.Fatalf("history has %d changes, want %d", len(order.StatusHistory), len(want))
+	}
+	for i, change := range order.StatusHistory {
+		if change != want[i] {
+			t.Errorf("history[%d] = %+v, want %+v", i, change, want[i])
+		}
+	}
+}
+
+func TestTransitionRejectsSkippedStatuses(t *testing.T) {
+	order := &models.Order{}
+	Create(order, "buyer", time.Now())
+
+	err := Transition(order, models.OrderStatusShipped, "staff", "", time.Now())
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Transition = %v, want ErrInvalidTransition", err)
+	}
+	if order.Status != models.OrderStatusPending || len(order.StatusHistory) != 1 {
+		t.Errorf("a rejected transition changed the order: %s with %d changes", order.Status, len(order.StatusHistory))
+	}
+}
@@ -0,0 +1,62 @@
+package roles
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/utils"
+)
+
+// cacheTTL is how long a user's roles are reused before being read again.
+// Changes made on this instance invalidate the cache at once.
+var cacheTTL = utils.DurationFromEnv("ROLE_CACHE_TTL", 30*time.Second)
+
+// maxCacheEntries is the size above which expired entries are dropped
+const maxCacheEntries = 1024
+
+type cacheEntry struct {
+	roles    []string
+	loadedAt time.Time
+}
+
+var (
+	cacheMu sync.RWMutex
+	cache   = make(map[string]cacheEntry)
+)
+
+// For returns the user's roles, from the cache when they were read recently
+func For(ctx context.Context, userID string) ([]string, error) {
+	cacheMu.RLock()
+	entry, ok := cache[userID]
+	cacheMu.RUnlock()
+	if ok && time.Since(entry.loadedAt) < cacheTTL {
+		return entry.roles, nil
+	}
+
+	user, err := database.DB.Users().Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	roles := Of(user)
+
+	cacheMu.Lock()
+	if len(cache) >= maxCacheEntries {
+		for id, existing := range cache {
+			if time.Since(existing.loadedAt) >= cacheTTL {
+				delete(cache, id)
+			}
+		}
+	}
+	cache[userID] = cacheEntry{roles: roles, loadedAt: time.Now()}
+	cacheMu.Unlock()
+	return roles, nil
+}
+
+// Invalidate drops the cached roles of a user after they change
+func Invalidate(userID string) {
+	cacheMu.Lock()
+	delete(cache, userID)
+	cacheMu.Unlock()
+}
@@ -0,0 +1,136 @@
+// Package roles defines the staff roles and the permissions each one grants.
+package roles
+
+import (
+	"sort"
+
+	"brew-detective-backend/internal/models"
+)
+
+// Permission allows a group of admin operations
+type Permission string
+
+const (
+	CatalogManage     Permission = "catalog:manage"     // Edit the coffee catalog
+	CasesManage       Permission = "cases:manage"       // Create, edit and rescore cases
+	SubmissionsRead   Permission = "submissions:read"   // View any submission
+	BadgesManage      Permission = "badges:manage"      // Edit badge definitions
+	LeaderboardManage Permission = "leaderboard:manage" // Manage seasons and rebuild standings
+	OrdersRead        Permission = "orders:read"        // View any order and its history
+	OrdersManage      Permission = "orders:manage"      // Create orders for users and change their status
+	UsersRead         Permission = "users:read"         // View users, their profiles and sessions
+	UsersManage       Permission = "users:manage"       // Edit any user's profile
+	SessionsManage    Permission = "sessions:manage"    // Revoke users' sessions
+	RolesManage       Permission = "roles:manage"       // Grant and revoke roles
+	KeysManage        Permission = "keys:manage"        // Rotate the token signing keys
+	AuditRead         Permission = "audit:read"         // View and export the audit log
+)
+
+// Roles
+const (
+	Admin       = "admin"
+	CaseCurator = "case-curator"
+	Fulfillment = "fulfillment"
+	Support     = "support"
+)
+
+// grants lists the permissions of each role. Admins have every permission.
+var grants = map[string][]Permission{
+	Admin: {
+		CatalogManage, CasesManage, SubmissionsRead, BadgesManage, LeaderboardManage, OrdersRead,
+		OrdersManage, UsersRead, UsersManage, SessionsManage, RolesManage, KeysManage, AuditRead,
+	},
+	CaseCurator: {CatalogManage, CasesManage, SubmissionsRead, BadgesManage, LeaderboardManage},
+	Fulfillment: {OrdersRead, OrdersManage},
+	Support:     {SubmissionsRead, OrdersRead, UsersRead, SessionsManage},
+}
+
+// IsValid reports whether role is a known role
+func IsValid(role string) bool {
+	_, ok := grants[role]
+	return ok
+}
+
+// Definitions returns every role with its permissions
+func Definitions() map[string][]Permission {
+	definitions := make(map[string][]Permission, len(grants))
+	for role, permissions := range grants {
+		definitions[role] = append([]Permission(nil), permissions...)
+	}
+	return definitions
+}
+
+// Of returns the user's roles. Users whose legacy type is "admin" have the admin role.
+func Of(user *models.User) []string {
+	roles := append([]string(nil), user.Roles...)
+	if user.Type == Admin && !contains(roles, Admin) {
+		roles = append(roles, Admin)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// Allows reports whether any of the roles grants the permission
+func Allows(roles []string, permission Permission) bool {
+	for _, role := range roles {
+		for _, granted := range grants[role] {
+			if granted == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PermissionsOf returns the distinct permissions the roles grant
+func PermissionsOf(roles []string) []Permission {
+	seen := make(map[Permission]bool)
+	permissions := []Permission{}
+	for _, role := range roles {
+		for _, permission := range grants[role] {
+			if !seen[permission] {
+				seen[permission] = true
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+	sort.Slice(permissions, func(i, j int) bool { return permissions[i] < permissions[j] })
+	return permissions
+}
+
+// Grant adds a role to the user. It returns false when the user already has it.
+func Grant(user *models.User, role string) bool {
+	if contains(Of(user), role) {
+		return false
+	}
+	user.Roles = append(user.Roles, role)
+	return true
+}
+
+// Revoke removes a role from the user, including the legacy admin type. It
+// returns false when the user does not have it.
+func Revoke(user *models.User, role string) bool {
+	if !contains(Of(user), role) {
+		return false
+	}
+	remaining := user.Roles[:0]
+	for _, existing := range user.Roles {
+		if existing != role {
+			remaining = append(remaining, existing)
+		}
+	}
+	user.Roles = remaining
+	if role == Admin && user.Type == Admin {
+		user.Type = "regular"
+	}
+	return true
+}
+
+func contains(roles []string, role string) bool {
+	for _, existing := range roles {
+		if existing == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package roles
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+)
+
+func TestOfIncludesTheLegacyAdminType(t *testing.T) {
+	tests := []struct {
+		user models.User
+		want string
+	}{
+		{models.User{Type: "regular"}, "[]"},
+		{models.User{Type: "regular", Roles: []string{Support, CaseCurator}}, "[case-curator support]"},
+		{models.User{Type: Admin}, "[admin]"},
+		{models.User{Type: Admin, Roles: []string{Admin, Support}}, "[admin support]"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(Of(&tt.user)); got != tt.want {
+			t.Errorf("Of(%+v) = %s, want %s", tt.user, got, tt.want)
+		}
+	}
+}
+
+func TestAllows(t *testing.T) {
+	tests := []struct {
+		roles      []string
+		permission Permission
+		want       bool
+	}{
+		{[]string{Admin}, AuditRead, true},
+		{[]string{CaseCurator}, CasesManage, true},
+		{[]string{CaseCurator}, OrdersRead, false},
+		{[]string{Fulfillment, Support}, UsersRead, true},
+		{[]string{"unknown"}, OrdersRead, false},
+		{nil, SubmissionsRead, false},
+	}
+	for _, tt := range tests {
+		if got := Allows(tt.roles, tt.permission); got != tt.want {
+			t.Errorf("Allows(%v, %s) = %v, want %v", tt.roles, tt.permission, got, tt.want)
+		}
+	}
+
+	if got := fmt.Sprint(PermissionsOf([]string{Fulfillment, Support})); got != "[orders:manage orders:read sessions:manage submissions:read users:read]" {
+		t.Errorf("PermissionsOf = %s", got)
+	}
+}
+
+func TestGrantAndRevoke(t *testing.T) {
+	user := &models.User{Type: Admin, Roles: []string{Support}}
+
+	if Grant(user, Admin) {
+		t.Error("granted admin to a legacy admin")
+	}
+	if !Grant(user, Fulfillment) || Grant(user, Fulfillment) {
+		t.Error("fulfillment not granted exactly once")
+	}
+	if !Revoke(user, Admin) {
+		t.Fatal("could not revoke the legacy admin type")
+	}
+	if user.Type != "regular" {
+		t.Errorf("type = %q after revoking admin", user.Type)
+	}
+	if !Revoke(user, Support) || Revoke(user, Support) {
+		t.Error("support not revoked exactly once")
+	}
+	if got := fmt.Sprint(Of(user)); got != "[fulfillment]" {
+		t.Errorf("roles = %s, want [fulfillment]", got)
+	}
+}
+
+func TestForCachesUntilInvalidated(t *testing.T) {
+	ctx := context.Background()
+	database.DB = database.NewMemoryStore()
+	user := &models.User{ID: "cached", Roles: []string{Support}}
+	if err := database.DB.Users().Save(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	Invalidate(user.ID)
+
+	if got, err := For(ctx, user.ID); err != nil || fmt.Sprint(got) != "[support]" {
+		t.Fatalf("For = %v, %v", got, err)
+	}
+
+	user.Roles = []string{Fulfillment}
+	if err := database.DB.Users().Save(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := For(ctx, user.ID); fmt.Sprint(got) != "[support]" {
+		t.Errorf("For = %v before invalidating, want the cached [support]", got)
+	}
+	Invalidate(user.ID)
+	if got, _ := For(ctx, user.ID); fmt.Sprint(got) != "[fulfillment]" {
+		t.Errorf("For = %v after invalidating, want [fulfillment]", got)
+	}
+}
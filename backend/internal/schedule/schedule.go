@@ -0,0 +1,218 @@
+// Package schedule moves cases through their phases: scheduled, open, closed
+// and revealed. At most one case is open at a time.
+package schedule
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/leaderboard"
+	"brew-detective-backend/internal/models"
+	"brew-detective-backend/internal/utils"
+)
+
+// Case phases
+const (
+	PhaseDraft     = "draft"     // Never opened and not scheduled
+	PhaseScheduled = "scheduled" // Waiting for opens_at
+	PhaseOpen      = "open"      // Accepting submissions
+	PhaseClosed    = "closed"    // Closed, but orders can still be submitted during the grace period
+	PhaseRevealed  = "revealed"  // Submissions are over and results are public
+)
+
+// GracePeriod is how long after a case closes its orders can still be submitted.
+// Results are revealed once it is over.
+var GracePeriod = utils.DurationFromEnv("SUBMISSION_GRACE_PERIOD", 24*time.Hour)
+
+// checkInterval is how often the scheduler looks for cases to open, close or reveal
+var checkInterval = utils.DurationFromEnv("CASE_SCHEDULE_INTERVAL", time.Minute)
+
+// Phase returns the phase a case is in
+func Phase(coffeeCase *models.CoffeeCase) string {
+	switch {
+	case coffeeCase.ResultsRevealedAt != nil:
+		return PhaseRevealed
+	case coffeeCase.IsActive:
+		return PhaseOpen
+	case coffeeCase.ClosedAt != nil:
+		return PhaseClosed
+	case coffeeCase.OpensAt != nil:
+		return PhaseScheduled
+	default:
+		return PhaseDraft
+	}
+}
+
+// AcceptsSubmissions reports whether a case still accepts submissions at the given time
+func AcceptsSubmissions(coffeeCase *models.CoffeeCase, at time.Time) bool {
+	if coffeeCase.IsActive {
+		return true
+	}
+	return coffeeCase.ClosedAt != nil && coffeeCase.ResultsRevealedAt == nil &&
+		at.Before(coffeeCase.ClosedAt.Add(GracePeriod))
+}
+
+// RevealDue reports whether a closed case's grace period is over, so its
+// results can be revealed
+func RevealDue(coffeeCase *models.CoffeeCase, at time.Time) bool {
+	return !coffeeCase.IsActive && coffeeCase.ClosedAt != nil && coffeeCase.ResultsRevealedAt == nil &&
+		!at.Before(coffeeCase.ClosedAt.Add(GracePeriod))
+}
+
+// CloseOthers closes every active case except keep, so that opening keep
+// leaves a single active case. In a transaction it must run before the
+// transaction's other writes, since it reads the active cases.
+func CloseOthers(ctx context.Context, tx database.Store, keep string, at time.Time) error {
+	active, err := tx.Cases().ListActive(ctx)
+	if err != nil {
+		return err
+	}
+	for _, other := range active {
+		if other.ID == keep {
+			continue
+		}
+		if err := tx.Cases().Update(ctx, other.ID, closeUpdates(at)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Open activates a case with the given extra field updates, closing the case
+// that was active before
+func Open(ctx context.Context, store database.Store, id string, updates map[string]interface{}, at time.Time) error {
+	return store.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+		if _, err := tx.Cases().Get(ctx, id); err != nil {
+			return err
+		}
+		if err := CloseOthers(ctx, tx, id, at); err != nil {
+			return err
+		}
+
+		opened := map[string]interface{}{"updated_at": at}
+		for field, value := range updates {
+			opened[field] = value
+		}
+		opened["is_active"] = true
+		opened["closed_at"] = nil
+		return tx.Cases().Update(ctx, id, opened)
+	})
+}
+
+func closeUpdates(at time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"is_active":  false,
+		"closed_at":  at,
+		"updated_at": at,
+	}
+}
+
+// Reveal ends a closed case: its final leaderboard is frozen and its results
+// become public
+func Reveal(ctx context.Context, store database.Store, coffeeCase *models.CoffeeCase, at time.Time) error {
+	if err := leaderboard.FinalizeCase(ctx, store, coffeeCase); err != nil {
+		return err
+	}
+	if err := store.Cases().Update(ctx, coffeeCase.ID, map[string]interface{}{"results_revealed_at": at}); err != nil {
+		return err
+	}
+	coffeeCase.ResultsRevealedAt = &at
+	return nil
+}
+
+// Run applies every transition that is due at the given time. A failing case
+// is logged and skipped so that it does not hold up the others.
+func Run(ctx context.Context, store database.Store, now time.Time) error {
+	cases := store.Cases()
+
+	// Repair data from before the single active case was enforced, keeping
+	// the case that was activated last
+	active, err := cases.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+	if len(active) > 1 {
+		latest := active[0]
+		for _, coffeeCase := range active[1:] {
+			if coffeeCase.UpdatedAt.After(latest.UpdatedAt) {
+				latest = coffeeCase
+			}
+		}
+		log.Printf("Found %d active cases, keeping %s", len(active), latest.ID)
+		if err := store.RunTransaction(ctx, func(ctx context.Context, tx database.Store) error {
+			return CloseOthers(ctx, tx, latest.ID, now)
+		}); err != nil {
+			return err
+		}
+		active = []models.CoffeeCase{latest}
+	}
+
+	// Close the active case once its window ends. The grace period starts at
+	// the scheduled time, even when the scheduler runs late.
+	for _, coffeeCase := range active {
+		if coffeeCase.ClosesAt != nil && !coffeeCase.ClosesAt.After(now) {
+			log.Printf("Closing case %s", coffeeCase.ID)
+			if err := cases.Update(ctx, coffeeCase.ID, closeUpdates(*coffeeCase.ClosesAt)); err != nil {
+				log.Printf("Failed to close case %s: %v", coffeeCase.ID, err)
+			}
+		}
+	}
+
+	// Open scheduled cases in order, each replacing the one before. A case
+	// only opens on its own once, and not after its window has ended.
+	opening, err := cases.ListOpeningBy(ctx, now)
+	if err != nil {
+		return err
+	}
+	for _, coffeeCase := range opening {
+		if coffeeCase.ClosedAt != nil || coffeeCase.ResultsRevealedAt != nil {
+			continue
+		}
+		if coffeeCase.ClosesAt != nil && !coffeeCase.ClosesAt.After(now) {
+			continue
+		}
+		log.Printf("Opening case %s", coffeeCase.ID)
+		if err := Open(ctx, store, coffeeCase.ID, nil, now); err != nil {
+			log.Printf("Failed to open case %s: %v", coffeeCase.ID, err)
+		}
+	}
+
+	// Reveal the results of cases whose grace period is over
+	closed, err := cases.ListClosedBy(ctx, now.Add(-GracePeriod))
+	if err != nil {
+		return err
+	}
+	for i := range closed {
+		if !RevealDue(&closed[i], now) {
+			continue
+		}
+		log.Printf("Revealing the results of case %s", closed[i].ID)
+		if err := Reveal(ctx, store, &closed[i], now); err != nil {
+			log.Printf("Failed to reveal case %s: %v", closed[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// Start runs the scheduler now and then periodically until ctx is done
+func Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			runCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+			if err := Run(runCtx, database.DB, time.Now()); err != nil {
+				log.Printf("Failed to run the case schedule: %v", err)
+			}
+			cancel()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
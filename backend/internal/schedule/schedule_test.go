@@ -0,0 +1,157 @@
+package schedule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"brew-detective-backend/internal/database"
+	"brew-detective-backend/internal/models"
+)
+
+func TestPhase(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		coffeeCase models.CoffeeCase
+		want       string
+	}{
+		{models.CoffeeCase{}, PhaseDraft},
+		{models.CoffeeCase{OpensAt: &now}, PhaseScheduled},
+		{models.CoffeeCase{IsActive: true, OpensAt: &now}, PhaseOpen},
+		{models.CoffeeCase{ClosedAt: &now}, PhaseClosed},
+		{models.CoffeeCase{ClosedAt: &now, ResultsRevealedAt: &now}, PhaseRevealed},
+	}
+	for _, tt := range tests {
+		if got := Phase(&tt.coffeeCase); got != tt.want {
+			t.Errorf("Phase(%+v) = %s, want %s", tt.coffeeCase, got, tt.want)
+		}
+	}
+}
+
+func TestSubmissionsCloseAfterTheGracePeriod(t *testing.T) {
+	closedAt := time.Now()
+	coffeeCase := &models.CoffeeCase{ClosedAt: &closedAt}
+
+	during := closedAt.Add(GracePeriod - time.Second)
+	if !AcceptsSubmissions(coffeeCase, during) || RevealDue(coffeeCase, during) {
+		t.Error("grace period not honored")
+	}
+	after := closedAt.Add(GracePeriod)
+	if AcceptsSubmissions(coffeeCase, after) || !RevealDue(coffeeCase, after) {
+		t.Error("submissions accepted after the grace period")
+	}
+
+	if !AcceptsSubmissions(&models.CoffeeCase{IsActive: true}, after) {
+		t.Error("open case refused submissions")
+	}
+	if AcceptsSubmissions(&models.CoffeeCase{}, closedAt) {
+		t.Error("draft case accepted submissions")
+	}
+	revealed := &models.CoffeeCase{ClosedAt: &closedAt, ResultsRevealedAt: &closedAt}
+	if AcceptsSubmissions(revealed, during) || RevealDue(revealed, after) {
+		t.Error("revealed case treated as closed")
+	}
+}
+
+// saveCases stores cases in a new in-memory store
+func saveCases(t *testing.T, cases ...models.CoffeeCase) database.Store {
+	t.Helper()
+	store := database.NewMemoryStore()
+	for i := range cases {
+		if err := store.Cases().Save(context.Background(), &cases[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func getCase(t *testing.T, store database.Store, id string) *models.CoffeeCase {
+	t.Helper()
+	coffeeCase, err := store.Cases().Get(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return coffeeCase
+}
+
+func TestOpenClosesTheActiveCase(t *testing.T) {
+	now := time.Now()
+	store := saveCases(t,
+		models.CoffeeCase{ID: "old", IsActive: true},
+		models.CoffeeCase{ID: "new", Name: "Caso"},
+	)
+
+	if err := Open(context.Background(), store, "new", map[string]interface{}{"name": "Caso nuevo"}, now); err != nil {
+		t.Fatal(err)
+	}
+
+	opened := getCase(t, store, "new")
+	if !opened.IsActive || opened.Name != "Caso nuevo" {
+		t.Errorf("opened case = %+v", opened)
+	}
+	closed := getCase(t, store, "old")
+	if closed.IsActive || closed.ClosedAt == nil || !closed.ClosedAt.Equal(now) {
+		t.Errorf("previous case = %+v, want closed at %v", closed, now)
+	}
+}
+
+func TestRunMovesCasesThroughTheirPhases(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+	longAgo := now.Add(-GracePeriod - time.Hour)
+
+	store := saveCases(t,
+		// Window over: closes at its scheduled end
+		models.CoffeeCase{ID: "ending", IsActive: true, ClosesAt: &past, UpdatedAt: past},
+		// Due to open, and replaces whatever is active
+		models.CoffeeCase{ID: "starting", OpensAt: &past, ClosesAt: &future},
+		// Scheduled for later
+		models.CoffeeCase{ID: "later", OpensAt: &future},
+		// Closed before, so it does not open on its own again
+		models.CoffeeCase{ID: "reopened", OpensAt: &past, ClosedAt: &past},
+		// Grace period over
+		models.CoffeeCase{ID: "graded", ClosedAt: &longAgo},
+	)
+	if err := Run(context.Background(), store, now); err != nil {
+		t.Fatal(err)
+	}
+
+	ending := getCase(t, store, "ending")
+	if ending.IsActive || ending.ClosedAt == nil || !ending.ClosedAt.Equal(past) {
+		t.Errorf("ending = %s closed at %v, want closed at %v", Phase(ending), ending.ClosedAt, past)
+	}
+	for id, want := range map[string]string{
+		"starting": PhaseOpen,
+		"later":    PhaseScheduled,
+		"reopened": PhaseClosed,
+		"graded":   PhaseRevealed,
+	} {
+		if got := Phase(getCase(t, store, id)); got != want {
+			t.Errorf("%s is %s, want %s", id, got, want)
+		}
+	}
+	if graded := getCase(t, store, "graded"); graded.FinalizedAt == nil {
+		t.Error("revealed case has no final leaderboard")
+	}
+}
+
+func TestRunKeepsTheLatestOfSeveralActiveCases(t *testing.T) {
+	now := time.Now()
+	store := saveCases(t,
+		models.CoffeeCase{ID: "a", IsActive: true, UpdatedAt: now.Add(-2 * time.Hour)},
+		models.CoffeeCase{ID: "b", IsActive: true, UpdatedAt: now.Add(-time.Hour)},
+		models.CoffeeCase{ID: "c", IsActive: true, UpdatedAt: now.Add(-3 * time.Hour)},
+	)
+	if err := Run(context.Background(), store, now); err != nil {
+		t.Fatal(err)
+	}
+
+	active, err := store.Cases().ListActive(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active) != 1 || active[0].ID != "b" {
+		t.Errorf("%d active cases, want only b", len(active))
+	}
+}
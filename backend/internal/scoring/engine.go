@@ -0,0 +1,287 @@
+// Package scoring grades case submissions against the case's coffees and scoring rules.
+package scoring
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"brew-detective-backend/internal/catalog"
+	"brew-detective-backend/internal/models"
+)
+
+// Question identifiers used in score breakdowns
+const (
+	QuestionRegion         = "region"
+	QuestionVariety        = "variety"
+	QuestionProcess        = "process"
+	QuestionTasteNote1     = "taste_note_1"
+	QuestionTasteNote2     = "taste_note_2"
+	QuestionFavoriteCoffee = "favorite_coffee"
+	QuestionBrewingMethod  = "brewing_method"
+)
+
+var (
+	// ErrUnknownCoffee is returned when an answer references a coffee that is not in the case
+	ErrUnknownCoffee = errors.New("answer references a coffee that is not part of the case")
+	// ErrDuplicateCoffee is returned when a submission answers the same coffee twice
+	ErrDuplicateCoffee = errors.New("submission answers the same coffee more than once")
+	// ErrInvalidRules is returned when the case's scoring rules cannot be applied
+	ErrInvalidRules = errors.New("invalid scoring rules")
+)
+
+// coffeeQuestion describes one of the questions asked for every coffee in a case
+type coffeeQuestion struct {
+	id string
+	// Questions sharing a group never earn credit twice for the same match
+	group   string
+	enabled func(models.EnabledQuestions) bool
+	answer  func(models.CoffeeAnswer) string
+	rule    func(models.ScoringRules) models.QuestionRule
+}
+
+var coffeeQuestions = []coffeeQuestion{
+	{
+		id:      QuestionRegion,
+		enabled: func(q models.EnabledQuestions) bool { return q.Region },
+		answer:  func(a models.CoffeeAnswer) string { return a.Region },
+		rule:    func(r models.ScoringRules) models.QuestionRule { return r.Region },
+	},
+	{
+		id:      QuestionVariety,
+		enabled: func(q models.EnabledQuestions) bool { return q.Variety },
+		answer:  func(a models.CoffeeAnswer) string { return a.Variety },
+		rule:    func(r models.ScoringRules) models.QuestionRule { return r.Variety },
+	},
+	{
+		id:      QuestionProcess,
+		enabled: func(q models.EnabledQuestions) bool { return q.Process },
+		answer:  func(a models.CoffeeAnswer) string { return a.Process },
+		rule:    func(r models.ScoringRules) models.QuestionRule { return r.Process },
+	},
+	{
+		id:      QuestionTasteNote1,
+		group:   "tasting_notes",
+		enabled: func(q models.EnabledQuestions) bool { return q.TasteNote1 },
+		answer:  func(a models.CoffeeAnswer) string { return a.TasteNote1 },
+		rule:    func(r models.ScoringRules) models.QuestionRule { return r.TasteNote1 },
+	},
+	{
+		id:      QuestionTasteNote2,
+		group:   "tasting_notes",
+		enabled: func(q models.EnabledQuestions) bool { return q.TasteNote2 },
+		answer:  func(a models.CoffeeAnswer) string { return a.TasteNote2 },
+		rule:    func(r models.ScoringRules) models.QuestionRule { return r.TasteNote2 },
+	},
+}
+
+// Result is the outcome of grading a submission
+type Result struct {
+	Score     int
+	Accuracy  float64
+	Breakdown []models.QuestionScore
+}
+
+// Engine grades submissions using a grader per coffee question
+type Engine struct {
+	graders map[string]Grader
+	// catalog resolves the brewing method answers the bonus is awarded for
+	catalog Catalog
+}
+
+// NewEngine creates an engine with the default graders. Answers are matched
+// without a catalog until SetCatalog is called.
+func NewEngine() *Engine {
+	e := &Engine{graders: make(map[string]Grader)}
+	e.SetCatalog(foldingCatalog{})
+	return e
+}
+
+// SetCatalog grades region, variety, process and tasting note answers by the
+// catalog items they refer to, and only awards the brewing method bonus for
+// brewing methods in the catalog
+func (e *Engine) SetCatalog(items Catalog) {
+	e.catalog = items
+	e.graders[QuestionRegion] = CatalogGrader(catalog.Region, func(c models.CoffeeItem) string { return c.Region }, items)
+	e.graders[QuestionVariety] = CatalogGrader(catalog.Variety, func(c models.CoffeeItem) string { return c.Variety }, items)
+	e.graders[QuestionProcess] = CatalogGrader(catalog.Process, func(c models.CoffeeItem) string { return c.Process }, items)
+	e.graders[QuestionTasteNote1] = TastingNoteGrader(items)
+	e.graders[QuestionTasteNote2] = TastingNoteGrader(items)
+}
+
+// SetGrader replaces the grader used for a coffee question
+func (e *Engine) SetGrader(question string, grader Grader) {
+	e.graders[question] = grader
+}
+
+// Default is the engine used by the package-level Score function
+var Default = NewEngine()
+
+// Score grades a submission with the default engine
+func Score(coffeeCase *models.CoffeeCase, submission *models.Submission) (*Result, error) {
+	return Default.Score(coffeeCase, submission)
+}
+
+// Score grades a submission against its case. It does not modify the submission.
+func (e *Engine) Score(coffeeCase *models.CoffeeCase, submission *models.Submission) (*Result, error) {
+	if coffeeCase == nil {
+		return nil, errors.New("scoring requires the submission's case")
+	}
+
+	rules := RulesFor(coffeeCase)
+	if err := ValidateRules(rules); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRules, err)
+	}
+
+	var enabled []coffeeQuestion
+	totalWeight := 0.0
+	for _, q := range coffeeQuestions {
+		if q.enabled(coffeeCase.EnabledQuestions) {
+			enabled = append(enabled, q)
+			totalWeight += q.rule(rules).Weight
+		}
+	}
+
+	// Nothing to grade without coffee questions or answers
+	if len(enabled) == 0 || len(submission.CoffeeAnswers) == 0 {
+		return &Result{Breakdown: []models.QuestionScore{}}, nil
+	}
+	if totalWeight <= 0 {
+		return nil, fmt.Errorf("%w: enabled questions have no weight", ErrInvalidRules)
+	}
+
+	coffees := make(map[string]models.CoffeeItem, len(coffeeCase.Coffees))
+	for _, coffee := range coffeeCase.Coffees {
+		coffees[coffee.ID] = coffee
+	}
+
+	result := &Result{}
+	points := 0.0
+	earnedWeight := 0.0
+	answered := make(map[string]bool)
+
+	for _, answer := range submission.CoffeeAnswers {
+		coffee, ok := coffees[answer.CoffeeID]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownCoffee, answer.CoffeeID)
+		}
+		if answered[answer.CoffeeID] {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateCoffee, answer.CoffeeID)
+		}
+		answered[answer.CoffeeID] = true
+
+		// Grade every question first, so that a match shared by questions of a
+		// group goes to whichever earns the most credit for it, whatever the order
+		scores := make([]models.QuestionScore, len(enabled))
+		matches := make([]string, len(enabled))
+		best := make(map[string]int)
+		for i, q := range enabled {
+			rule := q.rule(rules)
+			score := models.QuestionScore{
+				CoffeeID: answer.CoffeeID,
+				Question: q.id,
+				Answer:   strings.TrimSpace(q.answer(answer)),
+				Result:   ResultUnanswered,
+			}
+
+			if score.Answer != "" {
+				grade := e.graders[q.id].Grade(score.Answer, coffee)
+				score.Result = grade.Result
+
+				switch grade.Result {
+				case ResultCorrect:
+					score.Credit = 1
+				case ResultPartial:
+					score.Credit = math.Pow(rule.PartialCredit, math.Max(float64(grade.Levels), 1))
+				default:
+					if rule.Penalty > 0 {
+						score.Points = -float64(rule.Penalty)
+					}
+				}
+
+				if score.Credit > 0 && q.group != "" && grade.Match != "" {
+					matches[i] = q.group + ":" + strings.ToLower(grade.Match)
+					if j, ok := best[matches[i]]; !ok || score.Credit > scores[j].Credit {
+						best[matches[i]] = i
+					}
+				}
+			}
+			scores[i] = score
+		}
+
+		for i, q := range enabled {
+			rule := q.rule(rules)
+			score := scores[i]
+			if matches[i] != "" && best[matches[i]] != i {
+				score.Result = ResultDuplicate
+				score.Credit = 0
+			}
+			if score.Credit > 0 {
+				score.Points = float64(rules.PointsPerCoffee) * rule.Weight / totalWeight * score.Credit
+			}
+
+			points += score.Points
+			earnedWeight += rule.Weight * score.Credit
+			result.Breakdown = append(result.Breakdown, score)
+		}
+	}
+
+	// Opinion questions have no correct answer, so their bonus rewards taking
+	// part. The favorite coffee is free text and earns it when answered; the
+	// brewing method must be one of the catalog's. Rules set the bonus to 0 to
+	// turn it off.
+	bonuses := []struct {
+		id      string
+		enabled bool
+		answer  string
+		bonus   int
+		valid   func(answer string) bool
+	}{
+		{QuestionFavoriteCoffee, coffeeCase.EnabledQuestions.FavoriteCoffee, submission.FavoriteCoffee, rules.FavoriteCoffeeBonus,
+			func(string) bool { return true }},
+		{QuestionBrewingMethod, coffeeCase.EnabledQuestions.BrewingMethod, submission.BrewingMethod, rules.BrewingMethodBonus,
+			func(answer string) bool {
+				_, ok := e.catalog.Resolve(catalog.BrewingMethod, answer)
+				return ok
+			}},
+	}
+	for _, b := range bonuses {
+		if !b.enabled {
+			continue
+		}
+		score := models.QuestionScore{
+			Question: b.id,
+			Answer:   strings.TrimSpace(b.answer),
+			Result:   ResultUnanswered,
+		}
+		if score.Answer != "" {
+			score.Result = ResultIncorrect
+			if b.valid(score.Answer) {
+				score.Result = ResultCorrect
+				score.Credit = 1
+				score.Points = float64(b.bonus)
+			}
+		}
+		points += score.Points
+		result.Breakdown = append(result.Breakdown, score)
+	}
+
+	// Coffees left unanswered count as missed, so skipping them lowers accuracy
+	result.Accuracy = earnedWeight / (totalWeight * float64(len(coffeeCase.Coffees)))
+	// Small epsilon so that evenly split points (e.g. 3 x 33.33) don't truncate to 99
+	result.Score = int(math.Max(0, math.Floor(points+1e-9)))
+
+	return result, nil
+}
+
+// CoffeePoints adds up the points of a breakdown per coffee
+func CoffeePoints(breakdown []models.QuestionScore) map[string]float64 {
+	points := make(map[string]float64)
+	for _, score := range breakdown {
+		if score.CoffeeID != "" {
+			points[score.CoffeeID] += score.Points
+		}
+	}
+	return points
+}
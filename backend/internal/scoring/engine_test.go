@@ -0,0 +1,263 @@
+package scoring
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"brew-detective-backend/internal/catalog"
+	"brew-detective-backend/internal/models"
+)
+
+// flavorEngine grades against the built-in flavor wheel
+func flavorEngine() *Engine {
+	e := NewEngine()
+	e.SetCatalog(catalog.NewIndex(catalog.FlavorWheel()))
+	return e
+}
+
+func tastingNoteCase(notes ...string) *models.CoffeeCase {
+	return &models.CoffeeCase{
+		ID:               "case",
+		Coffees:          []models.CoffeeItem{{ID: "coffee", TastingNoteIDs: notes}},
+		EnabledQuestions: models.EnabledQuestions{TasteNote1: true, TasteNote2: true},
+	}
+}
+
+func noteSubmission(note1, note2 string) *models.Submission {
+	return &models.Submission{CoffeeAnswers: []models.CoffeeAnswer{{CoffeeID: "coffee", TasteNote1: note1, TasteNote2: note2}}}
+}
+
+func TestTastingNotePartialCredit(t *testing.T) {
+	e := flavorEngine()
+	coffeeCase := tastingNoteCase("blueberry", "lemon")
+
+	tests := []struct {
+		name         string
+		note1, note2 string
+		score        int
+		accuracy     float64
+	}{
+		{"exact", "blueberry", "lemon", 100, 1},
+		{"exact by label", "Arándano", "Limón", 100, 1},
+		{"parents", "berry", "citrus", 50, 0.5},
+		{"grandparent and parent", "fruity", "citrus", 37, 0.375},
+		{"parent and exact", "berry", "lemon", 75, 0.75},
+		{"unrelated", "chocolate", "", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := e.Score(coffeeCase, noteSubmission(tt.note1, tt.note2))
+			if err != nil {
+				t.Fatalf("Score: %v", err)
+			}
+			if result.Score != tt.score {
+				t.Errorf("score = %d, want %d", result.Score, tt.score)
+			}
+			if math.Abs(result.Accuracy-tt.accuracy) > 1e-9 {
+				t.Errorf("accuracy = %v, want %v", result.Accuracy, tt.accuracy)
+			}
+		})
+	}
+}
+
+func TestTastingNoteLevels(t *testing.T) {
+	e := flavorEngine()
+	coffeeCase := tastingNoteCase("blueberry")
+	coffeeCase.EnabledQuestions.TasteNote2 = false
+
+	tests := []struct {
+		answer string
+		result string
+		credit float64
+	}{
+		{"blueberry", ResultCorrect, 1},
+		{"berry", ResultPartial, 0.5},
+		{"fruity", ResultPartial, 0.25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.answer, func(t *testing.T) {
+			result, err := e.Score(coffeeCase, noteSubmission(tt.answer, ""))
+			if err != nil {
+				t.Fatalf("Score: %v", err)
+			}
+			score := result.Breakdown[0]
+			if score.Result != tt.result || math.Abs(score.Credit-tt.credit) > 1e-9 {
+				t.Errorf("got %s with credit %v, want %s with credit %v", score.Result, score.Credit, tt.result, tt.credit)
+			}
+		})
+	}
+}
+
+func TestTastingNotesNeverMatchTwice(t *testing.T) {
+	result, err := flavorEngine().Score(tastingNoteCase("blueberry", "lemon"), noteSubmission("blueberry", "blueberry"))
+	if err != nil {
+		t.Fatalf("Score: %v", err)
+	}
+	if got := result.Breakdown[1].Result; got != ResultDuplicate {
+		t.Errorf("second note = %s, want %s", got, ResultDuplicate)
+	}
+	if result.Score != 50 {
+		t.Errorf("score = %d, want 50", result.Score)
+	}
+}
+
+func TestTastingNotesKeepTheBestCredit(t *testing.T) {
+	coffeeCase := tastingNoteCase("blueberry", "lemon")
+
+	// A partial match on the first note must not cost the exact match on the second
+	for _, answers := range [][2]string{{"berry", "blueberry"}, {"blueberry", "berry"}} {
+		result, err := flavorEngine().Score(coffeeCase, noteSubmission(answers[0], answers[1]))
+		if err != nil {
+			t.Fatalf("Score: %v", err)
+		}
+		if result.Score != 50 {
+			t.Errorf("%v: score = %d, want 50", answers, result.Score)
+		}
+		for _, score := range result.Breakdown {
+			want := ResultDuplicate
+			if score.Answer == "blueberry" {
+				want = ResultCorrect
+			}
+			if score.Result != want {
+				t.Errorf("%v: %s = %s, want %s", answers, score.Answer, score.Result, want)
+			}
+		}
+	}
+}
+
+// originCase asks for the region and process of two coffees
+func originCase(rules *models.ScoringRules) *models.CoffeeCase {
+	return &models.CoffeeCase{
+		ID: "case",
+		Coffees: []models.CoffeeItem{
+			{ID: "c1", Region: "huila", Process: "washed"},
+			{ID: "c2", Region: "narino", Process: "natural"},
+		},
+		EnabledQuestions: models.EnabledQuestions{Region: true, Process: true, FavoriteCoffee: true, BrewingMethod: true},
+		ScoringRules:     rules,
+	}
+}
+
+func TestScoreRules(t *testing.T) {
+	weighted := DefaultRules()
+	weighted.Region.Weight = 3
+	weighted.Process.Penalty = 10
+
+	tests := []struct {
+		name     string
+		rules    *models.ScoringRules
+		answers  []models.CoffeeAnswer
+		favorite string
+		score    int
+		accuracy float64
+	}{
+		{
+			name:     "all correct with bonuses",
+			answers:  []models.CoffeeAnswer{{CoffeeID: "c1", Region: "Huila", Process: "washed"}, {CoffeeID: "c2", Region: "Nariño", Process: "natural"}},
+			favorite: "c1",
+			score:    300,
+			accuracy: 1,
+		},
+		{
+			name:     "half right",
+			answers:  []models.CoffeeAnswer{{CoffeeID: "c1", Region: "huila", Process: "natural"}, {CoffeeID: "c2", Region: "huila"}},
+			score:    50,
+			accuracy: 0.25,
+		},
+		{
+			name:     "weighted questions",
+			rules:    &weighted,
+			answers:  []models.CoffeeAnswer{{CoffeeID: "c1", Region: "huila"}, {CoffeeID: "c2", Process: "natural"}},
+			score:    100,
+			accuracy: 0.5,
+		},
+		{
+			name:     "wrong answers are penalized, blank ones are not",
+			rules:    &weighted,
+			answers:  []models.CoffeeAnswer{{CoffeeID: "c1", Region: "huila", Process: "natural"}, {CoffeeID: "c2"}},
+			score:    65,
+			accuracy: 0.375,
+		},
+		{
+			name:     "skipped coffees count as missed",
+			answers:  []models.CoffeeAnswer{{CoffeeID: "c1", Region: "huila", Process: "washed"}},
+			score:    100,
+			accuracy: 0.5,
+		},
+		{
+			name:    "scores never go below zero",
+			rules:   &weighted,
+			answers: []models.CoffeeAnswer{{CoffeeID: "c1", Process: "natural"}, {CoffeeID: "c2", Process: "washed"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			submission := &models.Submission{CoffeeAnswers: tt.answers, FavoriteCoffee: tt.favorite, BrewingMethod: tt.favorite}
+			result, err := NewEngine().Score(originCase(tt.rules), submission)
+			if err != nil {
+				t.Fatalf("Score: %v", err)
+			}
+			if result.Score != tt.score {
+				t.Errorf("score = %d, want %d", result.Score, tt.score)
+			}
+			if math.Abs(result.Accuracy-tt.accuracy) > 1e-9 {
+				t.Errorf("accuracy = %v, want %v", result.Accuracy, tt.accuracy)
+			}
+		})
+	}
+}
+
+func TestScoreRejectsInvalidSubmissions(t *testing.T) {
+	invalid := DefaultRules()
+	invalid.Region.PartialCredit = 2
+
+	tests := []struct {
+		name    string
+		rules   *models.ScoringRules
+		answers []models.CoffeeAnswer
+		want    error
+	}{
+		{"unknown coffee", nil, []models.CoffeeAnswer{{CoffeeID: "c9"}}, ErrUnknownCoffee},
+		{"repeated coffee", nil, []models.CoffeeAnswer{{CoffeeID: "c1"}, {CoffeeID: "c1"}}, ErrDuplicateCoffee},
+		{"invalid rules", &invalid, []models.CoffeeAnswer{{CoffeeID: "c1"}}, ErrInvalidRules},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewEngine().Score(originCase(tt.rules), &models.Submission{CoffeeAnswers: tt.answers})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Score = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBrewingMethodBonusNeedsACatalogMethod(t *testing.T) {
+	e := NewEngine()
+	e.SetCatalog(catalog.NewIndex([]models.CatalogItem{
+		{Category: catalog.BrewingMethod, Value: "v60", Label: "V60", Synonyms: []string{"Hario V60"}, IsActive: true},
+	}))
+
+	tests := []struct {
+		answer string
+		result string
+		score  int
+	}{
+		{"hario v60", ResultCorrect, 50},
+		{"x", ResultIncorrect, 0},
+		{"", ResultUnanswered, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.answer, func(t *testing.T) {
+			submission := &models.Submission{CoffeeAnswers: []models.CoffeeAnswer{{CoffeeID: "c1"}}, BrewingMethod: tt.answer}
+			result, err := e.Score(originCase(nil), submission)
+			if err != nil {
+				t.Fatalf("Score: %v", err)
+			}
+			bonus := result.Breakdown[len(result.Breakdown)-1]
+			if bonus.Question != QuestionBrewingMethod || bonus.Result != tt.result || result.Score != tt.score {
+				t.Errorf("got %s %s and %d points, want %s and %d", bonus.Question, bonus.Result, result.Score, tt.result, tt.score)
+			}
+		})
+	}
+}
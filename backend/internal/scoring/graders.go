@@ -0,0 +1,149 @@
+package scoring
+
+import (
+	"strings"
+
+	"brew-detective-backend/internal/catalog"
+	"brew-detective-backend/internal/models"
+)
+
+// Grade results
+const (
+	ResultCorrect    = "correct"
+	ResultPartial    = "partial"
+	ResultIncorrect  = "incorrect"
+	ResultDuplicate  = "duplicate"
+	ResultUnanswered = "unanswered"
+)
+
+// Grade is a grader's verdict on a single answer
+type Grade struct {
+	Result string // ResultCorrect, ResultPartial or ResultIncorrect
+	Match  string // The correct value the answer matched, used to avoid awarding the same match twice
+	// Levels is how many levels of a taxonomy separate a partial answer from
+	// its match. Each level multiplies the partial credit again; 0 counts as 1.
+	Levels int
+}
+
+// Grader compares a player's answer to a question against the correct coffee
+type Grader interface {
+	Grade(answer string, coffee models.CoffeeItem) Grade
+}
+
+// GraderFunc adapts a function to the Grader interface
+type GraderFunc func(answer string, coffee models.CoffeeItem) Grade
+
+func (f GraderFunc) Grade(answer string, coffee models.CoffeeItem) Grade {
+	return f(answer, coffee)
+}
+
+// ExactGrader accepts answers equal to the coffee field, ignoring case and surrounding spaces
+func ExactGrader(field func(models.CoffeeItem) string) Grader {
+	return GraderFunc(func(answer string, coffee models.CoffeeItem) Grade {
+		correct := strings.TrimSpace(field(coffee))
+		if correct != "" && strings.EqualFold(strings.TrimSpace(answer), correct) {
+			return Grade{Result: ResultCorrect, Match: correct}
+		}
+		return Grade{Result: ResultIncorrect}
+	})
+}
+
+// Catalog resolves answers and coffee values to the catalog values they refer to
+type Catalog interface {
+	// Canonical returns the value of the catalog item text refers to, or a
+	// normalized form of text when it is not in the catalog
+	Canonical(category, text string) string
+	// Parent returns the value of the item a value belongs under, or "" when
+	// it has none
+	Parent(category, value string) string
+	// Resolve returns the value of the item text refers to, and whether there is one
+	Resolve(category, text string) (string, bool)
+}
+
+// foldingCatalog has no items: it only ignores case, accents and separators
+type foldingCatalog struct{}
+
+func (foldingCatalog) Canonical(category, text string) string {
+	return catalog.Fold(text)
+}
+
+func (foldingCatalog) Parent(category, value string) string {
+	return ""
+}
+
+// Resolve cannot tell whether text is in a catalog, so it accepts any text
+func (foldingCatalog) Resolve(category, text string) (string, bool) {
+	folded := catalog.Fold(text)
+	return folded, folded != ""
+}
+
+// CatalogGrader accepts answers that refer to the same catalog item as the
+// coffee field, whether by its value, its label or one of its synonyms
+func CatalogGrader(category string, field func(models.CoffeeItem) string, items Catalog) Grader {
+	return GraderFunc(func(answer string, coffee models.CoffeeItem) Grade {
+		correct := items.Canonical(category, field(coffee))
+		if correct != "" && items.Canonical(category, answer) == correct {
+			return Grade{Result: ResultCorrect, Match: correct}
+		}
+		return Grade{Result: ResultIncorrect}
+	})
+}
+
+// TastingNoteGrader grades a tasting note answer against the coffee's notes
+// in the tasting note taxonomy. Naming one of the notes is correct; naming a
+// broader or narrower note on the same branch, such as "berry" for
+// "blueberry", is partial, and worth less the more levels apart they are.
+// The match is always the coffee's note, so the two tasting note questions
+// cannot earn credit for the same note twice.
+func TastingNoteGrader(items Catalog) Grader {
+	return GraderFunc(func(answer string, coffee models.CoffeeItem) Grade {
+		answer = items.Canonical(catalog.TastingNote, answer)
+		if answer == "" {
+			return Grade{Result: ResultIncorrect}
+		}
+		parent := func(value string) string { return items.Parent(catalog.TastingNote, value) }
+
+		best := Grade{Result: ResultIncorrect}
+		for _, note := range tastingNotes(coffee, items) {
+			if answer == note {
+				return Grade{Result: ResultCorrect, Match: note}
+			}
+			levels := distance(answer, note, parent)
+			if levels == 0 {
+				levels = distance(note, answer, parent)
+			}
+			if levels > 0 && (best.Levels == 0 || levels < best.Levels) {
+				best = Grade{Result: ResultPartial, Match: note, Levels: levels}
+			}
+		}
+		return best
+	})
+}
+
+// tastingNotes returns the canonical values of the coffee's notes, reading
+// the legacy comma-separated text for cases without note IDs
+func tastingNotes(coffee models.CoffeeItem, items Catalog) []string {
+	notes := coffee.TastingNoteIDs
+	if len(notes) == 0 {
+		notes = strings.Split(coffee.TastingNotes, ",")
+	}
+
+	var values []string
+	for _, note := range notes {
+		if value := items.Canonical(catalog.TastingNote, note); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// distance returns how many levels ancestor is above value in the taxonomy,
+// or 0 when it is not above value
+func distance(ancestor, value string, parent func(string) string) int {
+	for i, above := range catalog.Ancestors(value, parent) {
+		if above == ancestor {
+			return i + 1
+		}
+	}
+	return 0
+}
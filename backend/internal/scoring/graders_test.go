@@ -0,0 +1,98 @@
+package scoring
+
+import (
+	"testing"
+
+	"brew-detective-backend/internal/catalog"
+	"brew-detective-backend/internal/models"
+)
+
+func region(c models.CoffeeItem) string { return c.Region }
+
+func TestExactGrader(t *testing.T) {
+	grader := ExactGrader(region)
+	coffee := models.CoffeeItem{Region: "Huila"}
+
+	tests := []struct {
+		answer string
+		want   string
+	}{
+		{"Huila", ResultCorrect},
+		{"  huila ", ResultCorrect},
+		{"Huíla", ResultIncorrect},
+		{"Nariño", ResultIncorrect},
+	}
+	for _, tt := range tests {
+		if got := grader.Grade(tt.answer, coffee); got.Result != tt.want {
+			t.Errorf("Grade(%q) = %s, want %s", tt.answer, got.Result, tt.want)
+		}
+	}
+	if got := grader.Grade("", models.CoffeeItem{}); got.Result != ResultIncorrect {
+		t.Errorf("a blank answer matched a blank field: %s", got.Result)
+	}
+}
+
+func TestCatalogGrader(t *testing.T) {
+	index := catalog.NewIndex([]models.CatalogItem{
+		{Category: catalog.Region, Value: "central_valley", Label: "Valle Central", Synonyms: []string{"Central Valley"}, IsActive: true},
+		{Category: catalog.Region, Value: "tarrazu", Label: "Tarrazú", IsActive: true},
+	})
+	coffee := models.CoffeeItem{Region: "central_valley"}
+
+	tests := []struct {
+		answer string
+		want   string
+	}{
+		{"central_valley", ResultCorrect},
+		{"Valle Central", ResultCorrect},
+		{"valle  central", ResultCorrect},
+		{"Central Valley", ResultCorrect},
+		{"Tarrazú", ResultIncorrect},
+		{"Valle", ResultIncorrect},
+	}
+	for _, tt := range tests {
+		if got := CatalogGrader(catalog.Region, region, index).Grade(tt.answer, coffee); got.Result != tt.want {
+			t.Errorf("Grade(%q) = %s, want %s", tt.answer, got.Result, tt.want)
+		}
+	}
+
+	// Without a catalog, answers still match regardless of case and accents
+	folding := CatalogGrader(catalog.Region, region, foldingCatalog{})
+	if got := folding.Grade("TARRAZU", models.CoffeeItem{Region: "Tarrazú"}); got.Result != ResultCorrect {
+		t.Errorf("folded answer = %s, want %s", got.Result, ResultCorrect)
+	}
+}
+
+func TestTastingNoteGrader(t *testing.T) {
+	grader := TastingNoteGrader(catalog.NewIndex(catalog.FlavorWheel()))
+	coffee := models.CoffeeItem{TastingNoteIDs: []string{"berry", "lemon"}}
+
+	tests := []struct {
+		answer string
+		result string
+		match  string
+		levels int
+	}{
+		{"berry", ResultCorrect, "berry", 0},
+		{"Limón", ResultCorrect, "lemon", 0},
+		{"blueberry", ResultPartial, "berry", 1}, // Narrower than the coffee's note
+		{"fruity", ResultPartial, "berry", 1},    // Closer to berry than to lemon
+		{"chocolate", ResultIncorrect, "", 0},
+		{"", ResultIncorrect, "", 0},
+	}
+	for _, tt := range tests {
+		got := grader.Grade(tt.answer, coffee)
+		if got.Result != tt.result || got.Match != tt.match || got.Levels != tt.levels {
+			t.Errorf("Grade(%q) = %+v, want %s matching %q %d levels apart", tt.answer, got, tt.result, tt.match, tt.levels)
+		}
+	}
+}
+
+func TestTastingNoteGraderReadsLegacyNotes(t *testing.T) {
+	grader := TastingNoteGrader(catalog.NewIndex(catalog.FlavorWheel()))
+	coffee := models.CoffeeItem{TastingNotes: "Arándano, chocolate"}
+
+	if got := grader.Grade("blueberry", coffee); got.Result != ResultCorrect {
+		t.Errorf("legacy note = %s, want %s", got.Result, ResultCorrect)
+	}
+}
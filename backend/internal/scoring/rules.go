@@ -0,0 +1,60 @@
+package scoring
+
+import (
+	"fmt"
+
+	"brew-detective-backend/internal/models"
+)
+
+// DefaultRules returns the rules used for cases without their own configuration.
+// They reproduce the original fixed scoring: 100 points per coffee split evenly
+// across the enabled questions and a flat 50 point bonus per answered opinion question
+// (brewing methods only count when they are in the catalog).
+// A tasting note on the same branch of the taxonomy earns half the points per level.
+func DefaultRules() models.ScoringRules {
+	rule := models.QuestionRule{Weight: 1, PartialCredit: 0.5}
+
+	return models.ScoringRules{
+		PointsPerCoffee:     100,
+		Region:              rule,
+		Variety:             rule,
+		Process:             rule,
+		TasteNote1:          rule,
+		TasteNote2:          rule,
+		FavoriteCoffeeBonus: 50,
+		BrewingMethodBonus:  50,
+	}
+}
+
+// RulesFor returns the case's scoring rules, falling back to DefaultRules
+func RulesFor(coffeeCase *models.CoffeeCase) models.ScoringRules {
+	if coffeeCase.ScoringRules != nil {
+		return *coffeeCase.ScoringRules
+	}
+	return DefaultRules()
+}
+
+// ValidateRules checks that scoring rules are internally consistent
+func ValidateRules(rules models.ScoringRules) error {
+	if rules.PointsPerCoffee < 0 {
+		return fmt.Errorf("points_per_coffee must not be negative")
+	}
+	if rules.FavoriteCoffeeBonus < 0 || rules.BrewingMethodBonus < 0 {
+		return fmt.Errorf("bonuses must not be negative")
+	}
+
+	for _, q := range coffeeQuestions {
+		rule := q.rule(rules)
+		if rule.Weight < 0 {
+			return fmt.Errorf("%s.weight must not be negative", q.id)
+		}
+		if rule.Penalty < 0 {
+			return fmt.Errorf("%s.penalty must not be negative", q.id)
+		}
+		if rule.PartialCredit < 0 || rule.PartialCredit > 1 {
+			return fmt.Errorf("%s.partial_credit must be between 0 and 1", q.id)
+		}
+	}
+
+	return nil
+}
@@ -7,8 +7,17 @@ import (
 
 // GenerateOrderID generates a random 6-character order ID
 func GenerateOrderID() string {
+	return randomCode(6)
+}
+
+// GenerateInviteCode generates a random 8-character team invite code
+func GenerateInviteCode() string {
+	return randomCode(8)
+}
+
+// randomCode generates a random code of uppercase letters and digits
+func randomCode(length int) string {
 	const charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	const length = 6
 	
 	b := make([]byte, length)
 	for i := range b {
@@ -113,7 +113,7 @@ async function updateAuthUI() {
         const adminMenuItem = document.getElementById('adminMenuItem');
         if (adminMenuItem) {
             // Checking admin access
-            if (user.type === 'admin') {
+            if (Auth.isStaff(user)) {
                 // Admin menu displayed
                 adminMenuItem.style.display = 'block';
             } else {
@@ -344,8 +344,18 @@ async function handleTokenFromURL() {
     const params = new URLSearchParams(hash);
     const token = params.get('token');
 
+    if (params.get('linked')) {
+        showNotification('¡Cuenta vinculada exitosamente!', 'success');
+        window.history.replaceState({}, document.title, window.location.pathname);
+        return;
+    }
+
     if (token) {
         Auth.setToken(token);
+        const refreshToken = params.get('refresh_token');
+        if (refreshToken) {
+            Auth.setRefreshToken(refreshToken);
+        }
         
         // Get user data from the token
         try {
@@ -26,9 +26,10 @@ const API_CONFIG = {
         ADMIN_USERS: '/api/v1/admin/users',
         
         // Auth endpoints
-        AUTH_GOOGLE: '/auth/google',
+        AUTH_PROVIDER: '/auth',
         AUTH_CALLBACK: '/auth/google/callback',
-        AUTH_LOGOUT: '/auth/logout'
+        AUTH_LOGOUT: '/auth/logout',
+        AUTH_REFRESH: '/auth/refresh'
     }
 };
 
@@ -44,9 +45,44 @@ window.Auth = {
 
     removeToken() {
         localStorage.removeItem('auth_token');
+        localStorage.removeItem('refresh_token');
         localStorage.removeItem('user_data');
     },
 
+    getRefreshToken() {
+        return localStorage.getItem('refresh_token');
+    },
+
+    setRefreshToken(token) {
+        localStorage.setItem('refresh_token', token);
+    },
+
+    // Exchanges the refresh token for new tokens. Concurrent callers share one
+    // request because each refresh token can only be used once.
+    refresh() {
+        if (!this.refreshPromise) {
+            this.refreshPromise = (async () => {
+                const refreshToken = this.getRefreshToken();
+                if (!refreshToken) return false;
+
+                const response = await fetch(`${API_CONFIG.BASE_URL}${API_CONFIG.ENDPOINTS.AUTH_REFRESH}`, {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ refresh_token: refreshToken })
+                });
+                if (!response.ok) return false;
+
+                const data = await response.json();
+                this.setToken(data.access_token);
+                this.setRefreshToken(data.refresh_token);
+                return true;
+            })().catch(() => false).finally(() => {
+                this.refreshPromise = null;
+            });
+        }
+        return this.refreshPromise;
+    },
+
     getUser() {
         const userData = localStorage.getItem('user_data');
         return userData ? JSON.parse(userData) : null;
@@ -56,7 +92,15 @@ window.Auth = {
         localStorage.setItem('user_data', JSON.stringify(user));
     },
 
+    // Staff are users with any role; the backend checks each admin route's permission
+    isStaff(user = this.getUser()) {
+        return !!user && (user.type === 'admin' || (user.roles || []).length > 0);
+    },
+
     isAuthenticated() {
+        // An expired access token is renewed on the next request
+        if (this.getRefreshToken()) return true;
+
         const token = this.getToken();
         if (!token) return false;
         
@@ -69,15 +113,9 @@ window.Auth = {
         }
     },
 
-    async login() {
-        try {
-            const response = await fetch(`${API_CONFIG.BASE_URL}${API_CONFIG.ENDPOINTS.AUTH_GOOGLE}`);
-            const data = await response.json();
-            window.location.href = data.auth_url;
-        } catch (error) {
-            console.error('Login failed:', error);
-            throw error;
-        }
+    async login(provider = 'google') {
+        // Navigate to the backend so it can bind the login to this browser with a cookie
+        window.location.href = `${API_CONFIG.BASE_URL}${API_CONFIG.ENDPOINTS.AUTH_PROVIDER}/${provider}?redirect=true`;
     },
 
     async logout() {
@@ -96,7 +134,7 @@ window.Auth = {
 
 // API helper functions
 const API = {
-    async request(endpoint, options = {}) {
+    async request(endpoint, options = {}, retried = false) {
         const url = `${API_CONFIG.BASE_URL}${endpoint}`;
         const headers = {
             'Content-Type': 'application/json',
@@ -118,7 +156,10 @@ const API = {
             const response = await fetch(url, config);
             
             if (response.status === 401) {
-                // Token expired or invalid
+                // Access token expired or revoked: try once with refreshed tokens
+                if (!retried && token && await Auth.refresh()) {
+                    return this.request(endpoint, options, true);
+                }
                 Auth.removeToken();
                 throw new Error('Authentication required');
             }
@@ -126,17 +167,21 @@ const API = {
             if (!response.ok) {
                 // Try to get error message from response body
                 let errorMessage = `HTTP error! status: ${response.status}`;
+                let fieldErrors = null;
                 try {
                     const errorData = await response.json();
                     if (errorData.error) {
                         errorMessage = errorData.error;
                     }
+                    // Validation errors are listed field by field
+                    fieldErrors = errorData.fields || null;
                 } catch (parseError) {
                     // If we can't parse the error response, use the default message
                 }
                 
                 const error = new Error(errorMessage);
                 error.status = response.status;
+                error.fields = fieldErrors;
                 throw error;
             }
             
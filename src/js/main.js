@@ -800,6 +800,9 @@ async function loadCatalogData() {
         // Populate brewing method dropdown
         populateBrewingMethodDropdown(catalog.brewing_method || []);
         
+        // Suggest tasting notes from the flavor wheel
+        populateTastingNotes(catalog.tasting_note || []);
+        
     } catch (error) {
         console.error('Failed to load catalog data:', error);
         // Keep the existing hardcoded options as fallback
@@ -846,6 +849,40 @@ function populateBrewingMethodDropdown(items) {
     });
 }
 
+// Helper function to suggest tasting notes in the note inputs
+function populateTastingNotes(items) {
+    const datalist = document.getElementById('tastingNoteOptions');
+    if (!datalist) return;
+    
+    datalist.innerHTML = '';
+    items.forEach(item => {
+        const option = document.createElement('option');
+        option.value = item.label;
+        datalist.appendChild(option);
+    });
+}
+
+// Splits comma-separated tasting notes into the list a case coffee stores
+function parseTastingNotes(text) {
+    return text
+        .split(',')
+        .map(note => note.trim())
+        .filter(note => note);
+}
+
+// Converts a datetime-local input value to the timestamp the API expects
+function scheduleTime(value) {
+    return value ? new Date(value).toISOString() : null;
+}
+
+// Formats an API timestamp for a datetime-local input, in local time
+function scheduleInputValue(timestamp) {
+    if (!timestamp) return '';
+    const date = new Date(timestamp);
+    date.setMinutes(date.getMinutes() - date.getTimezoneOffset());
+    return date.toISOString().slice(0, 16);
+}
+
 // Admin functions
 function checkAdminAccess() {
     if (!Auth.isAuthenticated()) {
@@ -857,7 +894,7 @@ function checkAdminAccess() {
     }
     
     const user = Auth.getUser();
-    if (!user || user.type !== 'admin') {
+    if (!Auth.isStaff(user)) {
         showNotification('Acceso denegado: Se requieren privilegios de administrador', 'error');
         setTimeout(() => {
             showPage('home');
@@ -908,6 +945,8 @@ function displayCatalogItems(items) {
             <div style="background: rgba(0,0,0,0.3); padding: 1rem; border-radius: 8px; display: flex; justify-content: space-between; align-items: center; position: relative; z-index: 5;">
                 <div>
                     <strong>${item.label}</strong> (${item.value})
+                    ${item.synonyms && item.synonyms.length ? `<br><small>Sinónimos: ${item.synonyms.join(', ')}</small>` : ''}
+                    ${item.parent ? `<br><small>Nota superior: ${item.parent}</small>` : ''}
                     <br>
                     <small>Orden: ${item.display_order} | ${item.is_active ? 'Activo' : 'Inactivo'}</small>
                 </div>
@@ -931,6 +970,8 @@ function showAddItemForm() {
     document.getElementById('addItemForm').classList.add('admin-section__form--visible');
     document.getElementById('newItemValue').value = '';
     document.getElementById('newItemLabel').value = '';
+    document.getElementById('newItemSynonyms').value = '';
+    document.getElementById('newItemParent').value = '';
     document.getElementById('newItemOrder').value = '0';
     document.getElementById('newItemActive').checked = true;
 }
@@ -943,6 +984,11 @@ function createCatalogItem() {
     const category = document.getElementById('adminCategorySelect').value;
     const value = document.getElementById('newItemValue').value.trim();
     const label = document.getElementById('newItemLabel').value.trim();
+    const synonyms = document.getElementById('newItemSynonyms').value
+        .split(',')
+        .map(synonym => synonym.trim())
+        .filter(synonym => synonym);
+    const parent = document.getElementById('newItemParent').value.trim();
     const order = parseInt(document.getElementById('newItemOrder').value) || 0;
     const isActive = document.getElementById('newItemActive').checked;
     
@@ -955,6 +1001,8 @@ function createCatalogItem() {
         category: category,
         value: value,
         label: label,
+        synonyms: synonyms,
+        parent: parent,
         display_order: order,
         is_active: isActive
     };
@@ -967,7 +1015,7 @@ function createCatalogItem() {
         })
         .catch(error => {
             console.error('Error creating catalog item:', error);
-            showAdminNotification('Error al crear el item', 'error');
+            showAdminNotification(error.status === 409 || error.status === 400 ? `Error al crear el item: ${error.message}` : 'Error al crear el item', 'error');
         });
 }
 
@@ -1043,6 +1091,8 @@ function clearCaseForm() {
     document.getElementById('caseName').value = '';
     document.getElementById('caseDescription').value = '';
     document.getElementById('caseIsActive').checked = false;
+    document.getElementById('caseOpensAt').value = '';
+    document.getElementById('caseClosesAt').value = '';
     
     // Clear coffee details
     for (let i = 1; i <= 4; i++) {
@@ -1138,7 +1188,7 @@ async function createCase() {
             region: region,
             variety: variety,
             process: process,
-            tasting_notes: notes
+            tasting_note_ids: parseTastingNotes(notes)
         });
     }
     
@@ -1157,6 +1207,8 @@ async function createCase() {
         name: name,
         description: description,
         is_active: isActive,
+        opens_at: scheduleTime(document.getElementById('caseOpensAt').value),
+        closes_at: scheduleTime(document.getElementById('caseClosesAt').value),
         coffees: coffees,
         enabled_questions: enabledQuestions
     };
@@ -1168,8 +1220,17 @@ async function createCase() {
         loadAllCases();
     } catch (error) {
         console.error('Error creating case:', error);
-        showAdminNotification('Error al crear el caso', 'error');
+        showAdminNotification(withFieldErrors('Error al crear el caso', error), 'error');
+    }
+}
+
+// withFieldErrors appends the API's field-level validation errors to a message
+function withFieldErrors(message, error) {
+    if (!error.fields) {
+        return message;
     }
+    const details = Object.entries(error.fields).map(([field, problem]) => `${field}: ${problem}`);
+    return `${message}: ${details.join('; ')}`;
 }
 
 async function loadAllCases() {
@@ -1214,6 +1275,7 @@ function displayCases(cases) {
                             }
                         </div>
                         <p style="margin: 0.5rem 0; opacity: 0.8;">${caseItem.description}</p>
+                        ${caseItem.opens_at || caseItem.closes_at ? `<small style="opacity: 0.8;">Abre: ${caseItem.opens_at ? new Date(caseItem.opens_at).toLocaleString('es-ES') : '—'} | Cierra: ${caseItem.closes_at ? new Date(caseItem.closes_at).toLocaleString('es-ES') : '—'}</small>` : ''}
                         <div style="display: grid; grid-template-columns: repeat(auto-fit, minmax(200px, 1fr)); gap: 0.5rem; margin-top: 1rem;">
                             ${caseItem.coffees ? caseItem.coffees.map(coffee => `
                                 <div style="background: rgba(212, 175, 55, 0.1); padding: 0.5rem; border-radius: 4px; font-size: 0.9rem;">
@@ -1270,7 +1332,7 @@ async function toggleCaseStatus(caseId, newStatus) {
         loadAllCases();
     } catch (error) {
         console.error('Error updating case status:', error);
-        showAdminNotification('Error al actualizar el estado del caso', 'error');
+        showAdminNotification(withFieldErrors('Error al actualizar el estado del caso', error), 'error');
     }
 }
 
@@ -1348,6 +1410,8 @@ function populateEditForm(caseData) {
         console.error('editCaseDescription element not found');
     }
     
+    document.getElementById('editCaseOpensAt').value = scheduleInputValue(caseData.opens_at);
+    document.getElementById('editCaseClosesAt').value = scheduleInputValue(caseData.closes_at);
     if (editCaseIsActive) {
         editCaseIsActive.checked = caseData.is_active || false;
     } else {
@@ -1370,7 +1434,7 @@ function populateEditForm(caseData) {
             }
             
             if (notesElement) {
-                notesElement.value = coffee.tasting_notes || '';
+                notesElement.value = (coffee.tasting_note_ids || []).join(', ') || coffee.tasting_notes || '';
             } else {
                 console.error(`editCoffee${i}Notes element not found`);
             }
@@ -1579,7 +1643,7 @@ async function updateCase() {
             region: region,
             variety: variety,
             process: process,
-            tasting_notes: notes
+            tasting_note_ids: parseTastingNotes(notes)
         });
     }
     
@@ -1598,6 +1662,8 @@ async function updateCase() {
         name: name,
         description: description,
         is_active: isActive,
+        opens_at: scheduleTime(document.getElementById('editCaseOpensAt').value),
+        closes_at: scheduleTime(document.getElementById('editCaseClosesAt').value),
         coffees: coffees,
         enabled_questions: enabledQuestions
     };
@@ -1609,7 +1675,7 @@ async function updateCase() {
         loadAllCases();
     } catch (error) {
         console.error('Error updating case:', error);
-        showAdminNotification('Error al actualizar el caso', 'error');
+        showAdminNotification(withFieldErrors('Error al actualizar el caso', error), 'error');
     }
 }
 